@@ -49,7 +49,11 @@ export function createMcpServer(repository: Repository): McpServer {
           memory: bridge.memory,
           timeout_seconds: bridge.timeout_seconds,
           network_policy_profile: bridge.network_policy_profile,
+          wait_for_ready: true,
         });
+        if (runnerRun.ready === false) {
+          throw new Error(`runner run ${runnerRun.run_id} not ready: ${runnerRun.ready_error ?? "unknown"}`);
+        }
 
         repository.insertAudit(
           runId,
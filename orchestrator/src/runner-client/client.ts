@@ -3,28 +3,114 @@ export interface RunnerCreateRunRequest {
   command?: string[];
   args?: string[];
   env_allowlist?: Record<string, string>;
+  secrets?: string[];
   allowed_tools?: string[];
   downstream_port?: number;
   cpu?: string;
+  cpu_limit?: string;
   memory?: string;
+  memory_limit?: string;
+  ephemeral_storage?: string;
   timeout_seconds?: number;
   network_policy_profile: "deny-all" | "dns-only";
+  run_mode?: "pod" | "job";
+  wait_for_ready?: boolean;
+  ready_timeout_seconds?: number;
+  mcp_handshake?: boolean;
+  idle_timeout_seconds?: number;
 }
 
 export interface RunnerCreateRunResponse {
   run_id: string;
   pod_name: string;
+  run_mode: "pod" | "job";
   image_digest: string;
+  ready?: boolean;
+  ready_error?: string;
   policy_evidence: {
     registry_allowed: boolean;
+    matched_rule?: string;
     signature_verified: boolean;
     verifier: string;
     identity?: string;
+    trust_root?: string;
+    verification_cache?: "hit" | "miss" | "coalesced";
+    verified_at?: string;
     resolved_digest: string;
     denial_reason?: string;
+    policy_set_hash?: string;
+    rule_results?: { rule: string; allowed: boolean; message?: string }[];
+    resources?: {
+      cpu_request: string;
+      cpu_limit: string;
+      memory_request: string;
+      memory_limit: string;
+      ephemeral_storage?: string;
+      timeout_seconds: number;
+      violations?: string[];
+    };
+    env?: {
+      count: number;
+      total_bytes: number;
+      rejected?: { name: string; rule: string }[];
+    };
+    command?: {
+      entrypoint?: string[];
+      args?: string[];
+      entrypoint_overridden: boolean;
+      args_overridden: boolean;
+      rule?: string;
+    };
+    exceptions_used?: {
+      id: string;
+      check: string;
+      reason: string;
+      created_by: string;
+      expires_at: string;
+      waived_failure: string;
+    }[];
   };
 }
 
+export interface RunnerPolicyCheck {
+  name: string;
+  status: "pass" | "fail" | "skipped" | "waived";
+  detail?: string;
+}
+
+export interface RunnerPolicyEvaluateResponse {
+  allowed: boolean;
+  pinned_image?: string;
+  checks: RunnerPolicyCheck[];
+  policy_evidence: RunnerCreateRunResponse["policy_evidence"];
+}
+
+export interface RunnerWaitResponse {
+  run_id: string;
+  ready: boolean;
+  status: string;
+  reason?: string;
+  handshake: "skipped" | "ok" | "failed";
+  waited_ms: number;
+  error?: string;
+}
+
+export interface RunnerKeepaliveResponse {
+  run_id: string;
+  last_activity: string;
+  idle_timeout_seconds: number;
+  idle_expires_at?: string;
+}
+
+export interface RunnerQuarantineResponse {
+  run_id: string;
+  status: "quarantined";
+  trigger: string;
+  quarantined_at: string;
+  evidence_sha256: string;
+  evidence_errors?: string[];
+}
+
 export interface RunnerInvokeToolResponse {
   run_id: string;
   tool_name: string;
@@ -33,11 +119,12 @@ export interface RunnerInvokeToolResponse {
 }
 
 const baseUrl = process.env.RUNNER_BASE_URL ?? "http://127.0.0.1:8080";
+const callerIdentity = process.env.RUNNER_CALLER_IDENTITY ?? "orchestrator";
 
 export async function createRun(req: RunnerCreateRunRequest): Promise<RunnerCreateRunResponse> {
   const res = await fetch(`${baseUrl}/runs`, {
     method: "POST",
-    headers: { "content-type": "application/json" },
+    headers: { "content-type": "application/json", "x-runner-caller": callerIdentity },
     body: JSON.stringify(req),
   });
   if (!res.ok) {
@@ -47,6 +134,41 @@ export async function createRun(req: RunnerCreateRunRequest): Promise<RunnerCrea
   return (await res.json()) as RunnerCreateRunResponse;
 }
 
+export async function waitRun(runId: string, opts: { timeout_seconds?: number; mcp_handshake?: boolean } = {}): Promise<RunnerWaitResponse> {
+  const res = await fetch(`${baseUrl}/runs/${runId}/wait`, {
+    method: "POST",
+    headers: { "content-type": "application/json" },
+    body: JSON.stringify(opts),
+  });
+  if (res.status !== 200 && res.status !== 409 && res.status !== 504) {
+    const text = await res.text();
+    throw new Error(`runner wait failed: ${res.status} ${text}`);
+  }
+  return (await res.json()) as RunnerWaitResponse;
+}
+
+export async function keepaliveRun(runId: string): Promise<RunnerKeepaliveResponse> {
+  const res = await fetch(`${baseUrl}/runs/${runId}/keepalive`, { method: "POST" });
+  if (!res.ok) {
+    const text = await res.text();
+    throw new Error(`runner keepalive failed: ${res.status} ${text}`);
+  }
+  return (await res.json()) as RunnerKeepaliveResponse;
+}
+
+export async function quarantineRun(runId: string, reason: string): Promise<RunnerQuarantineResponse> {
+  const res = await fetch(`${baseUrl}/runs/${runId}/quarantine`, {
+    method: "POST",
+    headers: { "content-type": "application/json", "x-runner-caller": callerIdentity },
+    body: JSON.stringify({ reason }),
+  });
+  if (!res.ok) {
+    const text = await res.text();
+    throw new Error(`runner quarantine failed: ${res.status} ${text}`);
+  }
+  return (await res.json()) as RunnerQuarantineResponse;
+}
+
 export async function invokeTool(runId: string, toolName: string, input: Record<string, unknown>): Promise<RunnerInvokeToolResponse> {
   const res = await fetch(`${baseUrl}/runs/${runId}/tools/${toolName}`, {
     method: "POST",
@@ -59,3 +181,16 @@ export async function invokeTool(runId: string, toolName: string, input: Record<
   }
   return (await res.json()) as RunnerInvokeToolResponse;
 }
+
+export async function evaluatePolicy(req: RunnerCreateRunRequest): Promise<RunnerPolicyEvaluateResponse> {
+  const res = await fetch(`${baseUrl}/policy/evaluate`, {
+    method: "POST",
+    headers: { "content-type": "application/json", "x-runner-caller": callerIdentity },
+    body: JSON.stringify(req),
+  });
+  if (!res.ok) {
+    const text = await res.text();
+    throw new Error(`runner policy evaluate failed: ${res.status} ${text}`);
+  }
+  return (await res.json()) as RunnerPolicyEvaluateResponse;
+}
@@ -57,7 +57,11 @@ export async function executeWorkflowFile(
       image_ref: step.image_ref,
       allowed_tools: step.allowed_tools,
       network_policy_profile: "deny-all",
+      wait_for_ready: true,
     });
+    if (run.ready === false) {
+      throw new Error(`runner run ${run.run_id} not ready: ${run.ready_error ?? "unknown"}`);
+    }
 
     repository.insertAudit(
       runId,
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mcp-orc/runner/internal/policy"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: policytest <policy-dir>")
+		os.Exit(2)
+	}
+	results, err := policy.RunTests(os.Args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "policytest: %v\n", err)
+		os.Exit(2)
+	}
+	failed := 0
+	for _, r := range results {
+		if r.Passed {
+			fmt.Printf("PASS %s: %s\n", r.Suite, r.Case)
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL %s: %s: %s\n", r.Suite, r.Case, r.Detail)
+	}
+	fmt.Printf("%d passed, %d failed\n", len(results)-failed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -4,34 +4,159 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
 	"github.com/mcp-orc/runner/internal/api"
+	"github.com/mcp-orc/runner/internal/audit"
+	"github.com/mcp-orc/runner/internal/backend"
+	"github.com/mcp-orc/runner/internal/backend/local"
 	"github.com/mcp-orc/runner/internal/config"
+	"github.com/mcp-orc/runner/internal/exceptions"
 	"github.com/mcp-orc/runner/internal/k8s"
 	"github.com/mcp-orc/runner/internal/policy"
+	"github.com/mcp-orc/runner/internal/pool"
+	"github.com/mcp-orc/runner/internal/quarantine"
+	"github.com/mcp-orc/runner/internal/registry"
 	"github.com/mcp-orc/runner/internal/runs"
+	"github.com/mcp-orc/runner/internal/secrets"
 )
 
 func main() {
-	cfg := config.FromEnv()
-	policyCfg := policy.ConfigFromEnv()
-	k, err := k8s.NewClient()
+	local.MaybeRunSandbox()
+
+	src, err := config.Load(os.Getenv("RUNNER_CONFIG_FILE"))
 	if err != nil {
-		log.Fatalf("init k8s client: %v", err)
+		log.Fatalf("load config: %v", err)
+	}
+	cfg, err := config.From(src.Lookup)
+	if err != nil {
+		log.Fatalf("load config: %v", err)
+	}
+	policyCfg, err := policy.ConfigFrom(src.Lookup)
+	if err != nil {
+		log.Fatalf("load policy config: %v", err)
+	}
+	var k *k8s.Client
+	if cfg.Backend == "kubernetes" {
+		k, err = k8s.NewClient()
+		if err != nil {
+			log.Fatalf("init k8s client: %v", err)
+		}
+	}
+	be, err := runBackend(cfg, k)
+	if err != nil {
+		log.Fatalf("init %s backend: %v", cfg.Backend, err)
 	}
-
-	h := api.NewHandler(cfg, policyCfg, k, runs.NewStore())
-	srv := &http.Server{Addr: cfg.Addr, Handler: h.Router()}
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	var warm *pool.Pool
+	if cfg.WarmPoolSize > 0 {
+		warm = pool.New(be, pool.Config{
+			Size:              int(cfg.WarmPoolSize),
+			IdleSeconds:       cfg.WarmPoolIdleSeconds,
+			MaxImages:         int(cfg.WarmPoolMaxImages),
+			MaxTimeoutSeconds: cfg.MaxTimeout,
+		})
+		be = warm
+	}
+
+	var engine *policy.Engine
+	if policyCfg.RulesDir != "" {
+		engine, err = policy.NewEngine(policyCfg.RulesDir)
+		if err != nil {
+			log.Fatalf("load policy rules: %v", err)
+		}
+		engine.Watch(ctx, time.Duration(policyCfg.RulesReloadSeconds)*time.Second, func(hash string, err error) {
+			if err != nil {
+				audit.Event("policy_reload_failed", map[string]any{"dir": policyCfg.RulesDir, "error": err.Error(), "active_set_hash": hash})
+				return
+			}
+			audit.Event("policy_reloaded", map[string]any{"dir": policyCfg.RulesDir, "set_hash": hash})
+		})
+		audit.Event("policy_loaded", map[string]any{"dir": policyCfg.RulesDir, "set_hash": engine.SetHash()})
+	}
+
+	ex, err := exceptions.NewStore(cfg.ExceptionsFile)
+	if err != nil {
+		log.Fatalf("load policy exceptions: %v", err)
+	}
+	if cfg.ExceptionsFile == "" && cfg.AdminToken != "" {
+		log.Printf("RUNNER_EXCEPTIONS_FILE is not set; policy exceptions are kept in memory only")
+	}
+	if cfg.TLSClientCAFile == "" && cfg.AdminToken != "" {
+		log.Printf("RUNNER_TLS_CLIENT_CA_FILE is not set; no caller is authenticated, so policy exceptions never apply")
+	}
+
+	qs, err := quarantine.NewStore(cfg.QuarantineDir)
+	if err != nil {
+		log.Fatalf("load quarantine bundles: %v", err)
+	}
+	if cfg.QuarantineDir == "" {
+		log.Printf("RUNNER_QUARANTINE_DIR is not set; quarantine evidence is kept in memory only")
+	}
+
+	enforcer := policy.NewEnforcer(policyCfg, registry.NewClient(policyCfg.ImagePlatform, policyCfg.InsecureRegistries), ex)
+	if policyCfg.TrustRootsFile != "" {
+		enforcer.WatchTrust(ctx, time.Duration(policyCfg.RulesReloadSeconds)*time.Second, func(hash string, err error) {
+			if err != nil {
+				audit.Event("trust_reload_failed", map[string]any{"file": policyCfg.TrustRootsFile, "error": err.Error(), "active_trust_hash": hash})
+				return
+			}
+			audit.Event("trust_reloaded", map[string]any{"file": policyCfg.TrustRootsFile, "trust_hash": hash, "roots": trustRootNames(enforcer.Config().TrustRoots)})
+		})
+	}
+	audit.Event("trust_loaded", map[string]any{"file": policyCfg.TrustRootsFile, "trust_hash": enforcer.TrustHash(), "roots": trustRootNames(policyCfg.TrustRoots)})
+	h := api.NewHandler(cfg, enforcer, engine, be, runs.NewStore(), ex, secretBroker(cfg, k), qs)
+	h.Reconfigure(cfg, src, nil)
+	audit.Event("config_loaded", map[string]any{"file": src.Path, "config_hash": src.Hash})
+	watchConfig(ctx, src, h, enforcer, time.Duration(policyCfg.RulesReloadSeconds)*time.Second)
+
+	// The cluster self-check runs before anything creates pods.
+	if k != nil {
+		h.SetSelfCheck(k.CheckPrerequisites)
+	}
+	report := h.SelfCheck(ctx)
+	for _, c := range report.Checks {
+		if c.Status != k8s.CheckPass {
+			log.Printf("cluster self-check %s failed: %s", c.Name, c.Detail)
+		}
+	}
+	if !report.Ready {
+		log.Fatalf("cluster self-check failed; RUNNER_SELFCHECK_MODE=strict refuses to serve")
+	}
+	go h.RunSelfCheck(ctx, time.Duration(cfg.SelfCheckIntervalSeconds)*time.Second)
+	if warm != nil {
+		go warm.Run(ctx)
+	}
+	go h.RunReaper(ctx, time.Duration(cfg.IdleReapSeconds)*time.Second)
+	go h.RunGC(ctx, time.Duration(cfg.GCIntervalSeconds)*time.Second)
+
+	tlsCfg, err := cfg.TLS()
+	if err != nil {
+		log.Fatalf("load TLS config: %v", err)
+	}
+	srv := &http.Server{Addr: cfg.Addr, Handler: h.Router(), TLSConfig: tlsCfg}
+
 	go func() {
-		log.Printf("runner listening on %s", cfg.Addr)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		switch {
+		case tlsCfg == nil:
+			log.Printf("runner listening on %s", cfg.Addr)
+			err = srv.ListenAndServe()
+		case tlsCfg.ClientCAs == nil:
+			log.Printf("runner listening on %s (TLS)", cfg.Addr)
+			err = srv.ListenAndServeTLS("", "")
+		default:
+			log.Printf("runner listening on %s (mTLS)", cfg.Addr)
+			err = srv.ListenAndServeTLS("", "")
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("listen: %v", err)
 		}
 	}()
@@ -40,4 +165,81 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	_ = srv.Shutdown(shutdownCtx)
+	if warm != nil {
+		warm.Close(shutdownCtx)
+	}
+}
+
+func trustRootNames(roots []policy.TrustRoot) []string {
+	names := make([]string, 0, len(roots))
+	for _, r := range roots {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// watchConfig re-reads the config file on SIGHUP and whenever it changes on
+// disk. Reloadable sections (limits, profiles, policy) are applied in place;
+// changes to restart-only keys are audited and reported on /admin/config.
+func watchConfig(ctx context.Context, src *config.Source, h *api.Handler, enforcer *policy.Enforcer, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		var pending []string
+		for {
+			trigger := "file_change"
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				trigger = "sighup"
+			case <-t.C:
+				if src.Path == "" {
+					continue
+				}
+			}
+
+			next, nextPending, err := src.Reload()
+			if err == nil && next.Hash == src.Hash && slices.Equal(nextPending, pending) {
+				continue
+			}
+			var cfg config.Config
+			var policyCfg policy.Config
+			if err == nil {
+				cfg, err = config.From(next.Lookup)
+			}
+			if err == nil {
+				policyCfg, err = policy.ConfigFrom(next.Lookup)
+			}
+			if err != nil {
+				audit.Event("config_reload_failed", map[string]any{"file": src.Path, "trigger": trigger, "error": err.Error(), "active_config_hash": src.Hash})
+				continue
+			}
+
+			enforcer.SetConfig(policyCfg)
+			h.Reconfigure(cfg, next, nextPending)
+			audit.Event("config_reloaded", map[string]any{"file": next.Path, "trigger": trigger, "previous_config_hash": src.Hash, "config_hash": next.Hash, "restart_required": nextPending})
+			src, pending = next, nextPending
+		}
+	}()
+}
+
+func runBackend(cfg config.Config, k *k8s.Client) (backend.Backend, error) {
+	if cfg.Backend == "local" {
+		return local.New(local.Options{ImageDir: cfg.LocalImageDir, StateDir: cfg.LocalStateDir, Cgroup: cfg.LocalCgroup, HiddenDirs: []string{cfg.SecretsDir}})
+	}
+	return k, nil
+}
+
+func secretBroker(cfg config.Config, k *k8s.Client) *secrets.Broker {
+	switch cfg.SecretsBackend {
+	case "kubernetes":
+		return secrets.NewBroker(k.SecretBackend(cfg.SecretsNamespace))
+	case "file":
+		return secrets.NewBroker(secrets.FileBackend{Dir: cfg.SecretsDir})
+	}
+	return nil
 }
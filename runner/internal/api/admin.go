@@ -0,0 +1,113 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/mcp-orc/runner/internal/audit"
+	"github.com/mcp-orc/runner/internal/exceptions"
+	"github.com/mcp-orc/runner/internal/policy"
+	"github.com/mcp-orc/runner/internal/pool"
+)
+
+// requireAdmin guards the /admin routes with the static RUNNER_ADMIN_TOKEN.
+// The routes are not mounted at all when no token is configured.
+func (h *Handler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.config().AdminToken)) != 1 {
+			audit.Event("admin_auth_failed", map[string]any{"path": r.URL.Path, "caller": callerIdentity(r).Subject})
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) createException(w http.ResponseWriter, r *http.Request) {
+	var req CreateExceptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	maxTTL := time.Duration(h.config().MaxExceptionTTLSeconds) * time.Second
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if ttl <= 0 || ttl > maxTTL {
+		http.Error(w, "ttl_seconds must be between 1 and "+maxTTL.String(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	x, err := h.exceptions.Create(policy.Exception{
+		Digest:     req.Digest,
+		Repository: req.Repository,
+		Principal:  req.Principal,
+		Waive:      req.Waive,
+		Reason:     req.Reason,
+		CreatedBy:  adminActor(r),
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(ttl),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	audit.Event("policy_exception_created", map[string]any{"exception_id": x.ID, "created_by": x.CreatedBy, "principal": x.Principal, "digest": x.Digest, "repository": x.Repository, "waive": x.Waive, "reason": x.Reason, "expires_at": x.ExpiresAt})
+	writeJSON(w, http.StatusCreated, x)
+}
+
+func (h *Handler) listExceptions(w http.ResponseWriter, r *http.Request) {
+	all := h.exceptions.Exceptions()
+	out := all[:0:0]
+	includeInactive := r.URL.Query().Get("all") == "true"
+	now := time.Now().UTC()
+	for _, x := range all {
+		if includeInactive || x.Active(now) {
+			out = append(out, x)
+		}
+	}
+	writeJSON(w, http.StatusOK, ExceptionListResponse{Exceptions: out})
+}
+
+func (h *Handler) revokeException(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "exception_id")
+	by := adminActor(r)
+	x, err := h.exceptions.Revoke(id, by, time.Now().UTC())
+	if errors.Is(err, exceptions.ErrNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "revoke failed", http.StatusInternalServerError)
+		return
+	}
+	audit.Event("policy_exception_revoked", map[string]any{"exception_id": x.ID, "revoked_by": by})
+	writeJSON(w, http.StatusOK, x)
+}
+
+// getPool reports warm pool sizes and hit/miss counts when the backend is
+// wrapped in a pool (RUNNER_WARM_POOL_SIZE > 0).
+func (h *Handler) getPool(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.backend.(interface{ Stats() pool.Stats })
+	if !ok {
+		http.Error(w, "warm pool disabled", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, p.Stats())
+}
+
+func (h *Handler) getConfig(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	resp := ConfigStatusResponse{RestartRequired: h.pending}
+	if h.source != nil {
+		resp.File, resp.Hash, resp.LoadedAt = h.source.Path, h.source.Hash, h.source.LoadedAt
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
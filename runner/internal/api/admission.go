@@ -0,0 +1,237 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mcp-orc/runner/internal/audit"
+	"github.com/mcp-orc/runner/internal/config"
+	"github.com/mcp-orc/runner/internal/k8s"
+	"github.com/mcp-orc/runner/internal/policy"
+	"github.com/mcp-orc/runner/internal/secrets"
+)
+
+type admission struct {
+	caller       policy.Caller
+	pinnedRef    string
+	allowedTools []string
+	evidence     policy.Evidence
+	checks       []policy.Check
+	err          error
+	// secrets hold brokered values for the pod; they never enter evidence.
+	secrets []secrets.Secret
+}
+
+// requestStages run after the image pipeline, in order. Like the image
+// stages, a failure reports every later stage as skipped.
+var requestStages = []string{"resource_limits", "env", "secrets", "policy_rules"}
+
+func (a *admission) deny(stage, reason string, err error) admission {
+	a.err = err
+	a.evidence.DenialReason = reason
+	a.checks = append(a.checks, policy.Check{Name: stage, Status: policy.CheckFail, Detail: err.Error()})
+	a.skipAfter(stage, "blocked by "+stage)
+	return *a
+}
+
+func (a *admission) skipAfter(stage, detail string) {
+	idx := slices.Index(requestStages, stage)
+	for _, s := range requestStages[idx+1:] {
+		a.checks = append(a.checks, policy.Check{Name: s, Status: policy.CheckSkipped, Detail: detail})
+	}
+}
+
+// admit runs the admission pipeline. A dry run checks secret references
+// without fetching them, so an unauthenticated evaluation cannot probe which
+// secrets exist or read their image bindings.
+func (h *Handler) admit(r *http.Request, req CreateRunRequest, dryRun bool) admission {
+	a := admission{caller: callerIdentity(r)}
+	res := h.enforcer.Evaluate(policy.Request{ImageRef: req.ImageRef, DownstreamPort: downstreamPort(req), AllowedTools: req.AllowedTools, Command: req.Command, Args: req.Args, Principal: authenticatedSubject(a.caller)})
+	a.pinnedRef, a.allowedTools, a.evidence, a.checks, a.err = res.PinnedRef, res.AllowedTools, res.Evidence, res.Checks, res.Err
+	if a.err != nil {
+		for _, s := range requestStages {
+			a.checks = append(a.checks, policy.Check{Name: s, Status: policy.CheckSkipped, Detail: "blocked by image policy"})
+		}
+		return a
+	}
+
+	cfg := h.config()
+	rs, err := policy.ResolveResources(resourceRequest(req), policy.ResourceRequest{
+		CPU:              cfg.DefaultCPU,
+		Memory:           cfg.DefaultMemory,
+		EphemeralStorage: cfg.DefaultEphemeralStorage,
+		TimeoutSeconds:   cfg.DefaultTimeout,
+	}, policy.ResourceLimits{
+		MinCPU:              cfg.MinCPU,
+		MaxCPU:              cfg.MaxCPU,
+		MinMemory:           cfg.MinMemory,
+		MaxMemory:           cfg.MaxMemory,
+		MaxEphemeralStorage: cfg.MaxEphemeralStorage,
+		MinTimeoutSeconds:   cfg.MinTimeout,
+		MaxTimeoutSeconds:   cfg.MaxTimeout,
+	})
+	a.evidence.Resources = rs
+	if err != nil {
+		return a.deny("resource_limits", "resource_limits_exceeded", err)
+	}
+	a.checks = append(a.checks, policy.Check{Name: "resource_limits", Status: policy.CheckPass, Detail: fmt.Sprintf("cpu %s/%s memory %s/%s timeout %ds", rs.CPURequest, rs.CPULimit, rs.MemoryRequest, rs.MemoryLimit, rs.TimeoutSeconds)})
+
+	envEv, err := policy.CheckEnv(req.EnvAllowlist, policy.EnvLimits{
+		MaxVars:       cfg.EnvMaxVars,
+		MaxValueBytes: cfg.EnvMaxValueBytes,
+		MaxTotalBytes: cfg.EnvMaxTotalBytes,
+		Denylist:      cfg.EnvDenylist,
+		ScanSecrets:   cfg.EnvSecretScan,
+	})
+	a.evidence.Env = envEv
+	if err != nil {
+		return a.deny("env", "env_rejected", err)
+	}
+	a.checks = append(a.checks, policy.Check{Name: "env", Status: policy.CheckPass, Detail: fmt.Sprintf("%d vars, %d bytes", envEv.Count, envEv.TotalBytes)})
+
+	switch {
+	case len(req.Secrets) == 0:
+		a.checks = append(a.checks, policy.Check{Name: "secrets", Status: policy.CheckSkipped, Detail: "no secrets requested"})
+	case dryRun:
+		if err := h.secrets.CheckRefs(req.Secrets, cfg.MaxSecretsPerRun); err != nil {
+			return a.deny("secrets", "secret_unavailable", err)
+		}
+		a.checks = append(a.checks, policy.Check{Name: "secrets", Status: policy.CheckPass, Detail: "references valid; availability and image scope are checked at run creation"})
+	default:
+		ref, _ := policy.ParseReference(a.pinnedRef)
+		resolved, err := h.secrets.Resolve(r.Context(), req.Secrets, ref.Name(), cfg.MaxSecretsPerRun)
+		if err != nil {
+			return a.deny("secrets", "secret_unavailable", err)
+		}
+		a.secrets = resolved
+		a.checks = append(a.checks, policy.Check{Name: "secrets", Status: policy.CheckPass, Detail: strings.Join(req.Secrets, ", ")})
+	}
+
+	if h.policyEngine == nil {
+		a.checks = append(a.checks, policy.Check{Name: "policy_rules", Status: policy.CheckSkipped, Detail: "no policy rules configured"})
+		return a
+	}
+
+	decision := h.policyEngine.Evaluate(policy.NewInput(req, a.caller, req.ImageRef, a.evidence))
+	a.evidence.PolicySetHash = decision.SetHash
+	a.evidence.RuleResults = decision.Results
+	if !decision.Allowed {
+		a.evidence.DenialReason = "policy_rule_denied"
+		a.err = errors.New("policy rules denied")
+		a.checks = append(a.checks, policy.Check{Name: "policy_rules", Status: policy.CheckFail, Detail: ruleFailures(decision.Results)})
+		return a
+	}
+	a.checks = append(a.checks, policy.Check{Name: "policy_rules", Status: policy.CheckPass, Detail: decision.SetHash})
+	return a
+}
+
+func resourceRequest(req CreateRunRequest) policy.ResourceRequest {
+	return policy.ResourceRequest{
+		CPU:              req.CPU,
+		CPULimit:         req.CPULimit,
+		Memory:           req.Memory,
+		MemoryLimit:      req.MemoryLimit,
+		EphemeralStorage: req.EphemeralStorage,
+		TimeoutSeconds:   req.TimeoutSeconds,
+	}
+}
+
+func downstreamPort(req CreateRunRequest) int {
+	if req.DownstreamPort <= 0 {
+		return 8080
+	}
+	return req.DownstreamPort
+}
+
+func ruleFailures(results []policy.RuleResult) string {
+	msgs := []string{}
+	for _, r := range results {
+		if !r.Allowed {
+			msgs = append(msgs, r.Rule+": "+r.Message)
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func requestChecks(req CreateRunRequest, cfg config.Config) []policy.Check {
+	checks := []policy.Check{}
+	add := func(name string, err error, ok string) {
+		if err != nil {
+			checks = append(checks, policy.Check{Name: name, Status: policy.CheckFail, Detail: err.Error()})
+			return
+		}
+		checks = append(checks, policy.Check{Name: name, Status: policy.CheckPass, Detail: ok})
+	}
+
+	var reqErr error
+	switch {
+	case strings.TrimSpace(req.ImageRef) == "":
+		reqErr = errors.New("image_ref is required")
+	case req.TimeoutSeconds < 0:
+		reqErr = errors.New("timeout_seconds must be >= 0")
+	default:
+		reqErr = checkReadyTimeout("ready_timeout_seconds", req.ReadyTimeoutSeconds, cfg)
+		if reqErr == nil {
+			reqErr = checkIdleTimeout(req.IdleTimeoutSeconds, cfg)
+		}
+	}
+	add("request", reqErr, "required fields present")
+
+	var profileErr error
+	if !slices.Contains(cfg.NetworkProfiles, req.NetworkPolicyProfile) {
+		profileErr = errors.New("network_policy_profile must be one of " + strings.Join(cfg.NetworkProfiles, ", "))
+	}
+	add("network_profile", profileErr, req.NetworkPolicyProfile)
+
+	var modeErr error
+	switch {
+	case req.RunMode != "" && req.RunMode != k8s.RunModePod && req.RunMode != k8s.RunModeJob:
+		modeErr = errors.New("run_mode must be pod or job")
+	case req.RunMode == k8s.RunModeJob && cfg.Backend != "kubernetes":
+		modeErr = errors.New("run_mode job needs RUNNER_BACKEND=kubernetes")
+	}
+	add("run_mode", modeErr, runMode(req))
+
+	add("resources", resourceRequest(req).ParseQuantities(), "quantities parse")
+	return checks
+}
+
+func runMode(req CreateRunRequest) string {
+	if req.RunMode == "" {
+		return k8s.RunModePod
+	}
+	return req.RunMode
+}
+
+func validateCreateRequest(req CreateRunRequest, cfg config.Config) error {
+	for _, c := range requestChecks(req, cfg) {
+		if c.Status == policy.CheckFail {
+			return errors.New(c.Detail)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) evaluatePolicy(w http.ResponseWriter, r *http.Request) {
+	var req CreateRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	checks := requestChecks(req, h.config())
+	adm := h.admit(r, req, true)
+	checks = append(checks, adm.checks...)
+
+	allowed := true
+	for _, c := range checks {
+		if c.Status == policy.CheckFail {
+			allowed = false
+		}
+	}
+	audit.Event("policy_evaluated", map[string]any{"image_ref": req.ImageRef, "caller": adm.caller.Subject, "allowed": allowed, "policy_evidence": adm.evidence})
+	writeJSON(w, http.StatusOK, PolicyEvaluateResponse{Allowed: allowed, PinnedImage: adm.pinnedRef, Checks: checks, PolicyEvidence: adm.evidence})
+}
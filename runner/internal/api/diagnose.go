@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/mcp-orc/runner/internal/k8s"
+	"github.com/mcp-orc/runner/internal/runs"
+)
+
+// Normalized failure reasons reported as failure_reason. Kubernetes spreads
+// the same failure over container reasons, event reasons and free-form
+// messages; callers get one stable value per cause.
+const (
+	failureImagePull         = "image_pull_failed"
+	failureAdmissionRejected = "admission_rejected"
+	failureRuntimeClass      = "runtime_class_not_found"
+	failureSandbox           = "sandbox_creation_failed"
+	failureUnschedulable     = "unschedulable"
+	failureVolumeMount       = "volume_mount_failed"
+	failureContainerConfig   = "container_config_error"
+	failureCrashLoop         = "crash_loop"
+	failureOOMKilled         = "oom_killed"
+	failureContainerExit     = "container_exit_nonzero"
+	failureDeadline          = "deadline_exceeded"
+	failureBackoffLimit      = "backoff_limit_exceeded"
+	failureEvicted           = "evicted"
+	failureUnknown           = "unknown"
+)
+
+var imagePullReasons = map[string]bool{
+	"ImagePullBackOff": true, "ErrImagePull": true, "InvalidImageName": true,
+	"ErrImageNeverPull": true, "RegistryUnavailable": true, "ImageInspectError": true,
+}
+
+// progressReasons are Normal events that supersede earlier warnings, e.g. a
+// pod scheduled after FailedScheduling.
+var progressReasons = map[string]bool{
+	"Scheduled": true, "SuccessfulCreate": true, "Pulled": true, "Created": true, "Started": true,
+}
+
+var containerConfigReasons = map[string]bool{
+	"CreateContainerConfigError": true, "CreateContainerError": true, "RunContainerError": true,
+}
+
+// classifyMessage recognises failures that only show in an API error or
+// event message, such as a pod rejected at admission.
+func classifyMessage(msg string) string {
+	m := strings.ToLower(msg)
+	switch {
+	case strings.Contains(m, "runtimeclass") && strings.Contains(m, "not found"):
+		return failureRuntimeClass
+	case strings.Contains(m, "admission webhook"), strings.Contains(m, "violates podsecurity"),
+		strings.Contains(m, "exceeded quota"), strings.Contains(m, "is forbidden"):
+		return failureAdmissionRejected
+	}
+	return ""
+}
+
+// classifyEvent maps a Warning event to a failure reason, or "".
+func classifyEvent(e k8s.Event) string {
+	if e.Type != "Warning" {
+		return ""
+	}
+	switch e.Reason {
+	case "FailedCreate":
+		if r := classifyMessage(e.Message); r != "" {
+			return r
+		}
+		return failureAdmissionRejected
+	case "FailedCreatePodSandBox":
+		// No handler for the RuntimeClass on the node.
+		if strings.Contains(e.Message, "no runtime for") {
+			return failureRuntimeClass
+		}
+		return failureSandbox
+	case "FailedScheduling":
+		return failureUnschedulable
+	case "FailedMount", "FailedAttachVolume":
+		return failureVolumeMount
+	case "Failed", "BackOff":
+		if m := strings.ToLower(e.Message); strings.Contains(m, "pull") && strings.Contains(m, "image") {
+			return failureImagePull
+		}
+	case "Evicted":
+		return failureEvicted
+	}
+	return classifyMessage(e.Message)
+}
+
+// failureReason explains why a run is not progressing or has failed. The
+// container state is the most specific signal, then the newest warning not
+// superseded by progress, then the phase reason. It is "" for a healthy run.
+func failureReason(status, reason string, d k8s.PodDiagnostics) string {
+	for _, c := range d.Containers {
+		switch {
+		case imagePullReasons[c.Reason]:
+			return failureImagePull
+		case containerConfigReasons[c.Reason]:
+			return failureContainerConfig
+		case c.Reason == "CrashLoopBackOff":
+			return failureCrashLoop
+		case c.Reason == "OOMKilled":
+			return failureOOMKilled
+		}
+	}
+	if status == "succeeded" || status == "running" {
+		return ""
+	}
+	for i := len(d.Events) - 1; i >= 0; i-- {
+		e := d.Events[i]
+		if e.Type == "Normal" && progressReasons[e.Reason] {
+			break
+		}
+		if r := classifyEvent(e); r != "" {
+			return r
+		}
+	}
+	switch reason {
+	case "DeadlineExceeded":
+		return failureDeadline
+	case "Evicted":
+		return failureEvicted
+	}
+	if status != "failed" {
+		return ""
+	}
+	for _, c := range d.Containers {
+		if c.ExitCode != nil && *c.ExitCode != 0 {
+			return failureContainerExit
+		}
+	}
+	if reason == "BackoffLimitExceeded" {
+		return failureBackoffLimit
+	}
+	return failureUnknown
+}
+
+type diagnosis struct {
+	k8s.PodDiagnostics
+	reason string
+}
+
+// diagnose describes the run's workload and records the failure reason it
+// finds on the run, so it is still reported once the pod and its events are
+// gone. Describing is best effort: without diagnostics the reason comes
+// from the phase alone.
+func (h *Handler) diagnose(ctx context.Context, run runs.Run) diagnosis {
+	d := diagnosis{PodDiagnostics: k8s.PodDiagnostics{Containers: []k8s.ContainerState{}, Events: []k8s.Event{}}}
+	if !slices.Contains([]string{"stopped", "idle_expired", "not_found"}, run.Status) {
+		if pd, err := h.backend.DescribePod(ctx, run.Namespace, run.PodName); err == nil {
+			if pd.Containers != nil {
+				d.Containers = pd.Containers
+			}
+			if pd.Events != nil {
+				d.Events = pd.Events
+			}
+		}
+	}
+	d.reason = failureReason(run.Status, run.Reason, d.PodDiagnostics)
+	// Events expire and a deleted pod takes its container states along; a
+	// specific reason found earlier beats what is left to see now.
+	if run.FinishedAt != nil && run.FailureReason != "" && (d.reason == "" || d.reason == failureUnknown) {
+		d.reason = run.FailureReason
+	}
+	if d.reason != "" && d.reason != run.FailureReason {
+		_ = h.store.Update(run.RunID, func(orig runs.Run) runs.Run {
+			orig.FailureReason = d.reason
+			return orig
+		})
+	}
+	return d
+}
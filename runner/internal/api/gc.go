@@ -0,0 +1,239 @@
+package api
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mcp-orc/runner/internal/audit"
+	"github.com/mcp-orc/runner/internal/k8s"
+	"github.com/mcp-orc/runner/internal/runs"
+)
+
+// collector is the garbage collector's state. sweep serialises sweeps and
+// guards when terminal pods without a recorded finish time were first seen
+// and which pods' expired secrets were already deleted. mu guards only the
+// counters for /admin/gc, so reading them never waits on a backend call.
+type collector struct {
+	sweep   sync.Mutex
+	seen    map[string]time.Time
+	expired map[string]bool
+
+	mu    sync.Mutex
+	stats GCStats
+}
+
+func newCollector() collector {
+	return collector{
+		stats:   GCStats{PodsDeleted: map[string]int64{}, RecordsDeleted: map[string]int64{}, RunsFinished: map[string]int64{}},
+		seen:    map[string]time.Time{},
+		expired: map[string]bool{},
+	}
+}
+
+// retention returns the window for status; statuses without one use the
+// failed window, the most conservative default.
+func retention(windows map[string]int64, status string) time.Duration {
+	v, ok := windows[status]
+	if !ok {
+		v = windows["failed"]
+	}
+	return time.Duration(v) * time.Second
+}
+
+func terminalPhase(phase string) bool {
+	return phase == "Succeeded" || phase == "Failed"
+}
+
+// finishedAt is when pod ended, falling back to when a sweep first saw it
+// ended. c.sweep must be held.
+func (c *collector) finishedAt(pod k8s.RunPod, now time.Time) time.Time {
+	if !pod.FinishedAt.IsZero() {
+		return pod.FinishedAt
+	}
+	if t, ok := c.seen[pod.Name]; ok {
+		return t
+	}
+	c.seen[pod.Name] = now
+	return now
+}
+
+// CollectGarbage runs one GC sweep. It marks runs whose workload ended or
+// vanished as finished, deletes workloads once their status's pod retention
+// has passed and run records once their record retention has, and deletes
+// run pods the runner has no record of after RUNNER_GC_ORPHAN_SECONDS. Run
+// secrets are deleted once their expiry has passed, whatever the pod's state.
+func (h *Handler) CollectGarbage(ctx context.Context, now time.Time) error {
+	cfg := h.config()
+	started := time.Now()
+	pods, err := h.backend.ListRunPods(ctx, cfg.Namespace)
+
+	c := &h.gc
+	c.sweep.Lock()
+	defer c.sweep.Unlock()
+	c.count(func(s *GCStats) {
+		s.Sweeps++
+		s.LastSweep = &now
+		s.LastError = ""
+		if err != nil {
+			s.LastError = err.Error()
+		}
+	})
+	if err != nil {
+		audit.Event("gc_failed", map[string]any{"reason": err.Error()})
+		return err
+	}
+
+	live, listed := map[string]k8s.RunPod{}, map[string]bool{}
+	for _, p := range pods {
+		live[p.Name], listed[p.Name] = p, true
+		// The expiry is on the workload, so it survives a runner restart.
+		if p.SecretsExpireAt.IsZero() || now.Before(p.SecretsExpireAt) || c.expired[p.Name] {
+			continue
+		}
+		if err := h.backend.DeleteRunSecret(ctx, cfg.Namespace, k8s.RunSecretName(p.Name)); err != nil {
+			audit.Event("gc_secret_delete_failed", map[string]any{"run_id": p.RunID, "pod_name": p.Name, "reason": err.Error()})
+			continue
+		}
+		c.expired[p.Name] = true
+		c.count(func(s *GCStats) { s.SecretsExpired++ })
+		audit.Event("gc_secret_expired", map[string]any{"run_id": p.RunID, "pod_name": p.Name, "expired_at": p.SecretsExpireAt})
+	}
+	deletePod := func(name, runID, status string, secrets bool) bool {
+		if err := h.backend.DeletePod(ctx, cfg.Namespace, name); err != nil {
+			audit.Event("gc_pod_delete_failed", map[string]any{"run_id": runID, "pod_name": name, "reason": err.Error()})
+			return false
+		}
+		if secrets {
+			_ = h.backend.DeleteRunSecret(ctx, cfg.Namespace, k8s.RunSecretName(name))
+		}
+		delete(live, name)
+		delete(c.seen, name)
+		delete(c.expired, name)
+		c.count(func(s *GCStats) { s.PodsDeleted[status]++ })
+		audit.Event("gc_pod_deleted", map[string]any{"run_id": runID, "pod_name": name, "status": status})
+		return true
+	}
+
+	for _, run := range h.store.List() {
+		pod, hasPod := live[run.PodName]
+		delete(live, run.PodName)
+		if run.QuarantinedAt != nil {
+			continue
+		}
+		if run.FinishedAt == nil {
+			status, reason, finished := "", "", time.Time{}
+			switch {
+			case hasPod && terminalPhase(pod.Phase):
+				status, reason, finished = strings.ToLower(pod.Phase), pod.Reason, c.finishedAt(pod, now)
+			// A run stored after the listing may not have been listed yet.
+			case !hasPod && run.CreatedAt.Before(started):
+				status, reason, finished = "not_found", "pod_missing", now
+			}
+			if status == "" {
+				continue
+			}
+			_ = h.store.Update(run.RunID, func(orig runs.Run) runs.Run {
+				if orig.FinishedAt == nil {
+					orig.Status, orig.Reason, orig.FinishedAt = status, reason, &finished
+				}
+				return orig
+			})
+			if run, err = h.store.Get(run.RunID); err != nil {
+				continue
+			}
+			c.count(func(s *GCStats) { s.RunsFinished[run.Status]++ })
+			// Keep why it failed before retention deletes the pod and events.
+			if run.Status == "failed" {
+				h.diagnose(ctx, run)
+			}
+			// A run that hit its deadline failed as designed, not suspiciously.
+			if run.Status == "failed" && run.Reason != "DeadlineExceeded" {
+				// Leave the workload to the quarantine; a later sweep
+				// applies retention if it could not be isolated.
+				if h.autoQuarantine(run, "pod_failed", "workload failed: "+run.Reason, "gc") {
+					continue
+				}
+			}
+		}
+
+		ended := now.Sub(*run.FinishedAt)
+		if hasPod && ended >= retention(cfg.GCPodRetention, run.Status) {
+			hasPod = !deletePod(run.PodName, run.RunID, run.Status, len(run.Secrets) > 0)
+		}
+		if !hasPod && ended >= retention(cfg.GCRecordRetention, run.Status) {
+			h.store.Delete(run.RunID)
+			c.count(func(s *GCStats) { s.RecordsDeleted[run.Status]++ })
+			audit.Event("gc_record_deleted", map[string]any{"run_id": run.RunID, "status": run.Status, "finished_at": run.FinishedAt})
+		}
+	}
+
+	// What is left has no run record: warm pool pods, pods created by a
+	// previous runner process, or pods whose record was already collected.
+	// Quarantined pods wait for an admin whatever their phase.
+	for name, pod := range live {
+		switch {
+		case pod.Quarantined:
+		case terminalPhase(pod.Phase):
+			status := strings.ToLower(pod.Phase)
+			if now.Sub(c.finishedAt(pod, now)) >= retention(cfg.GCPodRetention, status) {
+				deletePod(name, pod.RunID, status, true)
+			}
+		case pod.Warm:
+		case cfg.GCOrphanSeconds > 0 && now.Sub(pod.CreatedAt) >= time.Duration(cfg.GCOrphanSeconds)*time.Second:
+			deletePod(name, pod.RunID, "orphaned", true)
+		}
+	}
+	for name := range c.seen {
+		if !listed[name] {
+			delete(c.seen, name)
+		}
+	}
+	for name := range c.expired {
+		if !listed[name] {
+			delete(c.expired, name)
+		}
+	}
+	c.count(func(s *GCStats) { s.LastDurationMillis = time.Since(started).Milliseconds() })
+	return nil
+}
+
+// count updates the /admin/gc counters.
+func (c *collector) count(update func(*GCStats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(&c.stats)
+}
+
+// RunGC sweeps immediately, to collect what a previous process left behind,
+// and then every interval until ctx is done.
+func (h *Handler) RunGC(ctx context.Context, interval time.Duration) {
+	_ = h.CollectGarbage(ctx, time.Now().UTC())
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			_ = h.CollectGarbage(ctx, now.UTC())
+		}
+	}
+}
+
+func (h *Handler) getGC(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config()
+	h.gc.mu.Lock()
+	stats := h.gc.stats
+	stats.PodsDeleted = maps.Clone(stats.PodsDeleted)
+	stats.RecordsDeleted = maps.Clone(stats.RecordsDeleted)
+	stats.RunsFinished = maps.Clone(stats.RunsFinished)
+	h.gc.mu.Unlock()
+	stats.IntervalSeconds = cfg.GCIntervalSeconds
+	stats.PodRetention = cfg.GCPodRetention
+	stats.RecordRetention = cfg.GCRecordRetention
+	writeJSON(w, http.StatusOK, stats)
+}
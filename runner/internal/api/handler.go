@@ -2,9 +2,9 @@ package api
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -12,30 +12,79 @@ import (
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/mcp-orc/runner/internal/audit"
+	"github.com/mcp-orc/runner/internal/backend"
 	"github.com/mcp-orc/runner/internal/config"
+	"github.com/mcp-orc/runner/internal/exceptions"
 	"github.com/mcp-orc/runner/internal/k8s"
 	"github.com/mcp-orc/runner/internal/policy"
+	"github.com/mcp-orc/runner/internal/quarantine"
 	"github.com/mcp-orc/runner/internal/runs"
+	"github.com/mcp-orc/runner/internal/secrets"
 )
 
 type Handler struct {
-	cfg       config.Config
-	policyCfg policy.Config
-	k8s       *k8s.Client
-	store     *runs.Store
+	mu           sync.RWMutex
+	cfg          config.Config
+	source       *config.Source
+	pending      []string
+	enforcer     *policy.Enforcer
+	policyEngine *policy.Engine
+	backend      backend.Backend
+	store        *runs.Store
+	exceptions   *exceptions.Store
+	secrets      *secrets.Broker
+	bundles      *quarantine.Store
+	gc           collector
+	selfCheck    selfCheck
+	// quarantining tracks automatic quarantines still in flight.
+	quarantining sync.WaitGroup
 }
 
-func NewHandler(cfg config.Config, policyCfg policy.Config, k *k8s.Client, s *runs.Store) *Handler {
-	return &Handler{cfg: cfg, policyCfg: policyCfg, k8s: k, store: s}
+func NewHandler(cfg config.Config, enforcer *policy.Enforcer, engine *policy.Engine, b backend.Backend, s *runs.Store, ex *exceptions.Store, sb *secrets.Broker, qs *quarantine.Store) *Handler {
+	return &Handler{cfg: cfg, enforcer: enforcer, policyEngine: engine, backend: b, store: s, exceptions: ex, secrets: sb, bundles: qs, gc: newCollector()}
+}
+
+// Reconfigure applies a reloaded config. pending lists keys that changed on
+// disk but only take effect after a restart.
+func (h *Handler) Reconfigure(cfg config.Config, src *config.Source, pending []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cfg, h.source, h.pending = cfg, src, pending
+}
+
+func (h *Handler) config() config.Config {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.cfg
 }
 
 func (h *Handler) Router() http.Handler {
 	r := chi.NewRouter()
+	r.Get("/readyz", h.readyz)
 	r.Post("/runs", h.createRun)
 	r.Get("/runs/{run_id}", h.getRun)
 	r.Get("/runs/{run_id}/logs", h.getRunLogs)
+	r.Post("/runs/{run_id}/wait", h.waitRun)
+	r.Post("/runs/{run_id}/keepalive", h.keepaliveRun)
 	r.Post("/runs/{run_id}/stop", h.stopRun)
+	r.Post("/runs/{run_id}/quarantine", h.quarantineRun)
 	r.Post("/runs/{run_id}/tools/{tool_name}", h.invokeTool)
+	r.Post("/policy/evaluate", h.evaluatePolicy)
+	if h.config().AdminToken != "" {
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(h.requireAdmin)
+			r.Post("/policy-exceptions", h.createException)
+			r.Get("/policy-exceptions", h.listExceptions)
+			r.Delete("/policy-exceptions/{exception_id}", h.revokeException)
+			r.Get("/config", h.getConfig)
+			r.Get("/pool", h.getPool)
+			r.Get("/gc", h.getGC)
+			r.Get("/quarantine", h.listQuarantined)
+			r.Get("/quarantine/{run_id}", h.getQuarantined)
+			r.Post("/quarantine/{run_id}/release", h.releaseQuarantined)
+			r.Delete("/quarantine/{run_id}", h.deleteQuarantined)
+		})
+	}
 	return r
 }
 
@@ -45,71 +94,113 @@ func (h *Handler) createRun(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
-	if err := validateCreateRequest(req); err != nil {
+	if !h.selfCheckReport().Ready {
+		http.Error(w, "cluster self-check failing; see /readyz", http.StatusServiceUnavailable)
+		return
+	}
+	cfg := h.config()
+	if err := validateCreateRequest(req, cfg); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	pinnedRef, evidence, err := policy.Enforce(h.policyCfg, req.ImageRef)
-	if err != nil {
-		audit.Event("run_create_denied", map[string]any{"reason": err.Error(), "image_ref": req.ImageRef, "policy_evidence": evidence})
-		writeJSON(w, http.StatusForbidden, map[string]any{"error": "policy_denied", "policy_evidence": evidence})
+	adm := h.admit(r, req, false)
+	if adm.err != nil {
+		if adm.evidence.Env != nil && len(adm.evidence.Env.Rejected) > 0 {
+			audit.Event("env_rejected", map[string]any{"image_ref": req.ImageRef, "caller": adm.caller.Subject, "rejected": adm.evidence.Env.Rejected})
+		}
+		audit.Event("run_create_denied", map[string]any{"reason": adm.err.Error(), "image_ref": req.ImageRef, "caller": adm.caller.Subject, "policy_evidence": adm.evidence})
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "policy_denied", "policy_evidence": adm.evidence})
 		return
 	}
+	pinnedRef, evidence, caller := adm.pinnedRef, adm.evidence, adm.caller
 
 	runID := uuid.NewString()
-	cpu := defaultIfEmpty(req.CPU, h.cfg.DefaultCPU)
-	mem := defaultIfEmpty(req.Memory, h.cfg.DefaultMemory)
-	timeout := req.TimeoutSeconds
-	if timeout <= 0 {
-		timeout = h.cfg.DefaultTimeout
-	}
-	port := req.DownstreamPort
-	if port <= 0 {
-		port = 8080
-	}
+	res := evidence.Resources
+	port := downstreamPort(req)
+	mode := runMode(req)
 
-	podName, err := h.k8s.CreateRunPod(r.Context(), k8s.PodSpecInput{
-		Namespace:        h.cfg.Namespace,
+	podName, err := h.backend.CreateRunPod(r.Context(), k8s.PodSpecInput{
+		Namespace:        cfg.Namespace,
 		RunID:            runID,
+		RunMode:          mode,
+		NetworkProfile:   req.NetworkPolicyProfile,
 		ImageRef:         pinnedRef,
 		Command:          req.Command,
 		Args:             req.Args,
 		EnvAllowlist:     req.EnvAllowlist,
-		CPU:              cpu,
-		Memory:           mem,
-		TimeoutSeconds:   timeout,
-		RuntimeClassName: h.cfg.RuntimeClassName,
-		ImagePullPolicy:  corev1.PullPolicy(h.cfg.ImagePullPolicy),
+		Secrets:          adm.secrets,
+		SecretTTLSeconds: secretTTL(cfg, res.TimeoutSeconds),
+		CPURequest:       res.CPURequest,
+		CPULimit:         res.CPULimit,
+		MemoryRequest:    res.MemoryRequest,
+		MemoryLimit:      res.MemoryLimit,
+		EphemeralStorage: res.EphemeralStorage,
+		TimeoutSeconds:   res.TimeoutSeconds,
+		DownstreamPort:   port,
+		ReadinessProbe:   cfg.ReadinessProbe,
+		RuntimeClassName: cfg.RuntimeClassName,
+		ImagePullPolicy:  corev1.PullPolicy(cfg.ImagePullPolicy),
+
+		BackoffLimit:            int32(cfg.JobBackoffLimit),
+		TTLSecondsAfterFinished: int32(cfg.JobTTLSeconds),
 	})
 	if err != nil {
-		audit.Event("run_create_denied", map[string]any{"reason": err.Error(), "image_ref": req.ImageRef, "policy_evidence": evidence})
-		http.Error(w, "pod creation failed", http.StatusInternalServerError)
+		failure := classifyMessage(err.Error())
+		if failure == "" {
+			failure = failureUnknown
+		}
+		// The API error can name cluster internals (namespaces, quotas,
+		// webhooks); it goes to the audit log, not to the caller.
+		audit.Event("run_create_denied", map[string]any{"reason": err.Error(), "failure_reason": failure, "image_ref": req.ImageRef, "policy_evidence": evidence})
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "pod_creation_failed", "failure_reason": failure})
 		return
 	}
 
 	allowed := map[string]struct{}{}
-	for _, t := range req.AllowedTools {
-		if tt := strings.TrimSpace(t); tt != "" {
-			allowed[tt] = struct{}{}
-		}
+	for _, t := range adm.allowedTools {
+		allowed[t] = struct{}{}
 	}
 
+	idle := idleTimeout(cfg, req.IdleTimeoutSeconds)
 	h.store.Put(runs.Run{
 		RunID:          runID,
 		PodName:        podName,
-		Namespace:      h.cfg.Namespace,
+		RunMode:        mode,
+		Namespace:      cfg.Namespace,
 		Status:         "starting",
 		CreatedAt:      time.Now().UTC(),
 		ImageDigest:    evidence.ResolvedDigest,
 		PolicyEvidence: evidence,
 		AllowedTools:   allowed,
 		DownstreamPort: port,
+		NetworkProfile: req.NetworkPolicyProfile,
+		Secrets:        req.Secrets,
+
+		IdleTimeoutSeconds: idle,
+		LastActivity:       time.Now().UTC(),
 	})
-	h.k8s.WaitAndDelete(h.cfg.Namespace, podName, h.cfg.CleanupSeconds)
-	audit.Event("run_created", map[string]any{"run_id": runID, "pod_name": podName, "runtime_class": h.cfg.RuntimeClassName, "image_digest": evidence.ResolvedDigest, "network_policy_profile": req.NetworkPolicyProfile, "policy_evidence": evidence})
+	for _, x := range evidence.ExceptionsUsed {
+		audit.Event("policy_exception_used", map[string]any{"run_id": runID, "caller": caller.Subject, "exception_id": x.ID, "check": x.Check, "waived_failure": x.Waived, "image_digest": evidence.ResolvedDigest, "expires_at": x.ExpiresAt})
+	}
+	audit.Event("run_created", map[string]any{"run_id": runID, "caller": caller.Subject, "pod_name": podName, "run_mode": mode, "idle_timeout_seconds": idle, "runtime_class": cfg.RuntimeClassName, "image_digest": evidence.ResolvedDigest, "network_policy_profile": req.NetworkPolicyProfile, "secrets": req.Secrets, "policy_evidence": evidence})
+
+	resp := CreateRunResponse{RunID: runID, PodName: podName, RunMode: mode, ImageDigest: evidence.ResolvedDigest, PolicyEvidence: evidence}
+	if req.WaitForReady {
+		run, _ := h.store.Get(runID)
+		wr := h.waitReady(r.Context(), run, readyTimeout(cfg, req.ReadyTimeoutSeconds), req.MCPHandshake)
+		resp.Ready, resp.ReadyError = &wr.Ready, wr.Error
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
 
-	writeJSON(w, http.StatusCreated, CreateRunResponse{RunID: runID, PodName: podName, ImageDigest: evidence.ResolvedDigest, PolicyEvidence: evidence})
+// secretTTL bounds how long a run's secret copy outlives pod start. By default
+// it lasts as long as the run may, plus the cleanup grace period.
+func secretTTL(cfg config.Config, timeoutSeconds int64) int64 {
+	if cfg.SecretTTLSeconds > 0 {
+		return cfg.SecretTTLSeconds
+	}
+	return timeoutSeconds + cfg.CleanupSeconds
 }
 
 func (h *Handler) getRun(w http.ResponseWriter, r *http.Request) {
@@ -120,10 +211,11 @@ func (h *Handler) getRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	status, reason, err := h.k8s.GetPodStatus(r.Context(), run.Namespace, run.PodName)
+	status, reason, err := h.backend.GetPodStatus(r.Context(), run.Namespace, run.PodName)
 	podIP := ""
-	if err == nil {
-		podIP, _ = h.k8s.GetPodIP(r.Context(), run.Namespace, run.PodName)
+	// A run the runner stopped keeps its stop status; its pod is gone.
+	if err == nil && run.FinishedAt == nil {
+		podIP, _ = h.backend.GetPodIP(r.Context(), run.Namespace, run.PodName)
 		_ = h.store.Update(runID, func(orig runs.Run) runs.Run {
 			orig.Status = strings.ToLower(status)
 			orig.Reason = reason
@@ -131,8 +223,10 @@ func (h *Handler) getRun(w http.ResponseWriter, r *http.Request) {
 		})
 		run, _ = h.store.Get(runID)
 	}
+	diag := h.diagnose(r.Context(), run)
 
-	writeJSON(w, http.StatusOK, RunStatusResponse{RunID: run.RunID, Status: run.Status, PodName: run.PodName, Namespace: run.Namespace, Reason: run.Reason, PodIP: podIP, ImageDigest: run.ImageDigest, PolicyEvidence: run.PolicyEvidence})
+	writeJSON(w, http.StatusOK, RunStatusResponse{RunID: run.RunID, Status: run.Status, PodName: run.PodName, RunMode: run.RunMode, Namespace: run.Namespace, Reason: run.Reason, PodIP: podIP, ImageDigest: run.ImageDigest, PolicyEvidence: run.PolicyEvidence, IdleTimeoutSeconds: run.IdleTimeoutSeconds, LastActivity: run.LastActivity, QuarantinedAt: run.QuarantinedAt,
+		FailureReason: diag.reason, Events: diag.Events, Containers: diag.Containers})
 }
 
 func (h *Handler) getRunLogs(w http.ResponseWriter, r *http.Request) {
@@ -142,7 +236,7 @@ func (h *Handler) getRunLogs(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
-	logs, err := h.k8s.GetPodLogs(r.Context(), run.Namespace, run.PodName)
+	logs, err := h.backend.GetPodLogs(r.Context(), run.Namespace, run.PodName)
 	if err != nil {
 		http.Error(w, "unable to fetch logs", http.StatusBadGateway)
 		return
@@ -157,10 +251,18 @@ func (h *Handler) stopRun(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
-	if err := h.k8s.DeletePod(r.Context(), run.Namespace, run.PodName); err != nil {
+	// Only an admin may end a quarantine; see deleteQuarantined.
+	if run.QuarantinedAt != nil {
+		http.Error(w, "run is quarantined", http.StatusConflict)
+		return
+	}
+	if err := h.backend.DeletePod(r.Context(), run.Namespace, run.PodName); err != nil {
 		http.Error(w, "stop failed", http.StatusInternalServerError)
 		return
 	}
+	if len(run.Secrets) > 0 {
+		_ = h.backend.DeleteRunSecret(r.Context(), run.Namespace, k8s.RunSecretName(run.PodName))
+	}
 	_ = h.store.Update(runID, func(orig runs.Run) runs.Run {
 		orig.Status = "stopped"
 		orig.StoppedByAP = true
@@ -183,6 +285,7 @@ func (h *Handler) invokeTool(w http.ResponseWriter, r *http.Request) {
 	if len(run.AllowedTools) > 0 {
 		if _, ok := run.AllowedTools[toolName]; !ok {
 			audit.Event("tool_scope_violation", map[string]any{"run_id": runID, "tool_name": toolName})
+			h.autoQuarantine(run, "tool_scope_violation", "tool "+toolName+" is outside the run's allowed_tools", callerIdentity(r).Subject)
 			http.Error(w, "tool not allowed for this run", http.StatusForbidden)
 			return
 		}
@@ -192,13 +295,20 @@ func (h *Handler) invokeTool(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
-	podIP, err := h.k8s.GetPodIP(r.Context(), run.Namespace, run.PodName)
+	done, ok := h.beginCall(runID)
+	if !ok {
+		run, _ = h.store.Get(runID)
+		http.Error(w, "run is "+run.Status, http.StatusConflict)
+		return
+	}
+	defer done()
+	podIP, err := h.backend.GetPodIP(r.Context(), run.Namespace, run.PodName)
 	if err != nil || podIP == "" {
 		http.Error(w, "pod ip unavailable", http.StatusBadGateway)
 		return
 	}
 	payload, _ := json.Marshal(req)
-	body, status, err := h.k8s.InvokeTool(r.Context(), podIP, run.DownstreamPort, toolName, payload)
+	body, status, err := h.backend.InvokeTool(r.Context(), podIP, run.DownstreamPort, toolName, payload)
 	if err != nil {
 		http.Error(w, "downstream call failed", http.StatusBadGateway)
 		return
@@ -211,26 +321,41 @@ func (h *Handler) invokeTool(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
-func validateCreateRequest(req CreateRunRequest) error {
-	if strings.TrimSpace(req.ImageRef) == "" {
-		return errors.New("image_ref is required")
+// callerIdentity prefers the verified mTLS client certificate and otherwise
+// takes the X-Runner-Caller header. Any client can set the header, so a
+// "header" identity is a label for audit, never an authorization; decisions
+// that depend on who the caller is must require Source "mtls".
+func callerIdentity(r *http.Request) policy.Caller {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		cert := r.TLS.VerifiedChains[0][0]
+		if len(cert.URIs) > 0 {
+			return policy.Caller{Subject: cert.URIs[0].String(), Source: "mtls"}
+		}
+		return policy.Caller{Subject: cert.Subject.CommonName, Source: "mtls"}
 	}
-	switch req.NetworkPolicyProfile {
-	case "deny-all", "dns-only":
-	default:
-		return errors.New("network_policy_profile must be deny-all or dns-only")
+	if v := strings.TrimSpace(r.Header.Get("X-Runner-Caller")); v != "" {
+		return policy.Caller{Subject: v, Source: "header"}
 	}
-	if req.TimeoutSeconds < 0 {
-		return errors.New("timeout_seconds must be >= 0")
+	return policy.Caller{Subject: "anonymous", Source: "none"}
+}
+
+// adminActor names who performed an admin call for the record. Only an mTLS
+// identity is trusted; otherwise all that is known is that the admin token
+// was presented.
+func adminActor(r *http.Request) string {
+	if p := authenticatedSubject(callerIdentity(r)); p != "" {
+		return p
 	}
-	return nil
+	return "admin-token"
 }
 
-func defaultIfEmpty(v, fallback string) string {
-	if strings.TrimSpace(v) == "" {
-		return fallback
+// authenticatedSubject is the caller's subject when it comes from a verified
+// client certificate, and empty otherwise.
+func authenticatedSubject(c policy.Caller) string {
+	if c.Source == "mtls" {
+		return c.Subject
 	}
-	return v
+	return ""
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
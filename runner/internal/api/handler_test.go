@@ -0,0 +1,1326 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mcp-orc/runner/internal/backend/fake"
+	"github.com/mcp-orc/runner/internal/config"
+	"github.com/mcp-orc/runner/internal/exceptions"
+	"github.com/mcp-orc/runner/internal/k8s"
+	"github.com/mcp-orc/runner/internal/policy"
+	"github.com/mcp-orc/runner/internal/quarantine"
+	"github.com/mcp-orc/runner/internal/runs"
+	"github.com/mcp-orc/runner/internal/secrets"
+)
+
+const (
+	testImage  = "ghcr.io/acme/mcp-echo@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	adminToken = "test-admin-token"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+type testEnv struct {
+	t         *testing.T
+	h         *Handler
+	backend   *fake.Backend
+	store     *runs.Store
+	bundles   *quarantine.Store
+	bundleDir string
+	server    http.Handler
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	secretDir := t.TempDir()
+	secret := `{"allowed_images": ["ghcr.io/acme/mcp-echo"], "data": {"token": "s3cr3t"}}`
+	if err := os.WriteFile(filepath.Join(secretDir, "echo-token.json"), []byte(secret), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]string{
+		"RUNNER_ADMIN_TOKEN":            adminToken,
+		"RUNNER_ALLOWLISTED_REGISTRIES": "ghcr.io/acme",
+		"RUNNER_REQUIRE_COSIGN":         "false",
+		"RUNNER_INSPECT_IMAGE_CONFIG":   "false",
+		"RUNNER_SECRETS_BACKEND":        "file",
+		"RUNNER_SECRETS_DIR":            secretDir,
+	}
+	lookup := func(k string) string { return values[k] }
+	cfg, err := config.From(lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policyCfg, err := policy.ConfigFrom(lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex, err := exceptions.NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, store := fake.New(), runs.NewStore()
+	broker := secrets.NewBroker(secrets.FileBackend{Dir: secretDir})
+	bundleDir := t.TempDir()
+	qs, err := quarantine.NewStore(bundleDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(cfg, policy.NewEnforcer(policyCfg, nil, ex), nil, b, store, ex, broker, qs)
+	return &testEnv{t: t, h: h, backend: b, store: store, bundles: qs, bundleDir: bundleDir, server: h.Router()}
+}
+
+func (e *testEnv) do(method, path, body string, header ...string) *httptest.ResponseRecorder {
+	e.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	e.server.ServeHTTP(rec, req)
+	return rec
+}
+
+func (e *testEnv) createRun(body string) CreateRunResponse {
+	e.t.Helper()
+	rec := e.do(http.MethodPost, "/runs", body)
+	if rec.Code != http.StatusCreated {
+		e.t.Fatalf("create run: %d %s", rec.Code, rec.Body)
+	}
+	var out CreateRunResponse
+	decode(e.t, rec, &out)
+	return out
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+}
+
+func runBody(extra string) string {
+	return `{"image_ref": "` + testImage + `", "network_policy_profile": "deny-all"` + extra + `}`
+}
+
+func TestCreateRun(t *testing.T) {
+	e := newTestEnv(t)
+	out := e.createRun(runBody(`, "allowed_tools": ["echo"], "cpu": "250m", "memory": "256Mi", "timeout_seconds": 60`))
+	if out.RunID == "" || out.PodName != "run-"+out.RunID || out.RunMode != "pod" || !strings.HasPrefix(out.ImageDigest, "sha256:") {
+		t.Fatalf("unexpected response %+v", out)
+	}
+	pod, ok := e.backend.Pod("mcp-runs", out.PodName)
+	if !ok {
+		t.Fatal("pod was not created")
+	}
+	if pod.Spec.ImageRef != testImage || pod.Spec.NetworkProfile != "deny-all" || pod.Spec.CPURequest != "250m" || pod.Spec.MemoryLimit != "256Mi" || pod.Spec.TimeoutSeconds != 60 {
+		t.Errorf("pod spec %+v", pod.Spec)
+	}
+	run, err := e.store.Get(out.RunID)
+	if err != nil || run.Status != "starting" {
+		t.Fatalf("run not stored: %v %+v", err, run)
+	}
+	if _, ok := run.AllowedTools["echo"]; !ok || len(run.AllowedTools) != 1 {
+		t.Errorf("allowed tools %v", run.AllowedTools)
+	}
+}
+
+func TestCreateJobRun(t *testing.T) {
+	e := newTestEnv(t)
+	out := e.createRun(runBody(`, "run_mode": "job", "timeout_seconds": 60`))
+	if out.PodName != "job-"+out.RunID || out.RunMode != "job" {
+		t.Fatalf("unexpected response %+v", out)
+	}
+	pod, ok := e.backend.Pod("mcp-runs", out.PodName)
+	if !ok {
+		t.Fatal("job was not created")
+	}
+	if pod.Spec.RunMode != "job" || pod.Spec.BackoffLimit != 2 || pod.Spec.TTLSecondsAfterFinished != 300 {
+		t.Errorf("job spec %+v", pod.Spec)
+	}
+	var status RunStatusResponse
+	decode(t, e.do(http.MethodGet, "/runs/"+out.RunID, ""), &status)
+	if status.RunMode != "job" || status.PodName != out.PodName {
+		t.Errorf("status %+v", status)
+	}
+}
+
+func TestCreateRunRejections(t *testing.T) {
+	e := newTestEnv(t)
+	cases := map[string]struct {
+		body   string
+		status int
+		reason string
+	}{
+		"invalid json":      {`{`, http.StatusBadRequest, ""},
+		"missing image":     {`{"network_policy_profile": "deny-all"}`, http.StatusBadRequest, ""},
+		"unknown profile":   {`{"image_ref": "` + testImage + `", "network_policy_profile": "open"}`, http.StatusBadRequest, ""},
+		"bad quantity":      {runBody(`, "cpu": "lots"`), http.StatusBadRequest, ""},
+		"not allowlisted":   {`{"image_ref": "docker.io/evil/x@sha256:` + strings.Repeat("a", 64) + `", "network_policy_profile": "deny-all"}`, http.StatusForbidden, "registry_not_allowlisted"},
+		"over cpu ceiling":  {runBody(`, "cpu": "64"`), http.StatusForbidden, "resource_limits_exceeded"},
+		"secret in env":     {runBody(`, "env_allowlist": {"DB_PASSWORD": "x"}`), http.StatusForbidden, "env_rejected"},
+		"unknown secret":    {runBody(`, "secrets": ["missing"]`), http.StatusForbidden, "secret_unavailable"},
+		"command override":  {runBody(`, "command": ["/bin/sh"]`), http.StatusForbidden, "entrypoint_override_denied"},
+		"args without rule": {runBody(`, "args": ["--debug"]`), http.StatusForbidden, "args_not_allowed"},
+		"unknown run mode":  {runBody(`, "run_mode": "daemonset"`), http.StatusBadRequest, ""},
+		"ready timeout":     {runBody(`, "wait_for_ready": true, "ready_timeout_seconds": 3600`), http.StatusBadRequest, ""},
+		"idle timeout":      {runBody(`, "idle_timeout_seconds": 86400`), http.StatusBadRequest, ""},
+	}
+	for label, c := range cases {
+		rec := e.do(http.MethodPost, "/runs", c.body)
+		if rec.Code != c.status {
+			t.Errorf("%s: status %d, want %d (%s)", label, rec.Code, c.status, rec.Body)
+			continue
+		}
+		if c.reason != "" {
+			var out struct {
+				Error          string          `json:"error"`
+				PolicyEvidence policy.Evidence `json:"policy_evidence"`
+			}
+			decode(t, rec, &out)
+			if out.Error != "policy_denied" || out.PolicyEvidence.DenialReason != c.reason {
+				t.Errorf("%s: got %s/%s, want %s", label, out.Error, out.PolicyEvidence.DenialReason, c.reason)
+			}
+		}
+	}
+	if len(e.backend.Pods()) != 0 {
+		t.Error("rejected requests created pods")
+	}
+}
+
+func TestCreateRunBackendFailure(t *testing.T) {
+	e := newTestEnv(t)
+	e.backend.CreateErr = errors.New("quota exceeded")
+	if rec := e.do(http.MethodPost, "/runs", runBody("")); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d", rec.Code)
+	}
+}
+
+func TestCreateRunWithSecret(t *testing.T) {
+	e := newTestEnv(t)
+	rec := e.do(http.MethodPost, "/runs", runBody(`, "secrets": ["echo-token"]`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status %d %s", rec.Code, rec.Body)
+	}
+	if bytes.Contains(rec.Body.Bytes(), []byte("s3cr3t")) {
+		t.Fatal("secret value echoed in response")
+	}
+	var out CreateRunResponse
+	decode(t, rec, &out)
+	pod, _ := e.backend.Pod("mcp-runs", out.PodName)
+	if len(pod.Spec.Secrets) != 1 || string(pod.Spec.Secrets[0].Data["token"]) != "s3cr3t" || pod.Spec.SecretTTLSeconds != 300+120 {
+		t.Fatalf("secret not passed to backend: %+v", pod.Spec)
+	}
+
+	if rec := e.do(http.MethodPost, "/runs/"+out.RunID+"/stop", ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("stop: %d", rec.Code)
+	}
+	if got := e.backend.DeletedSecrets(); len(got) != 1 || got[0] != "mcp-runs/"+out.PodName+"-secrets" {
+		t.Errorf("run secret not deleted on stop: %v", got)
+	}
+}
+
+func TestGetRun(t *testing.T) {
+	e := newTestEnv(t)
+	out := e.createRun(runBody(""))
+
+	rec := e.do(http.MethodGet, "/runs/"+out.RunID, "")
+	var st RunStatusResponse
+	decode(t, rec, &st)
+	if rec.Code != http.StatusOK || st.Status != "running" || st.PodIP == "" || st.ImageDigest != out.ImageDigest {
+		t.Fatalf("status %d %+v", rec.Code, st)
+	}
+
+	e.backend.SetPhase("mcp-runs", out.PodName, "Failed", "DeadlineExceeded")
+	decode(t, e.do(http.MethodGet, "/runs/"+out.RunID, ""), &st)
+	if st.Status != "failed" || st.Reason != "DeadlineExceeded" {
+		t.Errorf("phase change not reflected: %+v", st)
+	}
+
+	e.backend.StatusErr = errors.New("apiserver unavailable")
+	decode(t, e.do(http.MethodGet, "/runs/"+out.RunID, ""), &st)
+	if st.Status != "failed" {
+		t.Errorf("backend error should keep last known status, got %+v", st)
+	}
+
+	if rec := e.do(http.MethodGet, "/runs/nope", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown run: %d", rec.Code)
+	}
+}
+
+func TestGetRunDiagnostics(t *testing.T) {
+	e := newTestEnv(t)
+	out := e.createRun(runBody(""))
+	e.do(http.MethodGet, "/runs/"+out.RunID, "")
+	backoff := k8s.Event{Object: "Pod/" + out.PodName, Type: "Warning", Reason: "BackOff", Message: "Back-off restarting failed container untrusted-mcp", Count: 3}
+	e.backend.SetDiagnostics("mcp-runs", out.PodName, k8s.PodDiagnostics{
+		Containers: []k8s.ContainerState{{Name: "untrusted-mcp", State: "waiting", Reason: "CrashLoopBackOff", RestartCount: 4}},
+		Events:     []k8s.Event{backoff},
+	})
+
+	var st RunStatusResponse
+	decode(t, e.do(http.MethodGet, "/runs/"+out.RunID, ""), &st)
+	if st.Status != "running" || st.FailureReason != "crash_loop" || len(st.Events) != 1 || st.Events[0].Count != 3 || st.Containers[0].RestartCount != 4 {
+		t.Fatalf("status %+v", st)
+	}
+
+	// The reason is kept once the pod and its events are gone.
+	e.backend.SetDiagnostics("mcp-runs", out.PodName, k8s.PodDiagnostics{})
+	e.backend.SetPhase("mcp-runs", out.PodName, "Failed", "")
+	if err := e.h.CollectGarbage(context.Background(), time.Now().UTC()); err != nil {
+		t.Fatal(err)
+	}
+	e.backend.DeletePod(context.Background(), "mcp-runs", out.PodName)
+	decode(t, e.do(http.MethodGet, "/runs/"+out.RunID, ""), &st)
+	if st.Status != "failed" || st.FailureReason != "crash_loop" || st.Events == nil {
+		t.Errorf("status after pod deletion %+v", st)
+	}
+
+	healthy := e.createRun(runBody(""))
+	var hs RunStatusResponse
+	decode(t, e.do(http.MethodGet, "/runs/"+healthy.RunID, ""), &hs)
+	if hs.Status != "running" || hs.FailureReason != "" {
+		t.Errorf("healthy run %+v", hs)
+	}
+}
+
+func TestCreateRunFailureReason(t *testing.T) {
+	e := newTestEnv(t)
+	e.backend.CreateErr = errors.New(`pods "run-1" is forbidden: pod rejected: RuntimeClass "gvisor" not found`)
+	rec := e.do(http.MethodPost, "/runs", runBody(""))
+	var body map[string]string
+	decode(t, rec, &body)
+	if rec.Code != http.StatusInternalServerError || body["failure_reason"] != "runtime_class_not_found" {
+		t.Fatalf("%d %v", rec.Code, body)
+	}
+	if _, ok := body["message"]; ok || strings.Contains(rec.Body.String(), "gvisor") {
+		t.Errorf("API error leaked to the caller: %s", rec.Body)
+	}
+
+	e.backend.CreateErr = errors.New("etcdserver: request timed out")
+	body = nil
+	decode(t, e.do(http.MethodPost, "/runs", runBody("")), &body)
+	if body["error"] != "pod_creation_failed" || body["failure_reason"] != "unknown" {
+		t.Errorf("unclassified error: %v", body)
+	}
+}
+
+func TestFailureReason(t *testing.T) {
+	exit := func(code int32) *int32 { return &code }
+	warning := func(reason, msg string) k8s.Event { return k8s.Event{Type: "Warning", Reason: reason, Message: msg} }
+	for _, tc := range []struct {
+		name, status, reason string
+		diag                 k8s.PodDiagnostics
+		want                 string
+	}{
+		{name: "healthy", status: "running", want: ""},
+		{name: "pending without events", status: "pending", want: ""},
+		{name: "image pull backoff", status: "pending", diag: k8s.PodDiagnostics{Containers: []k8s.ContainerState{{State: "waiting", Reason: "ErrImagePull"}}}, want: "image_pull_failed"},
+		{name: "crash loop while running", status: "running", diag: k8s.PodDiagnostics{Containers: []k8s.ContainerState{{State: "waiting", Reason: "CrashLoopBackOff"}}}, want: "crash_loop"},
+		{name: "webhook rejects job pod", status: "pending", diag: k8s.PodDiagnostics{Events: []k8s.Event{warning("FailedCreate", `Error creating: admission webhook "validate.kyverno.svc" denied the request`)}}, want: "admission_rejected"},
+		{name: "runtime class missing", status: "pending", diag: k8s.PodDiagnostics{Events: []k8s.Event{warning("FailedCreate", `Error creating: pods "job-1-x" is forbidden: pod rejected: RuntimeClass "gvisor" not found`)}}, want: "runtime_class_not_found"},
+		{name: "runtime handler missing on node", status: "pending", diag: k8s.PodDiagnostics{Events: []k8s.Event{warning("FailedCreatePodSandBox", `no runtime for "runsc" is configured`)}}, want: "runtime_class_not_found"},
+		{name: "unschedulable", status: "pending", diag: k8s.PodDiagnostics{Events: []k8s.Event{warning("FailedScheduling", "0/3 nodes are available")}}, want: "unschedulable"},
+		{name: "scheduled after all", status: "pending", diag: k8s.PodDiagnostics{Events: []k8s.Event{warning("FailedScheduling", "0/3 nodes are available"), {Type: "Normal", Reason: "Scheduled"}}}, want: ""},
+		{name: "oom", status: "failed", diag: k8s.PodDiagnostics{Containers: []k8s.ContainerState{{State: "terminated", Reason: "OOMKilled", ExitCode: exit(137)}}}, want: "oom_killed"},
+		{name: "deadline", status: "failed", reason: "DeadlineExceeded", want: "deadline_exceeded"},
+		{name: "exit code", status: "failed", diag: k8s.PodDiagnostics{Containers: []k8s.ContainerState{{State: "terminated", Reason: "Error", ExitCode: exit(2)}}}, want: "container_exit_nonzero"},
+		{name: "job retries exhausted", status: "failed", reason: "BackoffLimitExceeded", want: "backoff_limit_exceeded"},
+		{name: "failed without detail", status: "failed", want: "unknown"},
+	} {
+		if got := failureReason(tc.status, tc.reason, tc.diag); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestGetRunLogs(t *testing.T) {
+	e := newTestEnv(t)
+	out := e.createRun(runBody(""))
+	e.backend.SetLogs("mcp-runs", out.PodName, "listening on :8080\n")
+
+	rec := e.do(http.MethodGet, "/runs/"+out.RunID+"/logs", "")
+	var logs LogsResponse
+	decode(t, rec, &logs)
+	if rec.Code != http.StatusOK || logs.RunID != out.RunID || logs.Stdout != "listening on :8080\n" {
+		t.Fatalf("logs %d %+v", rec.Code, logs)
+	}
+
+	e.backend.LogsErr = errors.New("stream closed")
+	if rec := e.do(http.MethodGet, "/runs/"+out.RunID+"/logs", ""); rec.Code != http.StatusBadGateway {
+		t.Errorf("backend error: %d", rec.Code)
+	}
+	if rec := e.do(http.MethodGet, "/runs/nope/logs", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown run: %d", rec.Code)
+	}
+}
+
+func TestStopRun(t *testing.T) {
+	e := newTestEnv(t)
+	out := e.createRun(runBody(""))
+
+	e.backend.DeleteErr = errors.New("forbidden")
+	if rec := e.do(http.MethodPost, "/runs/"+out.RunID+"/stop", ""); rec.Code != http.StatusInternalServerError {
+		t.Errorf("backend error: %d", rec.Code)
+	}
+	e.backend.DeleteErr = nil
+
+	if rec := e.do(http.MethodPost, "/runs/"+out.RunID+"/stop", ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("stop: %d", rec.Code)
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", out.PodName); !pod.Deleted {
+		t.Error("pod not deleted")
+	}
+	run, _ := e.store.Get(out.RunID)
+	if run.Status != "stopped" || !run.StoppedByAP || run.FinishedAt == nil {
+		t.Errorf("run not marked stopped: %+v", run)
+	}
+	if len(e.backend.DeletedSecrets()) != 0 {
+		t.Error("secret deletion attempted for a run without secrets")
+	}
+	if rec := e.do(http.MethodPost, "/runs/nope/stop", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown run: %d", rec.Code)
+	}
+}
+
+func TestWaitRun(t *testing.T) {
+	e := newTestEnv(t)
+	out := e.createRun(runBody(`, "downstream_port": 9000`))
+	path := "/runs/" + out.RunID + "/wait"
+
+	var resp WaitResponse
+	rec := e.do(http.MethodPost, path, "")
+	decode(t, rec, &resp)
+	if rec.Code != http.StatusOK || !resp.Ready || resp.Status != "running" || resp.Handshake != "skipped" {
+		t.Fatalf("wait %d %+v", rec.Code, resp)
+	}
+
+	e.backend.SetReady("mcp-runs", out.PodName, false)
+	rec = e.do(http.MethodPost, path, `{"timeout_seconds": 1}`)
+	decode(t, rec, &resp)
+	if rec.Code != http.StatusGatewayTimeout || resp.Ready || resp.Error != "pod not ready" {
+		t.Errorf("not ready: %d %+v", rec.Code, resp)
+	}
+	e.backend.SetReady("mcp-runs", out.PodName, true)
+
+	rec = e.do(http.MethodPost, path, `{"timeout_seconds": 1, "mcp_handshake": true}`)
+	decode(t, rec, &resp)
+	if rec.Code != http.StatusGatewayTimeout || resp.Handshake != "failed" {
+		t.Errorf("handshake without a server: %d %+v", rec.Code, resp)
+	}
+	e.backend.SetTool("/mcp", 200, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":\"runner-ready\",\"result\":{\"protocolVersion\":\"2025-06-18\"}}\n\n")
+	rec = e.do(http.MethodPost, path, `{"mcp_handshake": true}`)
+	decode(t, rec, &resp)
+	if rec.Code != http.StatusOK || resp.Handshake != "ok" {
+		t.Fatalf("handshake: %d %+v", rec.Code, resp)
+	}
+	calls := e.backend.Calls()
+	if last := calls[len(calls)-1]; last.Tool != "/mcp" || last.Port != 9000 || !bytes.Contains(last.Payload, []byte(`"method":"initialize"`)) {
+		t.Errorf("handshake call %+v", last)
+	}
+
+	e.backend.SetPhase("mcp-runs", out.PodName, "Failed", "Error")
+	rec = e.do(http.MethodPost, path, "")
+	decode(t, rec, &resp)
+	if rec.Code != http.StatusConflict || resp.Status != "failed" || resp.Reason != "Error" {
+		t.Errorf("finished run: %d %+v", rec.Code, resp)
+	}
+	if rec := e.do(http.MethodPost, path, `{"timeout_seconds": -1}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("negative timeout: %d", rec.Code)
+	}
+	if rec := e.do(http.MethodPost, "/runs/nope/wait", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown run: %d", rec.Code)
+	}
+}
+
+func TestCreateRunWaitForReady(t *testing.T) {
+	e := newTestEnv(t)
+	e.backend.SetTool("/mcp", 200, `{"jsonrpc":"2.0","id":"runner-ready","result":{"protocolVersion":"2025-06-18"}}`)
+	out := e.createRun(runBody(`, "wait_for_ready": true, "mcp_handshake": true`))
+	if out.Ready == nil || !*out.Ready || out.ReadyError != "" {
+		t.Fatalf("unexpected response %+v", out)
+	}
+	pod, _ := e.backend.Pod("mcp-runs", out.PodName)
+	if !pod.Spec.ReadinessProbe {
+		t.Error("readiness probe not requested")
+	}
+	if run, _ := e.store.Get(out.RunID); run.Status != "running" {
+		t.Errorf("run status %q", run.Status)
+	}
+	if out := e.createRun(runBody("")); out.Ready != nil {
+		t.Errorf("ready reported without wait_for_ready: %+v", out)
+	}
+}
+
+func TestCheckInitialize(t *testing.T) {
+	cases := map[string]struct {
+		body   string
+		status int
+		ok     bool
+	}{
+		"json":        {`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-06-18"}}`, 200, true},
+		"sse":         {"event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"protocolVersion\":\"2025-03-26\"}}\n", 200, true},
+		"rpc error":   {`{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"bad"}}`, 200, false},
+		"no version":  {`{"jsonrpc":"2.0","id":1,"result":{}}`, 200, false},
+		"http error":  {`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-06-18"}}`, 503, false},
+		"not jsonrpc": {`<html>`, 200, false},
+	}
+	for label, c := range cases {
+		if err := checkInitialize([]byte(c.body), c.status); (err == nil) != c.ok {
+			t.Errorf("%s: err = %v", label, err)
+		}
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	e := newTestEnv(t)
+	idle := e.createRun(runBody(`, "allowed_tools": ["echo"], "idle_timeout_seconds": 30`))
+	plain := e.createRun(runBody(""))
+	slow := e.createRun(runBody(`, "idle_timeout_seconds": 30`))
+	for _, out := range []CreateRunResponse{idle, plain, slow} {
+		e.do(http.MethodGet, "/runs/"+out.RunID, "")
+	}
+	e.backend.SetReady("mcp-runs", slow.PodName, false)
+
+	var ka KeepaliveResponse
+	rec := e.do(http.MethodPost, "/runs/"+idle.RunID+"/keepalive", "")
+	decode(t, rec, &ka)
+	if rec.Code != http.StatusOK || ka.IdleTimeoutSeconds != 30 || ka.IdleExpiresAt == nil || !ka.IdleExpiresAt.Equal(ka.LastActivity.Add(30*time.Second)) {
+		t.Fatalf("keepalive %d %+v", rec.Code, ka)
+	}
+
+	now := time.Now().UTC()
+	if n := e.h.ReapIdle(context.Background(), now.Add(20*time.Second)); n != 0 {
+		t.Fatalf("reaped %d runs before the idle timeout", n)
+	}
+	_ = e.store.Update(idle.RunID, func(r runs.Run) runs.Run { r.InFlight = 1; return r })
+	if n := e.h.ReapIdle(context.Background(), now.Add(time.Minute)); n != 0 {
+		t.Fatal("reaped a run with a tool call in flight")
+	}
+	_ = e.store.Update(idle.RunID, func(r runs.Run) runs.Run { r.InFlight = 0; return r })
+
+	e.backend.DeleteErr = errors.New("forbidden")
+	if n := e.h.ReapIdle(context.Background(), now.Add(time.Minute)); n != 0 {
+		t.Fatal("failed deletion counted as reaped")
+	}
+	if run, _ := e.store.Get(idle.RunID); run.FinishedAt != nil {
+		t.Fatalf("failed reap left the run finished: %+v", run)
+	}
+	e.backend.DeleteErr = nil
+
+	if n := e.h.ReapIdle(context.Background(), now.Add(time.Minute)); n != 1 {
+		t.Fatalf("reaped %d runs, want 1", n)
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", idle.PodName); !pod.Deleted {
+		t.Error("idle pod not deleted")
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", plain.PodName); pod.Deleted {
+		t.Error("run without idle timeout reaped")
+	}
+	// The idle clock starts once the pod is Ready, not at creation.
+	if pod, _ := e.backend.Pod("mcp-runs", slow.PodName); pod.Deleted {
+		t.Error("run reaped before it was ever ready")
+	}
+	e.backend.SetReady("mcp-runs", slow.PodName, true)
+	if n := e.h.ReapIdle(context.Background(), now.Add(2*time.Minute)); n != 0 {
+		t.Fatal("reaped a run as it became ready")
+	}
+	if n := e.h.ReapIdle(context.Background(), now.Add(2*time.Minute+31*time.Second)); n != 1 {
+		t.Fatalf("reaped %d runs idle since becoming ready, want 1", n)
+	}
+	var status RunStatusResponse
+	decode(t, e.do(http.MethodGet, "/runs/"+idle.RunID, ""), &status)
+	if status.Status != "idle_expired" || status.IdleTimeoutSeconds != 30 {
+		t.Errorf("status %+v", status)
+	}
+	if rec := e.do(http.MethodPost, "/runs/"+idle.RunID+"/keepalive", ""); rec.Code != http.StatusConflict {
+		t.Errorf("keepalive after expiry: %d", rec.Code)
+	}
+	if rec := e.do(http.MethodPost, "/runs/"+idle.RunID+"/tools/echo", `{"input": {}}`); rec.Code != http.StatusConflict {
+		t.Errorf("tool call after expiry: %d", rec.Code)
+	}
+	if rec := e.do(http.MethodPost, "/runs/nope/keepalive", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown run: %d", rec.Code)
+	}
+}
+
+func TestToolCallsKeepRunAlive(t *testing.T) {
+	e := newTestEnv(t)
+	out := e.createRun(runBody(`, "allowed_tools": ["echo"], "idle_timeout_seconds": 30`))
+	e.do(http.MethodGet, "/runs/"+out.RunID, "")
+	before, _ := e.store.Get(out.RunID)
+	e.backend.SetTool("echo", 200, `{}`)
+	time.Sleep(10 * time.Millisecond)
+	if rec := e.do(http.MethodPost, "/runs/"+out.RunID+"/tools/echo", `{"input": {}}`); rec.Code != http.StatusOK {
+		t.Fatalf("invoke: %d", rec.Code)
+	}
+	after, _ := e.store.Get(out.RunID)
+	if !after.LastActivity.After(before.LastActivity) || after.InFlight != 0 {
+		t.Errorf("tool call not recorded as activity: before %v, after %+v", before.LastActivity, after)
+	}
+}
+
+func TestCollectGarbage(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	done := e.createRun(runBody(""))
+	failed := e.createRun(runBody(""))
+	serving := e.createRun(runBody(""))
+	stopped := e.createRun(runBody(""))
+	for _, out := range []CreateRunResponse{done, failed, serving} {
+		e.do(http.MethodGet, "/runs/"+out.RunID, "")
+	}
+	e.backend.SetPhase("mcp-runs", done.PodName, "Succeeded", "")
+	e.backend.SetPhase("mcp-runs", failed.PodName, "Failed", "DeadlineExceeded")
+	e.do(http.MethodPost, "/runs/"+stopped.RunID+"/stop", "")
+
+	now := time.Now().UTC()
+	if err := e.h.CollectGarbage(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	run, _ := e.store.Get(done.RunID)
+	if run.Status != "succeeded" || run.FinishedAt == nil {
+		t.Fatalf("finished run not recorded: %+v", run)
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", done.PodName); pod.Deleted {
+		t.Fatal("pod deleted before its retention")
+	}
+
+	// Default retention: 120s for succeeded and failed pods, records kept
+	// for an hour (succeeded, stopped) or a day (failed).
+	if err := e.h.CollectGarbage(ctx, now.Add(3*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	for _, out := range []CreateRunResponse{done, failed} {
+		if pod, _ := e.backend.Pod("mcp-runs", out.PodName); !pod.Deleted {
+			t.Errorf("%s: terminal pod kept past retention", out.PodName)
+		}
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", serving.PodName); pod.Deleted {
+		t.Error("running pod deleted")
+	}
+	if _, err := e.store.Get(done.RunID); err != nil {
+		t.Error("record deleted before its retention")
+	}
+
+	if err := e.h.CollectGarbage(ctx, now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]bool{done.RunID: false, stopped.RunID: false, failed.RunID: true, serving.RunID: true} {
+		if _, err := e.store.Get(id); (err == nil) != want {
+			t.Errorf("run %s kept = %v, want %v", id, err == nil, want)
+		}
+	}
+
+	var stats GCStats
+	decode(t, e.do(http.MethodGet, "/admin/gc", "", "Authorization", "Bearer "+adminToken), &stats)
+	if stats.Sweeps != 3 || stats.PodsDeleted["succeeded"] != 1 || stats.PodsDeleted["failed"] != 1 ||
+		stats.RecordsDeleted["succeeded"] != 1 || stats.RecordsDeleted["stopped"] != 1 || stats.RunsFinished["failed"] != 1 ||
+		stats.PodRetention["succeeded"] != 120 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestCollectGarbageOrphans(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	out := e.createRun(runBody(""))
+	e.store.Delete(out.RunID)
+	warm, _ := e.backend.CreateRunPod(ctx, k8s.PodSpecInput{Namespace: "mcp-runs", RunID: "warm-1", Warm: true})
+	gone := e.createRun(runBody(""))
+	e.backend.DeletePod(ctx, "mcp-runs", gone.PodName)
+
+	now := time.Now().UTC()
+	if err := e.h.CollectGarbage(ctx, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", out.PodName); pod.Deleted {
+		t.Error("orphan deleted before RUNNER_GC_ORPHAN_SECONDS")
+	}
+	if run, _ := e.store.Get(gone.RunID); run.Status != "not_found" || run.FinishedAt == nil {
+		t.Errorf("run without a pod: %+v", run)
+	}
+	if err := e.h.CollectGarbage(ctx, now.Add(10*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", out.PodName); !pod.Deleted {
+		t.Error("orphan kept")
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", warm); pod.Deleted {
+		t.Error("warm pool pod collected")
+	}
+
+	e.backend.ListErr = errors.New("forbidden")
+	if err := e.h.CollectGarbage(ctx, now); err == nil {
+		t.Error("list failure not reported")
+	}
+}
+
+func TestGCStatsDuringSweep(t *testing.T) {
+	e := newTestEnv(t)
+	out := e.createRun(runBody(""))
+	e.do(http.MethodGet, "/runs/"+out.RunID, "")
+	e.backend.SetPhase("mcp-runs", out.PodName, "Succeeded", "")
+	deleting, release := make(chan struct{}), make(chan struct{})
+	e.backend.BeforeDelete = func(string) {
+		close(deleting)
+		<-release
+	}
+	swept := make(chan error)
+	go func() { swept <- e.h.CollectGarbage(context.Background(), time.Now().UTC().Add(time.Hour)) }()
+	<-deleting
+
+	// The sweep is stuck in DeletePod; the counters must still be readable.
+	got := make(chan int)
+	go func() {
+		got <- e.do(http.MethodGet, "/admin/gc", "", "Authorization", "Bearer "+adminToken).Code
+	}()
+	select {
+	case code := <-got:
+		if code != http.StatusOK {
+			t.Errorf("GET /admin/gc during a sweep: %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("GET /admin/gc blocked on a sweep's backend call")
+	}
+	close(release)
+	if err := <-swept; err != nil {
+		t.Fatal(err)
+	}
+	var stats GCStats
+	decode(t, e.do(http.MethodGet, "/admin/gc", "", "Authorization", "Bearer "+adminToken), &stats)
+	if stats.Sweeps != 1 || stats.PodsDeleted["succeeded"] != 1 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestCollectGarbageExpiredSecrets(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	out := e.createRun(runBody(`, "secrets": ["echo-token"]`))
+	// A pod from a previous runner process: only the listing knows its expiry.
+	left, _ := e.backend.CreateRunPod(ctx, k8s.PodSpecInput{Namespace: "mcp-runs", RunID: "left-1", Secrets: []secrets.Secret{{Name: "echo-token"}}, SecretTTLSeconds: 60})
+
+	now := time.Now().UTC()
+	if err := e.h.CollectGarbage(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.backend.DeletedSecrets(); len(got) != 0 {
+		t.Fatalf("secrets deleted before their TTL: %v", got)
+	}
+	if err := e.h.CollectGarbage(ctx, now.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.backend.DeletedSecrets(); len(got) != 1 || got[0] != "mcp-runs/"+left+"-secrets" {
+		t.Fatalf("expired secret of a record-less pod: %v", got)
+	}
+	// The run's secret expires at timeout + cleanup (420s); its pod still runs.
+	// The record-less pod is collected as an orphan by then.
+	for range 2 {
+		if err := e.h.CollectGarbage(ctx, now.Add(8*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := e.backend.DeletedSecrets(); len(got) != 3 || got[1] != "mcp-runs/"+out.PodName+"-secrets" {
+		t.Errorf("expired run secret: %v", got)
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", out.PodName); pod.Deleted {
+		t.Error("secret expiry deleted the pod")
+	}
+	var stats GCStats
+	decode(t, e.do(http.MethodGet, "/admin/gc", "", "Authorization", "Bearer "+adminToken), &stats)
+	if stats.SecretsExpired != 2 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	auth := []string{"Authorization", "Bearer " + adminToken}
+	out := e.createRun(runBody(`, "secrets": ["echo-token"]`))
+	e.do(http.MethodGet, "/runs/"+out.RunID, "")
+	e.backend.SetLogs("mcp-runs", out.PodName, "exfiltrating...")
+	path := "/runs/" + out.RunID + "/quarantine"
+
+	if rec := e.do(http.MethodPost, path, `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing reason: %d", rec.Code)
+	}
+	if rec := e.do(http.MethodPost, "/runs/nope/quarantine", `{"reason": "x"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown run: %d", rec.Code)
+	}
+	rec := e.do(http.MethodPost, path, `{"reason": "unexpected egress"}`, "X-Runner-Caller", "soc")
+	var resp QuarantineResponse
+	decode(t, rec, &resp)
+	if rec.Code != http.StatusOK || resp.Status != "quarantined" || resp.Trigger != "manual" || resp.SHA256 == "" {
+		t.Fatalf("quarantine: %d %+v", rec.Code, resp)
+	}
+	if rec := e.do(http.MethodPost, path, `{"reason": "again"}`); rec.Code != http.StatusConflict {
+		t.Errorf("second quarantine: %d", rec.Code)
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", out.PodName); !pod.Quarantined {
+		t.Fatal("pod not isolated")
+	}
+	b, err := e.bundles.Get(out.RunID)
+	if err != nil || b.Caller != "soc" || b.Evidence.Logs != "exfiltrating..." || b.SHA256 != resp.SHA256 {
+		t.Fatalf("bundle %+v, %v", b, err)
+	}
+
+	// The run is finished for callers, and only an admin can end it.
+	if rec := e.do(http.MethodPost, "/runs/"+out.RunID+"/tools/echo", `{"input": {}}`); rec.Code != http.StatusConflict {
+		t.Errorf("tool call on quarantined run: %d", rec.Code)
+	}
+	if rec := e.do(http.MethodPost, "/runs/"+out.RunID+"/stop", ""); rec.Code != http.StatusConflict {
+		t.Errorf("stop of quarantined run: %d", rec.Code)
+	}
+	var status RunStatusResponse
+	decode(t, e.do(http.MethodGet, "/runs/"+out.RunID, ""), &status)
+	if status.Status != "quarantined" || status.QuarantinedAt == nil {
+		t.Errorf("status %+v", status)
+	}
+	if err := e.h.CollectGarbage(ctx, time.Now().UTC().Add(30*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", out.PodName); pod.Deleted {
+		t.Fatal("garbage collector deleted a quarantined pod")
+	}
+	if len(e.backend.DeletedSecrets()) != 1 {
+		t.Errorf("expired secret of a quarantined run kept: %v", e.backend.DeletedSecrets())
+	}
+
+	var list QuarantineListResponse
+	decode(t, e.do(http.MethodGet, "/admin/quarantine", "", auth...), &list)
+	if len(list.Bundles) != 1 || list.Bundles[0].RunID != out.RunID {
+		t.Errorf("list %+v", list)
+	}
+	if rec := e.do(http.MethodDelete, "/admin/quarantine/"+out.RunID, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("delete without admin token: %d", rec.Code)
+	}
+	if rec := e.do(http.MethodDelete, "/admin/quarantine/"+out.RunID, "", auth...); rec.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", rec.Code, rec.Body)
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", out.PodName); !pod.Deleted {
+		t.Error("pod kept after delete")
+	}
+	if len(e.backend.DeletedSecrets()) != 2 {
+		t.Errorf("run secret not deleted: %v", e.backend.DeletedSecrets())
+	}
+	if rec := e.do(http.MethodPost, "/admin/quarantine/"+out.RunID+"/release", "", auth...); rec.Code != http.StatusConflict {
+		t.Errorf("release after delete: %d", rec.Code)
+	}
+
+	// Bundles survive a restart.
+	reloaded, err := quarantine.NewStore(e.bundleDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := reloaded.Get(out.RunID); err != nil || b.Resolution != "deleted" || b.SHA256 != resp.SHA256 {
+		t.Errorf("reloaded bundle %+v, %v", b, err)
+	}
+}
+
+func TestQuarantineRelease(t *testing.T) {
+	e := newTestEnv(t)
+	cfg := e.h.config()
+	cfg.QuarantineTriggers = []string{"tool_scope_violation"}
+	e.h.Reconfigure(cfg, nil, nil)
+	auth := []string{"Authorization", "Bearer " + adminToken}
+	out := e.createRun(runBody(`, "allowed_tools": ["echo"], "network_policy_profile": "dns-only"`))
+	e.do(http.MethodGet, "/runs/"+out.RunID, "")
+	e.backend.SetTool("echo", 200, `{}`)
+
+	if rec := e.do(http.MethodPost, "/runs/"+out.RunID+"/tools/shell", `{"input": {}}`, "X-Runner-Caller", "agent-7"); rec.Code != http.StatusForbidden {
+		t.Fatalf("out-of-scope call: %d", rec.Code)
+	}
+	e.h.quarantining.Wait()
+	b, err := e.bundles.Get(out.RunID)
+	if err != nil || b.Trigger != "tool_scope_violation" || b.Caller != "agent-7" {
+		t.Fatalf("scope violation not quarantined: %+v, %v", b, err)
+	}
+	path := "/admin/quarantine/" + out.RunID + "/release"
+	if rec := e.do(http.MethodPost, path, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("release without admin token: %d", rec.Code)
+	}
+	if rec := e.do(http.MethodPost, path, "", auth...); rec.Code != http.StatusOK {
+		t.Fatalf("release: %d %s", rec.Code, rec.Body)
+	}
+	if pod, _ := e.backend.Pod("mcp-runs", out.PodName); pod.Quarantined || pod.Spec.NetworkProfile != "dns-only" {
+		t.Errorf("pod after release %+v", pod)
+	}
+	if rec := e.do(http.MethodPost, "/runs/"+out.RunID+"/tools/echo", `{"input": {}}`); rec.Code != http.StatusOK {
+		t.Errorf("tool call after release: %d", rec.Code)
+	}
+	if b, _ := e.bundles.Get(out.RunID); b.Resolution != "released" {
+		t.Errorf("bundle after release %+v", b)
+	}
+	if rec := e.do(http.MethodPost, path, "", auth...); rec.Code != http.StatusConflict {
+		t.Errorf("second release: %d", rec.Code)
+	}
+
+	// Isolation failures leave the run as it was.
+	e.backend.QuarantineErr = errors.New("forbidden")
+	if rec := e.do(http.MethodPost, "/runs/"+out.RunID+"/quarantine", `{"reason": "x"}`); rec.Code != http.StatusInternalServerError {
+		t.Errorf("failed quarantine: %d", rec.Code)
+	}
+	if run, _ := e.store.Get(out.RunID); run.QuarantinedAt != nil || run.FinishedAt != nil {
+		t.Errorf("run after failed quarantine %+v", run)
+	}
+}
+
+func TestQuarantineOnPodFailure(t *testing.T) {
+	e := newTestEnv(t)
+	cfg := e.h.config()
+	cfg.QuarantineTriggers = []string{"pod_failed"}
+	e.h.Reconfigure(cfg, nil, nil)
+	timedOut := e.createRun(runBody(""))
+	crashed := e.createRun(runBody(""))
+	e.backend.SetPhase("mcp-runs", timedOut.PodName, "Failed", "DeadlineExceeded")
+	e.backend.SetPhase("mcp-runs", crashed.PodName, "Failed", "Error")
+
+	if err := e.h.CollectGarbage(context.Background(), time.Now().UTC()); err != nil {
+		t.Fatal(err)
+	}
+	e.h.quarantining.Wait()
+	if run, _ := e.store.Get(crashed.RunID); run.Status != "quarantined" || run.QuarantinedAt == nil {
+		t.Errorf("crashed run %+v", run)
+	}
+	if b, err := e.bundles.Get(crashed.RunID); err != nil || b.Trigger != "pod_failed" {
+		t.Errorf("bundle %+v, %v", b, err)
+	}
+	if run, _ := e.store.Get(timedOut.RunID); run.Status != "failed" {
+		t.Errorf("run past its deadline quarantined: %+v", run)
+	}
+}
+
+func TestSelfCheck(t *testing.T) {
+	e := newTestEnv(t)
+	var got k8s.Prerequisites
+	calls := 0
+	checks := []k8s.Check{{Name: "runtime_class", Status: k8s.CheckPass}, {Name: "rbac", Status: k8s.CheckFail, Detail: "missing: list events in mcp-runs"}}
+	e.h.SetSelfCheck(func(_ context.Context, p k8s.Prerequisites) []k8s.Check {
+		got, calls = p, calls+1
+		return checks
+	})
+	if rep := e.h.SelfCheck(context.Background()); rep.Passed || !rep.Ready || rep.Mode != "warn" {
+		t.Fatalf("warn report %+v", rep)
+	}
+	if got.RuntimeClass != "gvisor" || got.Namespace != "mcp-runs" || got.PodSecurityLevel != "restricted" || got.SecretsNamespace != "" {
+		t.Errorf("prerequisites %+v", got)
+	}
+	if rec := e.do(http.MethodGet, "/readyz", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "missing: list events") {
+		t.Errorf("warn readyz: %d %s", rec.Code, rec.Body)
+	}
+	e.createRun(runBody(""))
+
+	// Strict mode refuses new runs until the checks pass.
+	cfg := e.h.config()
+	cfg.SelfCheckMode = "strict"
+	e.h.Reconfigure(cfg, nil, nil)
+	var rep SelfCheckReport
+	rec := e.do(http.MethodGet, "/readyz", "")
+	decode(t, rec, &rep)
+	if rec.Code != http.StatusServiceUnavailable || rep.Ready || len(rep.Checks) != 2 {
+		t.Errorf("strict readyz: %d %+v", rec.Code, rep)
+	}
+	if rec := e.do(http.MethodPost, "/runs", runBody("")); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("create in strict mode: %d", rec.Code)
+	}
+	checks[1].Status = k8s.CheckPass
+	if rep := e.h.SelfCheck(context.Background()); !rep.Passed || !rep.Ready {
+		t.Errorf("passing report %+v", rep)
+	}
+	if rec := e.do(http.MethodGet, "/readyz", ""); rec.Code != http.StatusOK {
+		t.Errorf("readyz after fix: %d", rec.Code)
+	}
+	e.createRun(runBody(""))
+
+	cfg.SelfCheckMode = "off"
+	e.h.Reconfigure(cfg, nil, nil)
+	if rep := e.h.SelfCheck(context.Background()); calls != 2 || !rep.Ready || len(rep.Checks) != 0 {
+		t.Errorf("off: %d calls, %+v", calls, rep)
+	}
+}
+
+func TestInvokeTool(t *testing.T) {
+	e := newTestEnv(t)
+	out := e.createRun(runBody(`, "allowed_tools": ["echo"], "downstream_port": 9000`))
+	path := "/runs/" + out.RunID + "/tools/echo"
+
+	if rec := e.do(http.MethodPost, path, `{"input": {}}`); rec.Code != http.StatusBadGateway {
+		t.Errorf("pending pod without address: %d", rec.Code)
+	}
+	e.do(http.MethodGet, "/runs/"+out.RunID, "")
+
+	e.backend.SetTool("echo", 200, `{"text": "hi"}`)
+	rec := e.do(http.MethodPost, path, `{"input": {"text": "hi"}}`)
+	var resp ToolInvokeResponse
+	decode(t, rec, &resp)
+	if rec.Code != http.StatusOK || resp.Output["text"] != "hi" || resp.RawStatus != 200 || resp.ToolName != "echo" {
+		t.Fatalf("invoke %d %+v", rec.Code, resp)
+	}
+	calls := e.backend.Calls()
+	if len(calls) != 1 || calls[0].Port != 9000 || !bytes.Contains(calls[0].Payload, []byte(`"text":"hi"`)) {
+		t.Errorf("downstream call %+v", calls)
+	}
+
+	e.backend.SetTool("echo", 500, "boom")
+	decode(t, e.do(http.MethodPost, path, `{"input": {}}`), &resp)
+	if resp.RawStatus != 500 || resp.Output["raw"] != "boom" {
+		t.Errorf("non-JSON downstream body: %+v", resp)
+	}
+
+	if rec := e.do(http.MethodPost, path, `{`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid json: %d", rec.Code)
+	}
+	e.backend.InvokeErr = errors.New("connection refused")
+	if rec := e.do(http.MethodPost, path, `{"input": {}}`); rec.Code != http.StatusBadGateway {
+		t.Errorf("downstream error: %d", rec.Code)
+	}
+	if rec := e.do(http.MethodPost, "/runs/nope/tools/echo", `{"input": {}}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown run: %d", rec.Code)
+	}
+
+	// Without the tool_scope_violation trigger the run keeps serving.
+	e.backend.InvokeErr = nil
+	if rec := e.do(http.MethodPost, "/runs/"+out.RunID+"/tools/shell", `{"input": {}}`); rec.Code != http.StatusForbidden {
+		t.Errorf("tool outside allowlist: %d", rec.Code)
+	}
+	e.h.quarantining.Wait()
+	if rec := e.do(http.MethodPost, path, `{"input": {}}`); rec.Code != http.StatusOK {
+		t.Errorf("call after scope violation: %d", rec.Code)
+	}
+}
+
+func TestScopeViolationQuarantinesInBackground(t *testing.T) {
+	e := newTestEnv(t)
+	cfg := e.h.config()
+	cfg.QuarantineTriggers = []string{"tool_scope_violation"}
+	e.h.Reconfigure(cfg, nil, nil)
+	out := e.createRun(runBody(`, "allowed_tools": ["echo"]`))
+	e.do(http.MethodGet, "/runs/"+out.RunID, "")
+	e.backend.SetTool("echo", 200, `{}`)
+
+	release := make(chan struct{})
+	e.backend.BeforeQuarantine = func(string) { <-release }
+	responded := make(chan int)
+	go func() {
+		responded <- e.do(http.MethodPost, "/runs/"+out.RunID+"/tools/shell", `{"input": {}}`).Code
+	}()
+	select {
+	case code := <-responded:
+		if code != http.StatusForbidden {
+			t.Errorf("tool outside allowlist: %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the tool call waited on the quarantine")
+	}
+	close(release)
+	e.h.quarantining.Wait()
+
+	if b, err := e.bundles.Get(out.RunID); err != nil || b.Trigger != "tool_scope_violation" {
+		t.Fatalf("scope violation not quarantined: %+v, %v", b, err)
+	}
+	if rec := e.do(http.MethodPost, "/runs/"+out.RunID+"/tools/echo", `{"input": {}}`); rec.Code != http.StatusConflict {
+		t.Errorf("call after quarantine: %d", rec.Code)
+	}
+}
+
+func TestEvaluatePolicy(t *testing.T) {
+	e := newTestEnv(t)
+	var resp PolicyEvaluateResponse
+
+	rec := e.do(http.MethodPost, "/policy/evaluate", runBody(""))
+	decode(t, rec, &resp)
+	if rec.Code != http.StatusOK || !resp.Allowed || resp.PinnedImage != testImage {
+		t.Fatalf("allowed: %d %+v", rec.Code, resp)
+	}
+
+	rec = e.do(http.MethodPost, "/policy/evaluate", runBody(`, "cpu": "64"`))
+	decode(t, rec, &resp)
+	if rec.Code != http.StatusOK || resp.Allowed || resp.PolicyEvidence.DenialReason != "resource_limits_exceeded" {
+		t.Fatalf("denied: %d %+v", rec.Code, resp)
+	}
+	status := map[string]string{}
+	for _, c := range resp.Checks {
+		status[c.Name] = c.Status
+	}
+	if status["resource_limits"] != policy.CheckFail || status["env"] != policy.CheckSkipped || status["policy_rules"] != policy.CheckSkipped {
+		t.Errorf("checks after the failing stage should be skipped: %v", status)
+	}
+
+	// Secrets are not fetched: an existing and a missing secret look the same.
+	var known, missing PolicyEvaluateResponse
+	decode(t, e.do(http.MethodPost, "/policy/evaluate", runBody(`, "secrets": ["echo-token"]`)), &known)
+	decode(t, e.do(http.MethodPost, "/policy/evaluate", runBody(`, "secrets": ["missing"]`)), &missing)
+	if !known.Allowed || !missing.Allowed || !reflect.DeepEqual(known.Checks, missing.Checks) {
+		t.Errorf("dry run secrets: %+v / %+v", known.Checks, missing.Checks)
+	}
+	decode(t, e.do(http.MethodPost, "/policy/evaluate", runBody(`, "secrets": ["echo-token", "echo-token"]`)), &resp)
+	if resp.Allowed || resp.PolicyEvidence.DenialReason != "secret_unavailable" {
+		t.Errorf("duplicate secret reference: %+v", resp)
+	}
+
+	if rec := e.do(http.MethodPost, "/policy/evaluate", `{`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid json: %d", rec.Code)
+	}
+	if len(e.backend.Pods()) != 0 {
+		t.Error("dry run created a pod")
+	}
+}
+
+func TestAdminExceptions(t *testing.T) {
+	e := newTestEnv(t)
+	auth := []string{"Authorization", "Bearer " + adminToken}
+	body := `{"repository": "ghcr.io/acme/mcp-echo", "principal": "ci", "waive": ["signature"], "reason": "INC-1", "ttl_seconds": 600}`
+
+	if rec := e.do(http.MethodPost, "/admin/policy-exceptions", body); rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: %d", rec.Code)
+	}
+	if rec := e.do(http.MethodGet, "/admin/policy-exceptions", "", "Authorization", "Bearer wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: %d", rec.Code)
+	}
+
+	// The header names nobody; only the token authenticated the call.
+	rec := e.do(http.MethodPost, "/admin/policy-exceptions", body, append(auth, "X-Runner-Caller", "alice")...)
+	var x policy.Exception
+	decode(t, rec, &x)
+	if rec.Code != http.StatusCreated || x.ID == "" || x.Principal != "ci" || x.CreatedBy != "admin-token" {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body)
+	}
+	for label, bad := range map[string]string{
+		"invalid json": `{`,
+		"ttl too long": `{"repository": "ghcr.io/acme/mcp-echo", "principal": "ci", "waive": ["signature"], "reason": "x", "ttl_seconds": 999999999}`,
+		"unwaivable":   `{"repository": "ghcr.io/acme/mcp-echo", "principal": "ci", "waive": ["registry"], "reason": "x", "ttl_seconds": 60}`,
+		"anonymous":    `{"repository": "ghcr.io/acme/mcp-echo", "principal": "anonymous", "waive": ["signature"], "reason": "x", "ttl_seconds": 60}`,
+	} {
+		if rec := e.do(http.MethodPost, "/admin/policy-exceptions", bad, auth...); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d", label, rec.Code)
+		}
+	}
+
+	var list ExceptionListResponse
+	decode(t, e.do(http.MethodGet, "/admin/policy-exceptions", "", auth...), &list)
+	if len(list.Exceptions) != 1 {
+		t.Fatalf("list: %+v", list)
+	}
+
+	if rec := e.do(http.MethodDelete, "/admin/policy-exceptions/"+x.ID, "", auth...); rec.Code != http.StatusOK {
+		t.Fatalf("revoke: %d", rec.Code)
+	}
+	if rec := e.do(http.MethodDelete, "/admin/policy-exceptions/nope", "", auth...); rec.Code != http.StatusNotFound {
+		t.Errorf("revoke unknown: %d", rec.Code)
+	}
+	decode(t, e.do(http.MethodGet, "/admin/policy-exceptions", "", auth...), &list)
+	if len(list.Exceptions) != 0 {
+		t.Errorf("revoked exception still active: %+v", list)
+	}
+	decode(t, e.do(http.MethodGet, "/admin/policy-exceptions?all=true", "", auth...), &list)
+	if len(list.Exceptions) != 1 || list.Exceptions[0].RevokedAt == nil {
+		t.Errorf("all=true should include revoked: %+v", list)
+	}
+}
+
+// testPKI is a throwaway CA for the mTLS listener tests.
+type testPKI struct {
+	dir    string
+	caFile string
+	ca     *x509.Certificate
+	key    *ecdsa.PrivateKey
+	pool   *x509.CertPool
+	serial int64
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	p := &testPKI{dir: t.TempDir(), pool: x509.NewCertPool()}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ca, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	p.key, p.serial = key, 1
+	p.pool.AddCert(p.ca)
+	p.caFile = p.write(t, "ca.pem", "CERTIFICATE", der)
+	return p
+}
+
+func (p *testPKI) write(t *testing.T, name, kind string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(p.dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// issue signs a certificate for cn, valid for 127.0.0.1 as both server and
+// client, and writes it and its key as cn.pem and cn-key.pem.
+func (p *testPKI) issue(t *testing.T, cn string) (cert tls.Certificate, certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(p.serial),
+		Subject:      pkix.Name{CommonName: cn},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.ca, &key.PublicKey, p.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = p.write(t, cn+".pem", "CERTIFICATE", der), p.write(t, cn+"-key.pem", "EC PRIVATE KEY", keyDER)
+	if cert, err = tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+	return cert, certFile, keyFile
+}
+
+// client returns an HTTP client that trusts the CA and, unless cert is the
+// zero value, presents cert.
+func (p *testPKI) client(cert tls.Certificate) *http.Client {
+	cfg := &tls.Config{RootCAs: p.pool}
+	if cert.Certificate != nil {
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+}
+
+func TestExceptionWaivesSignatureOverMTLS(t *testing.T) {
+	e := newTestEnv(t)
+	pc := e.h.enforcer.Config()
+	pc.RequireCosignVerify = true
+	e.h.enforcer.SetConfig(pc)
+
+	pki := newTestPKI(t)
+	_, certFile, keyFile := pki.issue(t, "runner")
+	values := map[string]string{"RUNNER_TLS_CERT_FILE": certFile, "RUNNER_TLS_KEY_FILE": keyFile, "RUNNER_TLS_CLIENT_CA_FILE": pki.caFile}
+	cfg, err := config.From(func(k string) string { return values[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsCfg, err := cfg.TLS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(e.server)
+	srv.TLS = tlsCfg
+	srv.StartTLS()
+	defer srv.Close()
+	ops, _, _ := pki.issue(t, "ops")
+	ci, _, _ := pki.issue(t, "ci")
+
+	post := func(c *http.Client, path, body string) (int, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, out
+	}
+	runOutcome := func(c *http.Client) (int, policy.Evidence) {
+		t.Helper()
+		code, body := post(c, "/runs", runBody(""))
+		var out struct {
+			PolicyEvidence policy.Evidence `json:"policy_evidence"`
+		}
+		if err := json.Unmarshal(body, &out); err != nil {
+			t.Fatalf("decode %s: %v", body, err)
+		}
+		return code, out.PolicyEvidence
+	}
+
+	if _, err := pki.client(tls.Certificate{}).Get(srv.URL + "/readyz"); err == nil {
+		t.Error("connection without a client certificate was accepted")
+	}
+	if code, ev := runOutcome(pki.client(ci)); code != http.StatusForbidden || ev.DenialReason != "cosign_verify_failed" {
+		t.Fatalf("unsigned image before the exception: %d %+v", code, ev)
+	}
+
+	code, body := post(pki.client(ops), "/admin/policy-exceptions", `{"repository": "ghcr.io/acme/mcp-echo", "principal": "ci", "waive": ["signature"], "reason": "INC-1", "ttl_seconds": 600}`)
+	var x policy.Exception
+	if err := json.Unmarshal(body, &x); err != nil || code != http.StatusCreated || x.CreatedBy != "ops" {
+		t.Fatalf("create exception: %d %s", code, body)
+	}
+
+	code, ev := runOutcome(pki.client(ci))
+	if code != http.StatusCreated || len(ev.ExceptionsUsed) != 1 || ev.ExceptionsUsed[0].ID != x.ID || ev.ExceptionsUsed[0].Check != "signature" {
+		t.Fatalf("run under the exception: %d %+v", code, ev)
+	}
+	// Other certificates, and the header claiming the principal, stay denied.
+	if code, ev := runOutcome(pki.client(ops)); code != http.StatusForbidden || ev.DenialReason != "cosign_verify_failed" {
+		t.Errorf("run by another principal: %d %+v", code, ev)
+	}
+	if rec := e.do(http.MethodPost, "/runs", runBody(""), "X-Runner-Caller", "ci"); rec.Code != http.StatusForbidden {
+		t.Errorf("run by header-named principal: %d", rec.Code)
+	}
+}
+
+func TestAdminConfig(t *testing.T) {
+	e := newTestEnv(t)
+	if rec := e.do(http.MethodGet, "/admin/config", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: %d", rec.Code)
+	}
+	rec := e.do(http.MethodGet, "/admin/config", "", "Authorization", "Bearer "+adminToken)
+	var st ConfigStatusResponse
+	decode(t, rec, &st)
+	if rec.Code != http.StatusOK || len(st.RestartRequired) != 0 {
+		t.Errorf("config: %d %+v", rec.Code, st)
+	}
+	if rec := e.do(http.MethodGet, "/admin/pool", "", "Authorization", "Bearer "+adminToken); rec.Code != http.StatusNotFound {
+		t.Errorf("pool without RUNNER_WARM_POOL_SIZE: %d", rec.Code)
+	}
+}
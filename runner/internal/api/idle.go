@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/mcp-orc/runner/internal/audit"
+	"github.com/mcp-orc/runner/internal/config"
+	"github.com/mcp-orc/runner/internal/k8s"
+	"github.com/mcp-orc/runner/internal/runs"
+)
+
+func checkIdleTimeout(seconds int64, cfg config.Config) error {
+	if seconds < 0 || seconds > cfg.MaxIdleTimeoutSeconds {
+		return fmt.Errorf("idle_timeout_seconds must be within 0-%d", cfg.MaxIdleTimeoutSeconds)
+	}
+	return nil
+}
+
+func idleTimeout(cfg config.Config, seconds int64) int64 {
+	if seconds == 0 {
+		return cfg.DefaultIdleTimeoutSeconds
+	}
+	return seconds
+}
+
+func (h *Handler) keepaliveRun(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "run_id")
+	var run runs.Run
+	err := h.store.Update(runID, func(orig runs.Run) runs.Run {
+		if orig.FinishedAt == nil {
+			orig.LastActivity = time.Now().UTC()
+		}
+		run = orig
+		return orig
+	})
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if run.FinishedAt != nil {
+		http.Error(w, "run is "+run.Status, http.StatusConflict)
+		return
+	}
+	writeJSON(w, http.StatusOK, keepaliveResponse(run))
+}
+
+func keepaliveResponse(run runs.Run) KeepaliveResponse {
+	resp := KeepaliveResponse{RunID: run.RunID, LastActivity: run.LastActivity, IdleTimeoutSeconds: run.IdleTimeoutSeconds}
+	if run.IdleTimeoutSeconds > 0 {
+		t := run.LastActivity.Add(time.Duration(run.IdleTimeoutSeconds) * time.Second)
+		resp.IdleExpiresAt = &t
+	}
+	return resp
+}
+
+// beginCall records tool activity on a run and holds off the reaper until
+// the returned func is called. It reports false once the run has finished.
+func (h *Handler) beginCall(runID string) (func(), bool) {
+	started := false
+	_ = h.store.Update(runID, func(orig runs.Run) runs.Run {
+		if orig.FinishedAt != nil {
+			return orig
+		}
+		started = true
+		orig.InFlight++
+		orig.LastActivity = time.Now().UTC()
+		return orig
+	})
+	if !started {
+		return nil, false
+	}
+	return func() {
+		_ = h.store.Update(runID, func(orig runs.Run) runs.Run {
+			orig.InFlight--
+			orig.LastActivity = time.Now().UTC()
+			return orig
+		})
+	}, true
+}
+
+func idleExpired(run runs.Run, now time.Time) bool {
+	return run.FinishedAt == nil && run.IdleTimeoutSeconds > 0 && run.InFlight == 0 && run.ReadyAt != nil &&
+		now.Sub(run.LastActivity) > time.Duration(run.IdleTimeoutSeconds)*time.Second
+}
+
+// startIdleClock starts the idle clock of a run whose workload has become
+// Ready since the last look. Until then the run cannot be idle; its deadline
+// bounds a start that never completes.
+func (h *Handler) startIdleClock(ctx context.Context, run runs.Run, now time.Time) {
+	ready, err := h.backend.PodReady(ctx, run.Namespace, run.PodName)
+	if err != nil || !ready {
+		return
+	}
+	_ = h.store.Update(run.RunID, func(orig runs.Run) runs.Run {
+		if orig.ReadyAt == nil {
+			orig.ReadyAt, orig.LastActivity = &now, now
+		}
+		return orig
+	})
+}
+
+// ReapIdle stops every run that has gone longer than its idle timeout
+// without tool activity and returns how many it stopped.
+func (h *Handler) ReapIdle(ctx context.Context, now time.Time) int {
+	reaped := 0
+	for _, run := range h.store.List() {
+		if run.ReadyAt == nil && run.FinishedAt == nil && run.IdleTimeoutSeconds > 0 {
+			h.startIdleClock(ctx, run, now)
+			continue
+		}
+		if !idleExpired(run, now) {
+			continue
+		}
+		// Mark the run first so a tool call racing the reaper is refused
+		// rather than sent to a pod that is going away.
+		marked := false
+		_ = h.store.Update(run.RunID, func(orig runs.Run) runs.Run {
+			if !idleExpired(orig, now) {
+				return orig
+			}
+			marked = true
+			orig.Status, orig.Reason = "idle_expired", fmt.Sprintf("no tool activity for %ds", orig.IdleTimeoutSeconds)
+			orig.FinishedAt = &now
+			return orig
+		})
+		if !marked {
+			continue
+		}
+		if err := h.backend.DeletePod(ctx, run.Namespace, run.PodName); err != nil {
+			_ = h.store.Update(run.RunID, func(orig runs.Run) runs.Run {
+				orig.Status, orig.Reason, orig.FinishedAt = run.Status, run.Reason, nil
+				return orig
+			})
+			audit.Event("run_idle_reap_failed", map[string]any{"run_id": run.RunID, "reason": err.Error()})
+			continue
+		}
+		if len(run.Secrets) > 0 {
+			_ = h.backend.DeleteRunSecret(ctx, run.Namespace, k8s.RunSecretName(run.PodName))
+		}
+		reaped++
+		audit.Event("run_idle_expired", map[string]any{"run_id": run.RunID, "pod_name": run.PodName, "idle_timeout_seconds": run.IdleTimeoutSeconds, "last_activity": run.LastActivity})
+	}
+	return reaped
+}
+
+// RunReaper calls ReapIdle every interval until ctx is done.
+func (h *Handler) RunReaper(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			h.ReapIdle(ctx, now.UTC())
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/mcp-orc/runner/internal/audit"
+	"github.com/mcp-orc/runner/internal/k8s"
+	"github.com/mcp-orc/runner/internal/quarantine"
+	"github.com/mcp-orc/runner/internal/runs"
+)
+
+var (
+	errAlreadyQuarantined = errors.New("run is already quarantined")
+	errWorkloadGone       = errors.New("run has no workload left to quarantine")
+)
+
+// autoQuarantineTimeout bounds an automatic quarantine, which runs detached
+// from the request or sweep that triggered it.
+const autoQuarantineTimeout = 30 * time.Second
+
+// quarantine isolates the run's workload, captures an evidence bundle and
+// marks the run quarantined. The run is marked first, so the idle reaper and
+// the garbage collector leave the workload alone while it is isolated, and
+// reverted if isolation fails. Evidence that cannot be collected is recorded
+// in the bundle; a bundle that cannot be stored fails the call, but the
+// workload stays isolated.
+func (h *Handler) quarantine(ctx context.Context, runID, trigger, reason, caller string) (quarantine.Bundle, error) {
+	now := time.Now().UTC()
+	var prev runs.Run
+	var refused error
+	err := h.store.Update(runID, func(orig runs.Run) runs.Run {
+		prev = orig
+		switch {
+		case orig.QuarantinedAt != nil:
+			refused = errAlreadyQuarantined
+		case slices.Contains([]string{"stopped", "idle_expired", "not_found", "quarantined"}, orig.Status):
+			refused = errWorkloadGone
+		default:
+			orig.Status, orig.Reason, orig.FinishedAt, orig.QuarantinedAt = "quarantined", trigger, &now, &now
+		}
+		return orig
+	})
+	if err != nil {
+		return quarantine.Bundle{}, err
+	}
+	if refused != nil {
+		return quarantine.Bundle{}, refused
+	}
+
+	if err := h.backend.QuarantinePod(ctx, prev.Namespace, prev.PodName); err != nil {
+		_ = h.store.Update(runID, func(orig runs.Run) runs.Run {
+			orig.Status, orig.Reason, orig.FinishedAt, orig.QuarantinedAt = prev.Status, prev.Reason, prev.FinishedAt, nil
+			return orig
+		})
+		audit.Event("run_quarantine_failed", map[string]any{"run_id": runID, "trigger": trigger, "reason": err.Error()})
+		return quarantine.Bundle{}, err
+	}
+
+	evidence, err := h.backend.CollectEvidence(ctx, prev.Namespace, prev.PodName)
+	if err != nil {
+		evidence = k8s.Evidence{CollectedAt: time.Now().UTC(), Events: []k8s.Event{}, Errors: []string{err.Error()}}
+	}
+	b, err := h.bundles.Put(quarantine.Bundle{
+		RunID:          runID,
+		PodName:        prev.PodName,
+		Trigger:        trigger,
+		Reason:         reason,
+		Caller:         caller,
+		QuarantinedAt:  now,
+		ImageDigest:    prev.ImageDigest,
+		PolicyEvidence: prev.PolicyEvidence,
+		Evidence:       evidence,
+	})
+	if err != nil {
+		audit.Event("run_quarantine_failed", map[string]any{"run_id": runID, "trigger": trigger, "reason": err.Error(), "isolated": true})
+		return quarantine.Bundle{}, err
+	}
+	audit.Event("run_quarantined", map[string]any{"run_id": runID, "pod_name": prev.PodName, "trigger": trigger, "reason": reason, "caller": caller, "image_digest": prev.ImageDigest, "evidence_sha256": b.SHA256, "evidence_errors": evidence.Errors})
+	return b, nil
+}
+
+// autoQuarantine starts quarantining the run in the background if trigger is
+// enabled in RUNNER_QUARANTINE_TRIGGERS, and reports whether it did, so the
+// caller never waits on the backend. Failures are audited by quarantine.
+func (h *Handler) autoQuarantine(run runs.Run, trigger, reason, caller string) bool {
+	if !slices.Contains(h.config().QuarantineTriggers, trigger) || run.QuarantinedAt != nil {
+		return false
+	}
+	h.quarantining.Add(1)
+	go func() {
+		defer h.quarantining.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), autoQuarantineTimeout)
+		defer cancel()
+		_, _ = h.quarantine(ctx, run.RunID, trigger, reason, caller)
+	}()
+	return true
+}
+
+func (h *Handler) quarantineRun(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "run_id")
+	var req QuarantineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.store.Get(runID); err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	b, err := h.quarantine(r.Context(), runID, "manual", req.Reason, callerIdentity(r).Subject)
+	switch {
+	case errors.Is(err, errAlreadyQuarantined), errors.Is(err, errWorkloadGone):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "quarantine failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, QuarantineResponse{RunID: runID, Status: "quarantined", Trigger: b.Trigger, QuarantinedAt: b.QuarantinedAt, SHA256: b.SHA256, EvidenceErrors: b.Evidence.Errors})
+}
+
+func (h *Handler) listQuarantined(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, QuarantineListResponse{Bundles: h.bundles.List()})
+}
+
+func (h *Handler) getQuarantined(w http.ResponseWriter, r *http.Request) {
+	b, err := h.bundles.Get(chi.URLParam(r, "run_id"))
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+// quarantinedRun loads a run under quarantine for the admin release and
+// delete calls, answering the request itself when there is none. Run records
+// do not survive a restart, so without one an unresolved bundle stands in
+// for it; such a run can only be deleted.
+func (h *Handler) quarantinedRun(w http.ResponseWriter, runID string, needRecord bool) (runs.Run, bool) {
+	run, err := h.store.Get(runID)
+	if err == nil {
+		if run.QuarantinedAt == nil {
+			http.Error(w, "run is not quarantined", http.StatusConflict)
+			return runs.Run{}, false
+		}
+		return run, true
+	}
+	b, err := h.bundles.Get(runID)
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return runs.Run{}, false
+	}
+	if b.Resolution != "" {
+		http.Error(w, "quarantine already "+b.Resolution, http.StatusConflict)
+		return runs.Run{}, false
+	}
+	if needRecord {
+		http.Error(w, "run record is gone; the workload can only be deleted", http.StatusConflict)
+		return runs.Run{}, false
+	}
+	return runs.Run{RunID: runID, PodName: b.PodName, Namespace: h.config().Namespace, QuarantinedAt: &b.QuarantinedAt}, true
+}
+
+// releaseQuarantined lifts the isolation and returns the run to service. Its
+// evidence bundle is kept.
+func (h *Handler) releaseQuarantined(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "run_id")
+	run, ok := h.quarantinedRun(w, runID, true)
+	if !ok {
+		return
+	}
+	if err := h.backend.ReleasePod(r.Context(), run.Namespace, run.PodName, run.NetworkProfile); err != nil {
+		http.Error(w, "release failed", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now().UTC()
+	_ = h.store.Update(runID, func(orig runs.Run) runs.Run {
+		orig.Status, orig.Reason, orig.FinishedAt, orig.QuarantinedAt = "running", "", nil, nil
+		orig.LastActivity = now
+		return orig
+	})
+	by := callerIdentity(r).Subject
+	b, err := h.bundles.Resolve(runID, "released", by, now)
+	if err != nil && !errors.Is(err, quarantine.ErrNotFound) {
+		http.Error(w, "record release failed", http.StatusInternalServerError)
+		return
+	}
+	audit.Event("run_quarantine_released", map[string]any{"run_id": runID, "released_by": by, "evidence_sha256": b.SHA256})
+	writeJSON(w, http.StatusOK, b)
+}
+
+// deleteQuarantined removes the quarantined workload and its secrets; the
+// quarantine NetworkPolicy goes with it. The run record ages out under the
+// "quarantined" record retention and the evidence bundle is kept.
+func (h *Handler) deleteQuarantined(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "run_id")
+	run, ok := h.quarantinedRun(w, runID, false)
+	if !ok {
+		return
+	}
+	if err := h.backend.DeletePod(r.Context(), run.Namespace, run.PodName); err != nil {
+		http.Error(w, "delete failed", http.StatusInternalServerError)
+		return
+	}
+	_ = h.backend.DeleteRunSecret(r.Context(), run.Namespace, k8s.RunSecretName(run.PodName))
+	now := time.Now().UTC()
+	_ = h.store.Update(runID, func(orig runs.Run) runs.Run {
+		orig.FinishedAt, orig.QuarantinedAt = &now, nil
+		return orig
+	})
+	by := callerIdentity(r).Subject
+	b, err := h.bundles.Resolve(runID, "deleted", by, now)
+	if err != nil && !errors.Is(err, quarantine.ErrNotFound) {
+		http.Error(w, "record deletion failed", http.StatusInternalServerError)
+		return
+	}
+	audit.Event("run_quarantine_deleted", map[string]any{"run_id": runID, "deleted_by": by, "evidence_sha256": b.SHA256})
+	writeJSON(w, http.StatusOK, b)
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/mcp-orc/runner/internal/audit"
+	"github.com/mcp-orc/runner/internal/config"
+	"github.com/mcp-orc/runner/internal/runs"
+)
+
+// initializeRequest is the MCP initialize call sent as the readiness
+// handshake. The server's answer is checked, never returned to the caller.
+var initializeRequest = []byte(`{"jsonrpc":"2.0","id":"runner-ready","method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"mcp-runner","version":"0.1.0"}}}`)
+
+const readyPollInterval = 500 * time.Millisecond
+
+func (h *Handler) waitRun(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "run_id")
+	run, err := h.store.Get(runID)
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	var req WaitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	cfg := h.config()
+	if err := checkReadyTimeout("timeout_seconds", req.TimeoutSeconds, cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := h.waitReady(r.Context(), run, readyTimeout(cfg, req.TimeoutSeconds), req.MCPHandshake)
+	status := http.StatusOK
+	switch {
+	case resp.Ready:
+	case resp.Terminal:
+		status = http.StatusConflict
+	default:
+		status = http.StatusGatewayTimeout
+	}
+	writeJSON(w, status, resp)
+}
+
+func checkReadyTimeout(field string, seconds int64, cfg config.Config) error {
+	if seconds < 0 || seconds > cfg.MaxReadyTimeoutSeconds {
+		return fmt.Errorf("%s must be within 0-%d", field, cfg.MaxReadyTimeoutSeconds)
+	}
+	return nil
+}
+
+func readyTimeout(cfg config.Config, seconds int64) time.Duration {
+	if seconds == 0 {
+		seconds = cfg.ReadyTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// waitReady polls until the run's pod is Ready and, with handshake, answers
+// the MCP initialize request; it gives up at the deadline or as soon as the
+// pod has finished.
+func (h *Handler) waitReady(ctx context.Context, run runs.Run, timeout time.Duration, handshake bool) WaitResponse {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	resp := WaitResponse{RunID: run.RunID, Handshake: "skipped"}
+	finish := func() WaitResponse {
+		resp.WaitedMillis = time.Since(start).Milliseconds()
+		_ = h.store.Update(run.RunID, func(orig runs.Run) runs.Run {
+			if resp.Status != "" && orig.FinishedAt == nil {
+				orig.Status, orig.Reason = resp.Status, resp.Reason
+			}
+			// Readiness starts the idle clock; a slow start is not idleness.
+			if resp.Ready {
+				now := time.Now().UTC()
+				orig.LastActivity = now
+				if orig.ReadyAt == nil {
+					orig.ReadyAt = &now
+				}
+			}
+			return orig
+		})
+		event := "run_ready"
+		if !resp.Ready {
+			event = "run_not_ready"
+		}
+		audit.Event(event, map[string]any{"run_id": run.RunID, "status": resp.Status, "handshake": resp.Handshake, "waited_ms": resp.WaitedMillis, "error": resp.Error})
+		return resp
+	}
+
+	t := time.NewTicker(readyPollInterval)
+	defer t.Stop()
+	for {
+		phase, reason, err := h.backend.GetPodStatus(ctx, run.Namespace, run.PodName)
+		if err == nil {
+			resp.Status, resp.Reason = strings.ToLower(phase), reason
+			switch resp.Status {
+			case "succeeded", "failed", "not_found":
+				resp.Terminal = true
+				resp.Error = "run finished before becoming ready"
+				return finish()
+			}
+			if resp.Error = h.checkReady(ctx, run, handshake); resp.Error == "" {
+				resp.Ready = true
+				if handshake {
+					resp.Handshake = "ok"
+				}
+				return finish()
+			}
+		} else {
+			resp.Error = err.Error()
+		}
+		select {
+		case <-ctx.Done():
+			if handshake {
+				resp.Handshake = "failed"
+			}
+			if resp.Error == "" {
+				resp.Error = "timed out waiting for readiness"
+			}
+			return finish()
+		case <-t.C:
+		}
+	}
+}
+
+// checkReady returns why the run is not ready yet, or "" once it is.
+func (h *Handler) checkReady(ctx context.Context, run runs.Run, handshake bool) string {
+	ready, err := h.backend.PodReady(ctx, run.Namespace, run.PodName)
+	if err != nil {
+		return err.Error()
+	}
+	if !ready {
+		return "pod not ready"
+	}
+	if !handshake {
+		return ""
+	}
+	addr, err := h.backend.GetPodIP(ctx, run.Namespace, run.PodName)
+	if err != nil || addr == "" {
+		return "pod ip unavailable"
+	}
+	body, status, err := h.backend.PostJSON(ctx, addr, run.DownstreamPort, h.config().MCPHandshakePath, initializeRequest)
+	if err != nil {
+		return "initialize: " + err.Error()
+	}
+	if err := checkInitialize(body, status); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// checkInitialize accepts a JSON-RPC initialize result, sent as plain JSON
+// or, by Streamable HTTP servers, as the first event of an SSE stream.
+func checkInitialize(body []byte, status int) error {
+	if status != http.StatusOK {
+		return fmt.Errorf("initialize: HTTP %d", status)
+	}
+	raw := bytes.TrimSpace(body)
+	if !bytes.HasPrefix(raw, []byte("{")) {
+		for _, line := range bytes.Split(raw, []byte("\n")) {
+			if data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:")); ok {
+				raw = bytes.TrimSpace(data)
+				break
+			}
+		}
+	}
+	var resp struct {
+		Result *struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return errors.New("initialize: response is not JSON-RPC")
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("initialize: %s", resp.Error.Message)
+	}
+	if resp.Result == nil || resp.Result.ProtocolVersion == "" {
+		return errors.New("initialize: no protocolVersion in result")
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mcp-orc/runner/internal/audit"
+	"github.com/mcp-orc/runner/internal/k8s"
+)
+
+// selfCheck is the cluster self-check's state: the checks to run, nil
+// without a cluster, and the latest report for /readyz.
+type selfCheck struct {
+	mu     sync.Mutex
+	run    func(context.Context, k8s.Prerequisites) []k8s.Check
+	report SelfCheckReport
+}
+
+// SetSelfCheck installs the cluster prerequisite checks. Without them, as on
+// the local backend, there is nothing to verify and the runner is ready.
+func (h *Handler) SetSelfCheck(run func(context.Context, k8s.Prerequisites) []k8s.Check) {
+	h.selfCheck.mu.Lock()
+	defer h.selfCheck.mu.Unlock()
+	h.selfCheck.run = run
+}
+
+// SelfCheck verifies the cluster prerequisites and keeps the result for
+// /readyz. A change in which checks fail is audited.
+func (h *Handler) SelfCheck(ctx context.Context) SelfCheckReport {
+	cfg := h.config()
+	h.selfCheck.mu.Lock()
+	run, prev := h.selfCheck.run, h.selfCheck.report
+	h.selfCheck.mu.Unlock()
+
+	now := time.Now().UTC()
+	rep := SelfCheckReport{CheckedAt: &now, Checks: []k8s.Check{}}
+	if run != nil && cfg.SelfCheckMode != "off" {
+		p := k8s.Prerequisites{RuntimeClass: cfg.RuntimeClassName, Namespace: cfg.Namespace, PodSecurityLevel: cfg.SelfCheckPodSecurity}
+		if cfg.SecretsBackend == "kubernetes" {
+			p.SecretsNamespace = cfg.SecretsNamespace
+		}
+		rep.Checks = run(ctx, p)
+	}
+	failed := failedChecks(rep.Checks)
+	rep.Passed = len(failed) == 0
+
+	h.selfCheck.mu.Lock()
+	h.selfCheck.report = rep
+	h.selfCheck.mu.Unlock()
+	if prev.CheckedAt == nil || !slices.Equal(failedChecks(prev.Checks), failed) {
+		audit.Event("cluster_selfcheck", map[string]any{"mode": cfg.SelfCheckMode, "passed": rep.Passed, "failed": failed, "checks": rep.Checks})
+	}
+	return h.selfCheckReport()
+}
+
+// RunSelfCheck repeats the self-check every interval until ctx is done. The
+// first check is the caller's, before the runner starts serving.
+func (h *Handler) RunSelfCheck(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			h.SelfCheck(ctx)
+		}
+	}
+}
+
+// selfCheckReport is the latest report under the current mode: in strict
+// mode the runner is not ready, and refuses new runs, while a check fails.
+func (h *Handler) selfCheckReport() SelfCheckReport {
+	mode := h.config().SelfCheckMode
+	h.selfCheck.mu.Lock()
+	rep := h.selfCheck.report
+	h.selfCheck.mu.Unlock()
+	if rep.Checks == nil {
+		rep.Checks = []k8s.Check{}
+	}
+	rep.Mode = mode
+	rep.Ready = rep.Passed || mode != "strict"
+	return rep
+}
+
+func failedChecks(checks []k8s.Check) []string {
+	failed := []string{}
+	for _, c := range checks {
+		if c.Status != k8s.CheckPass {
+			failed = append(failed, c.Name)
+		}
+	}
+	return failed
+}
+
+func (h *Handler) readyz(w http.ResponseWriter, r *http.Request) {
+	rep := h.selfCheckReport()
+	code := http.StatusOK
+	if !rep.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, rep)
+}
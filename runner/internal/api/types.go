@@ -1,36 +1,140 @@
 package api
 
-import "github.com/mcp-orc/runner/internal/policy"
+import (
+	"time"
+
+	"github.com/mcp-orc/runner/internal/k8s"
+	"github.com/mcp-orc/runner/internal/policy"
+	"github.com/mcp-orc/runner/internal/quarantine"
+)
 
 type CreateRunRequest struct {
 	ImageRef             string            `json:"image_ref"`
 	Command              []string          `json:"command,omitempty"`
 	Args                 []string          `json:"args,omitempty"`
 	EnvAllowlist         map[string]string `json:"env_allowlist,omitempty"`
+	Secrets              []string          `json:"secrets,omitempty"`
 	AllowedTools         []string          `json:"allowed_tools,omitempty"`
 	DownstreamPort       int               `json:"downstream_port,omitempty"`
 	CPU                  string            `json:"cpu,omitempty"`
+	CPULimit             string            `json:"cpu_limit,omitempty"`
 	Memory               string            `json:"memory,omitempty"`
+	MemoryLimit          string            `json:"memory_limit,omitempty"`
+	EphemeralStorage     string            `json:"ephemeral_storage,omitempty"`
 	TimeoutSeconds       int64             `json:"timeout_seconds,omitempty"`
 	NetworkPolicyProfile string            `json:"network_policy_profile"`
+	// RunMode is "pod" (default) or "job"; see k8s.RunModeJob.
+	RunMode string `json:"run_mode,omitempty"`
+	// WaitForReady holds the response until the run is ready to serve tool
+	// calls, as POST /runs/{run_id}/wait does.
+	WaitForReady        bool  `json:"wait_for_ready,omitempty"`
+	ReadyTimeoutSeconds int64 `json:"ready_timeout_seconds,omitempty"`
+	MCPHandshake        bool  `json:"mcp_handshake,omitempty"`
+	// IdleTimeoutSeconds stops the run after this long without a tool call
+	// or keepalive; 0 uses RUNNER_DEFAULT_IDLE_TIMEOUT_SECONDS.
+	IdleTimeoutSeconds int64 `json:"idle_timeout_seconds,omitempty"`
 }
 
 type CreateRunResponse struct {
 	RunID          string          `json:"run_id"`
 	PodName        string          `json:"pod_name"`
+	RunMode        string          `json:"run_mode"`
 	ImageDigest    string          `json:"image_digest"`
 	PolicyEvidence policy.Evidence `json:"policy_evidence"`
+	// Ready and ReadyError are only set when wait_for_ready was requested.
+	Ready      *bool  `json:"ready,omitempty"`
+	ReadyError string `json:"ready_error,omitempty"`
+}
+
+type WaitRequest struct {
+	TimeoutSeconds int64 `json:"timeout_seconds,omitempty"`
+	MCPHandshake   bool  `json:"mcp_handshake,omitempty"`
+}
+
+type WaitResponse struct {
+	RunID  string `json:"run_id"`
+	Ready  bool   `json:"ready"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	// Handshake is "skipped", "ok" or "failed".
+	Handshake    string `json:"handshake"`
+	WaitedMillis int64  `json:"waited_ms"`
+	Error        string `json:"error,omitempty"`
+	Terminal     bool   `json:"-"`
 }
 
 type RunStatusResponse struct {
 	RunID          string          `json:"run_id"`
 	Status         string          `json:"status"`
 	PodName        string          `json:"pod_name"`
+	RunMode        string          `json:"run_mode"`
 	Namespace      string          `json:"namespace"`
 	Reason         string          `json:"reason,omitempty"`
 	PodIP          string          `json:"pod_ip,omitempty"`
 	ImageDigest    string          `json:"image_digest,omitempty"`
 	PolicyEvidence policy.Evidence `json:"policy_evidence"`
+
+	IdleTimeoutSeconds int64      `json:"idle_timeout_seconds,omitempty"`
+	LastActivity       time.Time  `json:"last_activity"`
+	QuarantinedAt      *time.Time `json:"quarantined_at,omitempty"`
+
+	// FailureReason is a normalized cause (e.g. image_pull_failed) for a run
+	// that is stuck or failed; Events and Containers are the evidence.
+	FailureReason string               `json:"failure_reason,omitempty"`
+	Events        []k8s.Event          `json:"events"`
+	Containers    []k8s.ContainerState `json:"containers"`
+}
+
+// GCStats counts what the garbage collector did since the runner started.
+// Counters are keyed by run status; pods deleted without a run record and
+// still running are counted as "orphaned".
+type GCStats struct {
+	IntervalSeconds    int64            `json:"interval_seconds"`
+	PodRetention       map[string]int64 `json:"pod_retention_seconds"`
+	RecordRetention    map[string]int64 `json:"record_retention_seconds"`
+	Sweeps             int64            `json:"sweeps"`
+	LastSweep          *time.Time       `json:"last_sweep,omitempty"`
+	LastDurationMillis int64            `json:"last_duration_ms"`
+	LastError          string           `json:"last_error,omitempty"`
+	RunsFinished       map[string]int64 `json:"runs_finished"`
+	PodsDeleted        map[string]int64 `json:"pods_deleted"`
+	RecordsDeleted     map[string]int64 `json:"records_deleted"`
+	SecretsExpired     int64            `json:"secrets_expired"`
+}
+
+// SelfCheckReport is the latest cluster self-check, served on /readyz.
+// Passed is false while any check fails; Ready is false only when that
+// happens in strict mode.
+type SelfCheckReport struct {
+	Mode      string      `json:"mode"`
+	Ready     bool        `json:"ready"`
+	Passed    bool        `json:"passed"`
+	CheckedAt *time.Time  `json:"checked_at,omitempty"`
+	Checks    []k8s.Check `json:"checks"`
+}
+
+type KeepaliveResponse struct {
+	RunID              string     `json:"run_id"`
+	LastActivity       time.Time  `json:"last_activity"`
+	IdleTimeoutSeconds int64      `json:"idle_timeout_seconds"`
+	IdleExpiresAt      *time.Time `json:"idle_expires_at,omitempty"`
+}
+
+type QuarantineRequest struct {
+	Reason string `json:"reason"`
+}
+
+type QuarantineResponse struct {
+	RunID          string    `json:"run_id"`
+	Status         string    `json:"status"`
+	Trigger        string    `json:"trigger"`
+	QuarantinedAt  time.Time `json:"quarantined_at"`
+	SHA256         string    `json:"evidence_sha256"`
+	EvidenceErrors []string  `json:"evidence_errors,omitempty"`
+}
+
+type QuarantineListResponse struct {
+	Bundles []quarantine.Summary `json:"bundles"`
 }
 
 type LogsResponse struct {
@@ -49,3 +153,30 @@ type ToolInvokeResponse struct {
 	Output    map[string]any `json:"output"`
 	RawStatus int            `json:"raw_status"`
 }
+
+type PolicyEvaluateResponse struct {
+	Allowed        bool            `json:"allowed"`
+	PinnedImage    string          `json:"pinned_image,omitempty"`
+	Checks         []policy.Check  `json:"checks"`
+	PolicyEvidence policy.Evidence `json:"policy_evidence"`
+}
+
+type CreateExceptionRequest struct {
+	Digest     string   `json:"digest,omitempty"`
+	Repository string   `json:"repository,omitempty"`
+	Principal  string   `json:"principal"`
+	Waive      []string `json:"waive"`
+	Reason     string   `json:"reason"`
+	TTLSeconds int64    `json:"ttl_seconds"`
+}
+
+type ExceptionListResponse struct {
+	Exceptions []policy.Exception `json:"exceptions"`
+}
+
+type ConfigStatusResponse struct {
+	File            string    `json:"file,omitempty"`
+	Hash            string    `json:"config_hash"`
+	LoadedAt        time.Time `json:"loaded_at"`
+	RestartRequired []string  `json:"restart_required,omitempty"`
+}
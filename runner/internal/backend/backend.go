@@ -0,0 +1,51 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/mcp-orc/runner/internal/k8s"
+)
+
+// Backend runs the workload behind a run. *k8s.Client is the production
+// implementation; fake.Backend simulates one in memory for tests.
+type Backend interface {
+	// CreateRunPod starts the workload and returns its name.
+	CreateRunPod(ctx context.Context, in k8s.PodSpecInput) (string, error)
+	// GetPodStatus returns the workload phase and reason; a missing workload
+	// is reported as "not_found", not as an error.
+	GetPodStatus(ctx context.Context, namespace, podName string) (string, string, error)
+	GetPodLogs(ctx context.Context, namespace, podName string) (string, error)
+	// DeletePod is idempotent.
+	DeletePod(ctx context.Context, namespace, podName string) error
+	// GetPodIP returns the address tool calls are proxied to, or "" while the
+	// workload has none yet.
+	GetPodIP(ctx context.Context, namespace, podName string) (string, error)
+	InvokeTool(ctx context.Context, podIP string, port int, toolName string, payload []byte) ([]byte, int, error)
+	// PostJSON posts payload to an arbitrary path on the server, e.g. the
+	// MCP endpoint for the initialize handshake.
+	PostJSON(ctx context.Context, podIP string, port int, path string, payload []byte) ([]byte, int, error)
+	// PodReady reports whether the workload is Ready: its container runs and,
+	// with PodSpecInput.ReadinessProbe, accepts connections on its port.
+	PodReady(ctx context.Context, namespace, podName string) (bool, error)
+	// ListRunPods returns every run workload in the namespace, including
+	// ones the runner has no record of (e.g. from before a restart).
+	ListRunPods(ctx context.Context, namespace string) ([]k8s.RunPod, error)
+	DeleteRunSecret(ctx context.Context, namespace, name string) error
+	// ClaimPod hands a running warm pool pod (PodSpecInput.Warm) to a run:
+	// it takes the run's ID, network profile and timeout. It fails if the pod
+	// is not a running, unclaimed warm pod.
+	ClaimPod(ctx context.Context, namespace, podName string, claim k8s.Claim) error
+	// QuarantinePod cuts the workload off from the network and marks it
+	// quarantined, which ListRunPods reports. It is idempotent.
+	QuarantinePod(ctx context.Context, namespace, podName string) error
+	// ReleasePod lifts the quarantine and restores networkProfile. A missing
+	// workload is not an error.
+	ReleasePod(ctx context.Context, namespace, podName, networkProfile string) error
+	// DescribePod returns the workload's container states and events, for
+	// diagnosing a run that does not start or fails.
+	DescribePod(ctx context.Context, namespace, podName string) (k8s.PodDiagnostics, error)
+	// CollectEvidence captures the workload's state for a quarantine bundle.
+	CollectEvidence(ctx context.Context, namespace, podName string) (k8s.Evidence, error)
+}
+
+var _ Backend = (*k8s.Client)(nil)
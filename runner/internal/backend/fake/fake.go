@@ -0,0 +1,381 @@
+// Package fake is an in-memory backend.Backend. Pods start Pending, become
+// Running with an address on their first status read, and answer tool calls
+// from canned responses.
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mcp-orc/runner/internal/k8s"
+)
+
+var ErrNotFound = errors.New("pod not found")
+
+type Pod struct {
+	Spec    k8s.PodSpecInput
+	Phase   string
+	Reason  string
+	IP      string
+	Logs    string
+	Deleted bool
+	// CreatedAt and FinishedAt are reported by ListRunPods; SetPhase sets
+	// FinishedAt when the phase is terminal.
+	CreatedAt  time.Time
+	FinishedAt time.Time
+	// Claim is set once a warm pod has been handed to a run.
+	Claim *k8s.Claim
+	// NotReady keeps a Running pod from reporting Ready.
+	NotReady bool
+	// Diagnostics is what DescribePod reports; see SetDiagnostics.
+	Diagnostics k8s.PodDiagnostics
+	// Quarantined is set by QuarantinePod and cleared by ReleasePod, which
+	// also records the restored profile in Spec.NetworkProfile.
+	Quarantined bool
+}
+
+type ToolResponse struct {
+	Status int
+	Body   string
+}
+
+type Call struct {
+	PodIP   string
+	Port    int
+	Tool    string
+	Payload []byte
+}
+
+type Backend struct {
+	mu             sync.Mutex
+	pods           map[string]*Pod
+	tools          map[string]ToolResponse
+	calls          []Call
+	deletedSecrets []string
+	nextIP         int
+
+	// Errors returned by the matching method when set.
+	CreateErr error
+	StatusErr error
+	LogsErr   error
+	DeleteErr error
+	InvokeErr error
+	ClaimErr  error
+	ListErr   error
+	// QuarantineErr fails QuarantinePod and ReleasePod.
+	QuarantineErr error
+	// BeforeDelete, when set, runs at the start of DeletePod, outside the
+	// backend's lock; tests use it to hold a deletion in flight.
+	BeforeDelete func(podName string)
+	// BeforeQuarantine, when set, runs at the start of QuarantinePod, outside
+	// the backend's lock; tests use it to hold an isolation in flight.
+	BeforeQuarantine func(podName string)
+}
+
+func New() *Backend {
+	return &Backend{pods: map[string]*Pod{}, tools: map[string]ToolResponse{}}
+}
+
+func key(namespace, name string) string { return namespace + "/" + name }
+
+func (b *Backend) CreateRunPod(_ context.Context, in k8s.PodSpecInput) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.CreateErr != nil {
+		return "", b.CreateErr
+	}
+	name := k8s.RunName(in.RunID, in.RunMode)
+	b.pods[key(in.Namespace, name)] = &Pod{Spec: in, Phase: "Pending", CreatedAt: time.Now()}
+	return name, nil
+}
+
+func (b *Backend) GetPodStatus(_ context.Context, namespace, podName string) (string, string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.StatusErr != nil {
+		return "", "", b.StatusErr
+	}
+	p, ok := b.pods[key(namespace, podName)]
+	if !ok || p.Deleted {
+		return "not_found", "pod_missing", nil
+	}
+	if p.Phase == "Pending" {
+		b.nextIP++
+		p.Phase, p.IP = "Running", fmt.Sprintf("10.0.0.%d", b.nextIP)
+	}
+	return p.Phase, p.Reason, nil
+}
+
+func (b *Backend) GetPodLogs(_ context.Context, namespace, podName string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.LogsErr != nil {
+		return "", b.LogsErr
+	}
+	p, ok := b.pods[key(namespace, podName)]
+	if !ok || p.Deleted {
+		return "", ErrNotFound
+	}
+	return p.Logs, nil
+}
+
+func (b *Backend) DeletePod(_ context.Context, namespace, podName string) error {
+	if b.BeforeDelete != nil {
+		b.BeforeDelete(podName)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.DeleteErr != nil {
+		return b.DeleteErr
+	}
+	if p, ok := b.pods[key(namespace, podName)]; ok {
+		p.Deleted, p.IP = true, ""
+	}
+	return nil
+}
+
+func (b *Backend) GetPodIP(_ context.Context, namespace, podName string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, ok := b.pods[key(namespace, podName)]
+	if !ok || p.Deleted {
+		return "", ErrNotFound
+	}
+	return p.IP, nil
+}
+
+func (b *Backend) InvokeTool(_ context.Context, podIP string, port int, toolName string, payload []byte) ([]byte, int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, Call{PodIP: podIP, Port: port, Tool: toolName, Payload: payload})
+	if b.InvokeErr != nil {
+		return nil, 0, b.InvokeErr
+	}
+	resp, ok := b.tools[toolName]
+	if !ok {
+		return []byte(`{"error":"unknown tool"}`), 404, nil
+	}
+	return []byte(resp.Body), resp.Status, nil
+}
+
+// PostJSON answers from the response set with SetTool for path.
+func (b *Backend) PostJSON(_ context.Context, podIP string, port int, path string, payload []byte) ([]byte, int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, Call{PodIP: podIP, Port: port, Tool: path, Payload: payload})
+	if b.InvokeErr != nil {
+		return nil, 0, b.InvokeErr
+	}
+	resp, ok := b.tools[path]
+	if !ok {
+		return []byte(`{"error":"not found"}`), 404, nil
+	}
+	return []byte(resp.Body), resp.Status, nil
+}
+
+// PodReady is true for Running pods unless SetReady marked them otherwise.
+func (b *Backend) PodReady(_ context.Context, namespace, podName string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, ok := b.pods[key(namespace, podName)]
+	if !ok || p.Deleted {
+		return false, nil
+	}
+	return p.Phase == "Running" && !p.NotReady, nil
+}
+
+func (b *Backend) SetReady(namespace, podName string, ready bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if p, ok := b.pods[key(namespace, podName)]; ok {
+		p.NotReady = !ready
+	}
+}
+
+// ListRunPods returns the live pods in namespace, warm ones included.
+func (b *Backend) ListRunPods(_ context.Context, namespace string) ([]k8s.RunPod, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ListErr != nil {
+		return nil, b.ListErr
+	}
+	out := []k8s.RunPod{}
+	for _, p := range b.pods {
+		if p.Deleted || p.Spec.Namespace != namespace {
+			continue
+		}
+		runID := p.Spec.RunID
+		if p.Claim != nil {
+			runID = p.Claim.RunID
+		}
+		rp := k8s.RunPod{
+			Name:        k8s.RunName(p.Spec.RunID, p.Spec.RunMode),
+			RunID:       runID,
+			Phase:       p.Phase,
+			Reason:      p.Reason,
+			Warm:        p.Spec.Warm && p.Claim == nil,
+			Quarantined: p.Quarantined,
+			CreatedAt:   p.CreatedAt,
+			FinishedAt:  p.FinishedAt,
+		}
+		if len(p.Spec.Secrets) > 0 && p.Spec.SecretTTLSeconds > 0 {
+			rp.SecretsExpireAt = p.CreatedAt.Add(time.Duration(p.Spec.SecretTTLSeconds) * time.Second)
+		}
+		out = append(out, rp)
+	}
+	return out, nil
+}
+
+func (b *Backend) DeleteRunSecret(_ context.Context, namespace, name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deletedSecrets = append(b.deletedSecrets, key(namespace, name))
+	return nil
+}
+
+func (b *Backend) ClaimPod(_ context.Context, namespace, podName string, claim k8s.Claim) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ClaimErr != nil {
+		return b.ClaimErr
+	}
+	p, ok := b.pods[key(namespace, podName)]
+	if !ok || p.Deleted {
+		return ErrNotFound
+	}
+	if !p.Spec.Warm || p.Claim != nil || p.Phase != "Running" {
+		return fmt.Errorf("pod %s is not a running, unclaimed warm pod", podName)
+	}
+	p.Claim = &claim
+	return nil
+}
+
+func (b *Backend) QuarantinePod(_ context.Context, namespace, podName string) error {
+	if b.BeforeQuarantine != nil {
+		b.BeforeQuarantine(podName)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.QuarantineErr != nil {
+		return b.QuarantineErr
+	}
+	p, ok := b.pods[key(namespace, podName)]
+	if !ok || p.Deleted {
+		return ErrNotFound
+	}
+	p.Quarantined = true
+	return nil
+}
+
+func (b *Backend) ReleasePod(_ context.Context, namespace, podName, networkProfile string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.QuarantineErr != nil {
+		return b.QuarantineErr
+	}
+	if p, ok := b.pods[key(namespace, podName)]; ok && !p.Deleted {
+		p.Quarantined, p.Spec.NetworkProfile = false, networkProfile
+	}
+	return nil
+}
+
+func (b *Backend) DescribePod(_ context.Context, namespace, podName string) (k8s.PodDiagnostics, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.StatusErr != nil {
+		return k8s.PodDiagnostics{}, b.StatusErr
+	}
+	p, ok := b.pods[key(namespace, podName)]
+	if !ok || p.Deleted {
+		return k8s.PodDiagnostics{Containers: []k8s.ContainerState{}, Events: []k8s.Event{}}, nil
+	}
+	return p.Diagnostics, nil
+}
+
+// SetDiagnostics sets what DescribePod reports for a pod.
+func (b *Backend) SetDiagnostics(namespace, podName string, d k8s.PodDiagnostics) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if p, ok := b.pods[key(namespace, podName)]; ok {
+		p.Diagnostics = d
+	}
+}
+
+// CollectEvidence reports the pod's phase as its container status and its
+// logs; it fails for a missing pod.
+func (b *Backend) CollectEvidence(_ context.Context, namespace, podName string) (k8s.Evidence, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, ok := b.pods[key(namespace, podName)]
+	if !ok || p.Deleted {
+		return k8s.Evidence{}, ErrNotFound
+	}
+	status, _ := json.Marshal([]map[string]string{{"name": "untrusted-mcp", "phase": p.Phase, "reason": p.Reason}})
+	return k8s.Evidence{CollectedAt: time.Now().UTC(), ContainerStatuses: status, Events: []k8s.Event{}, Logs: p.Logs}, nil
+}
+
+// SetPhase moves a pod to phase, e.g. "Succeeded" or "Failed".
+func (b *Backend) SetPhase(namespace, podName, phase, reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if p, ok := b.pods[key(namespace, podName)]; ok {
+		p.Phase, p.Reason = phase, reason
+		if phase == "Succeeded" || phase == "Failed" {
+			p.FinishedAt = time.Now()
+		}
+	}
+}
+
+func (b *Backend) SetLogs(namespace, podName, logs string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if p, ok := b.pods[key(namespace, podName)]; ok {
+		p.Logs = logs
+	}
+}
+
+// SetTool makes every pod answer toolName with status and body. A name
+// starting with "/" is a path answered by PostJSON instead.
+func (b *Backend) SetTool(toolName string, status int, body string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tools[toolName] = ToolResponse{Status: status, Body: body}
+}
+
+// Pod returns a copy of the pod's current state.
+func (b *Backend) Pod(namespace, podName string) (Pod, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, ok := b.pods[key(namespace, podName)]
+	if !ok {
+		return Pod{}, false
+	}
+	return *p, true
+}
+
+// Pods returns copies of every pod ever created, deleted ones included.
+func (b *Backend) Pods() []Pod {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]Pod, 0, len(b.pods))
+	for _, p := range b.pods {
+		out = append(out, *p)
+	}
+	return out
+}
+
+func (b *Backend) Calls() []Call {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Call(nil), b.calls...)
+}
+
+func (b *Backend) DeletedSecrets() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.deletedSecrets...)
+}
@@ -0,0 +1,95 @@
+package local
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mcp-orc/runner/internal/policy"
+)
+
+// image is a locally available artifact for a digest-pinned reference. It is
+// either an unpacked OCI rootfs with its image config, or a single executable.
+type image struct {
+	Rootfs     string
+	Binary     string
+	Entrypoint []string
+	Cmd        []string
+	Env        []string
+	WorkingDir string
+}
+
+// resolveImage looks the reference up under dir, preferring an entry for the
+// exact digest:
+//
+//	<dir>/<registry>/<repository>/<digest hex>/
+//	<dir>/<registry>/<repository>/
+//
+// An entry holds rootfs/ plus an optional config.json (OCI image config, as
+// written by umoci or `crane config`), or an executable named entrypoint.
+// The directory is trusted as-is: the signature gate covers the reference,
+// not what was unpacked here.
+func resolveImage(dir, imageRef string) (image, error) {
+	ref, err := policy.ParseReference(imageRef)
+	if err != nil {
+		return image{}, err
+	}
+	base := filepath.Join(dir, ref.Registry, filepath.FromSlash(ref.Repository))
+	candidates := []string{base}
+	if hex, ok := strings.CutPrefix(ref.Digest, "sha256:"); ok {
+		candidates = []string{filepath.Join(base, hex), base}
+	}
+	for _, c := range candidates {
+		if fi, err := os.Stat(filepath.Join(c, "rootfs")); err == nil && fi.IsDir() {
+			img := image{Rootfs: filepath.Join(c, "rootfs")}
+			if err := readImageConfig(filepath.Join(c, "config.json"), &img); err != nil {
+				return image{}, err
+			}
+			return img, nil
+		}
+		if fi, err := os.Stat(filepath.Join(c, "entrypoint")); err == nil && fi.Mode().IsRegular() && fi.Mode()&0o111 != 0 {
+			bin := filepath.Join(c, "entrypoint")
+			return image{Binary: bin, Entrypoint: []string{bin}}, nil
+		}
+	}
+	return image{}, fmt.Errorf("no local image for %s under %s", ref.Name(), base)
+}
+
+func readImageConfig(path string, img *image) error {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var blob struct {
+		Config struct {
+			Entrypoint []string `json:"Entrypoint"`
+			Cmd        []string `json:"Cmd"`
+			Env        []string `json:"Env"`
+			WorkingDir string   `json:"WorkingDir"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(raw, &blob); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	img.Entrypoint, img.Cmd = blob.Config.Entrypoint, blob.Config.Cmd
+	img.Env, img.WorkingDir = blob.Config.Env, blob.Config.WorkingDir
+	return nil
+}
+
+// argv applies Kubernetes command/args semantics to the image defaults.
+func (img image) argv(command, args []string) []string {
+	entry, rest := img.Entrypoint, img.Cmd
+	if len(command) > 0 {
+		entry, rest = command, nil
+	}
+	if len(args) > 0 {
+		rest = args
+	}
+	return append(append([]string{}, entry...), rest...)
+}
@@ -0,0 +1,79 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mcp-orc/runner/internal/k8s"
+)
+
+const testDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+func TestResolveImagePrefersDigestEntry(t *testing.T) {
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "ghcr.io", "acme", "tool")
+	pinned := filepath.Join(repo, strings.TrimPrefix(testDigest, "sha256:"))
+	if err := os.MkdirAll(filepath.Join(pinned, "rootfs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := `{"config":{"Entrypoint":["/bin/tool"],"Cmd":["serve"],"Env":["A=1"],"WorkingDir":"/app"}}`
+	if err := os.WriteFile(filepath.Join(pinned, "config.json"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "entrypoint"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := resolveImage(dir, "ghcr.io/acme/tool@"+testDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Rootfs != filepath.Join(pinned, "rootfs") || img.WorkingDir != "/app" || !slices.Equal(img.Env, []string{"A=1"}) {
+		t.Fatalf("unexpected image %+v", img)
+	}
+	if got := img.argv(nil, nil); !slices.Equal(got, []string{"/bin/tool", "serve"}) {
+		t.Fatalf("default argv = %v", got)
+	}
+	if got := img.argv(nil, []string{"--stdio"}); !slices.Equal(got, []string{"/bin/tool", "--stdio"}) {
+		t.Fatalf("args override = %v", got)
+	}
+	if got := img.argv([]string{"/bin/other"}, nil); !slices.Equal(got, []string{"/bin/other"}) {
+		t.Fatalf("command override = %v", got)
+	}
+
+	other := "sha256:" + strings.Repeat("2", 64)
+	img, err = resolveImage(dir, "ghcr.io/acme/tool@"+other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Binary != filepath.Join(repo, "entrypoint") || img.Rootfs != "" {
+		t.Fatalf("expected repository fallback binary, got %+v", img)
+	}
+
+	if _, err := resolveImage(dir, "ghcr.io/acme/missing@"+testDigest); err == nil {
+		t.Fatal("expected error for missing image")
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	l, err := parseLimits(k8s.PodSpecInput{CPULimit: "250m", MemoryLimit: "128Mi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.cpuQuotaMicros != 25000 || l.memoryBytes != 128<<20 || l.tmpfsBytes != defaultTmpfsSize {
+		t.Fatalf("unexpected limits %+v", l)
+	}
+	l, err = parseLimits(k8s.PodSpecInput{CPULimit: "2", EphemeralStorage: "1Gi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.cpuQuotaMicros != 200000 || l.memoryBytes != 0 || l.tmpfsBytes != 1<<30 {
+		t.Fatalf("unexpected limits %+v", l)
+	}
+	if _, err := parseLimits(k8s.PodSpecInput{MemoryLimit: "lots"}); err == nil {
+		t.Fatal("expected error for malformed memory limit")
+	}
+}
@@ -0,0 +1,50 @@
+package local
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/mcp-orc/runner/internal/k8s"
+)
+
+const (
+	cpuPeriodMicros  = 100000
+	maxPids          = 512
+	defaultTmpfsSize = 64 << 20
+)
+
+type limits struct {
+	cpuQuotaMicros int64
+	memoryBytes    int64
+	tmpfsBytes     int64
+}
+
+// parseLimits maps the pod limits onto cgroup v2 values. Requests have no
+// local equivalent; ephemeral storage bounds the run's writable /tmp.
+func parseLimits(in k8s.PodSpecInput) (limits, error) {
+	var l limits
+	if in.CPULimit != "" {
+		q, err := resource.ParseQuantity(in.CPULimit)
+		if err != nil {
+			return l, fmt.Errorf("cpu limit: %w", err)
+		}
+		l.cpuQuotaMicros = q.MilliValue() * cpuPeriodMicros / 1000
+	}
+	if in.MemoryLimit != "" {
+		q, err := resource.ParseQuantity(in.MemoryLimit)
+		if err != nil {
+			return l, fmt.Errorf("memory limit: %w", err)
+		}
+		l.memoryBytes = q.Value()
+	}
+	l.tmpfsBytes = defaultTmpfsSize
+	if in.EphemeralStorage != "" {
+		q, err := resource.ParseQuantity(in.EphemeralStorage)
+		if err != nil {
+			return l, fmt.Errorf("ephemeral storage: %w", err)
+		}
+		l.tmpfsBytes = q.Value()
+	}
+	return l, nil
+}
@@ -0,0 +1,525 @@
+// Package local runs MCP servers as sandboxed local processes instead of
+// Kubernetes pods, for development on a laptop. Each run gets its own user,
+// mount, PID, network, IPC and UTS namespaces, a seccomp filter, and (when a
+// delegated cgroup v2 directory is configured) CPU, memory and PID limits.
+// The network namespace has only loopback, so every run behaves like the
+// deny-all profile; tool calls reach the server through a unix socket.
+package local
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mcp-orc/runner/internal/k8s"
+	"github.com/mcp-orc/runner/internal/secrets"
+)
+
+const maxLogBytes = 4 << 20
+
+type Options struct {
+	// ImageDir holds unpacked images; see resolveImage.
+	ImageDir string
+	// StateDir holds per-run logs, sockets and secrets.
+	StateDir string
+	// Cgroup is a delegated cgroup v2 directory runs are placed under. When
+	// empty, resource limits are not enforced.
+	Cgroup string
+	// HiddenDirs are host directories a run without a rootfs must not see,
+	// such as the file secrets backend's. StateDir and $HOME always are.
+	HiddenDirs []string
+}
+
+type run struct {
+	runID    string
+	created  time.Time
+	finished time.Time
+	dir      string
+	socket   string
+	phase    string
+	reason   string
+	exitCode int
+	cancel   context.CancelFunc
+	done     chan struct{}
+	deadline bool
+	timer    *time.Timer
+	warm     bool
+	probe    bool
+	// quarantined runs keep running but get no address; see QuarantinePod.
+	quarantined bool
+	// secretsExpire is when the garbage collector removes the secrets dir.
+	secretsExpire time.Time
+}
+
+type Backend struct {
+	opts Options
+
+	mu   sync.Mutex
+	runs map[string]*run
+}
+
+func New(opts Options) (*Backend, error) {
+	if opts.ImageDir == "" {
+		return nil, errors.New("local backend: image dir is required")
+	}
+	if opts.StateDir == "" {
+		opts.StateDir = filepath.Join(os.TempDir(), "mcp-runner")
+	}
+	if err := os.MkdirAll(opts.StateDir, 0o700); err != nil {
+		return nil, fmt.Errorf("local backend: %w", err)
+	}
+	if err := checkSupport(opts); err != nil {
+		return nil, fmt.Errorf("local backend: %w", err)
+	}
+	return &Backend{opts: opts, runs: map[string]*run{}}, nil
+}
+
+func key(namespace, name string) string { return namespace + "/" + name }
+
+func (b *Backend) CreateRunPod(_ context.Context, in k8s.PodSpecInput) (string, error) {
+	if in.RunMode == k8s.RunModeJob {
+		return "", errors.New("job runs need the kubernetes backend")
+	}
+	img, err := resolveImage(b.opts.ImageDir, in.ImageRef)
+	if err != nil {
+		return "", err
+	}
+	argv := img.argv(in.Command, in.Args)
+	if len(argv) == 0 {
+		return "", errors.New("image has no entrypoint or cmd and no command was given")
+	}
+	limits, err := parseLimits(in)
+	if err != nil {
+		return "", err
+	}
+
+	name := "run-" + in.RunID
+	dir := filepath.Join(b.opts.StateDir, name)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	spec := sandboxSpec{
+		RunID:      in.RunID,
+		Rootfs:     img.Rootfs,
+		Argv:       argv,
+		Env:        sandboxEnv(img, in),
+		WorkingDir: img.WorkingDir,
+		Socket:     filepath.Join(dir, "mcp.sock"),
+		Port:       in.DownstreamPort,
+		TmpfsBytes: limits.tmpfsBytes,
+	}
+	if spec.Rootfs == "" {
+		if err := os.Mkdir(binaryTmpDir(spec), 0o700); err != nil {
+			_ = os.RemoveAll(dir)
+			return "", err
+		}
+		spec.Env = append(spec.Env, "TMPDIR="+binaryTmpDir(spec))
+		spec.Hidden = b.hiddenDirs()
+	}
+	if len(in.Secrets) > 0 {
+		spec.SecretsDir = filepath.Join(dir, "secrets")
+		if err := writeSecrets(spec.SecretsDir, in.Secrets); err != nil {
+			_ = os.RemoveAll(dir)
+			return "", err
+		}
+		if spec.Rootfs == "" {
+			spec.Env = append(spec.Env, "MCP_SECRETS_DIR="+spec.SecretsDir)
+		} else {
+			spec.Env = append(spec.Env, "MCP_SECRETS_DIR="+secrets.MountPath)
+		}
+	}
+	logFile, err := os.OpenFile(filepath.Join(dir, "output.log"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &run{runID: in.RunID, created: time.Now(), dir: dir, socket: spec.Socket, phase: "Pending", cancel: cancel, done: make(chan struct{}), warm: in.Warm, probe: in.ReadinessProbe}
+	if len(in.Secrets) > 0 && in.SecretTTLSeconds > 0 {
+		r.secretsExpire = r.created.Add(time.Duration(in.SecretTTLSeconds) * time.Second)
+	}
+	b.mu.Lock()
+	b.runs[key(in.Namespace, name)] = r
+	b.mu.Unlock()
+
+	proc, err := startSandbox(ctx, b.opts, spec, limits, logFile)
+	logFile.Close()
+	if err != nil {
+		cancel()
+		b.forget(in.Namespace, name)
+		_ = os.RemoveAll(dir)
+		return "", fmt.Errorf("start sandbox: %w", err)
+	}
+	b.setPhase(r, "Running", "")
+
+	if in.TimeoutSeconds > 0 {
+		timer := time.AfterFunc(time.Duration(in.TimeoutSeconds)*time.Second, func() {
+			b.mu.Lock()
+			r.deadline = true
+			b.mu.Unlock()
+			cancel()
+		})
+		b.mu.Lock()
+		r.timer = timer
+		b.mu.Unlock()
+	}
+	go func() {
+		defer close(r.done)
+		code, oom, err := proc.wait()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		r.exitCode = code
+		switch {
+		case r.deadline:
+			r.phase, r.reason = "Failed", "DeadlineExceeded"
+		case oom:
+			r.phase, r.reason = "Failed", "OOMKilled"
+		case err == nil && code == 0:
+			r.phase, r.reason = "Succeeded", ""
+		default:
+			r.phase, r.reason = "Failed", "Error"
+		}
+		r.finished = time.Now()
+		_ = os.Remove(r.socket)
+	}()
+	return name, nil
+}
+
+// hiddenDirs are the host directories covered for a run without a rootfs:
+// the state dir holds every other run's secrets and sockets, and $HOME the
+// developer's credentials. Nested entries are dropped; the outer one covers
+// them.
+func (b *Backend) hiddenDirs() []string {
+	dirs := append([]string{b.opts.StateDir}, b.opts.HiddenDirs...)
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, home)
+	}
+	var abs []string
+	for _, d := range dirs {
+		if d == "" {
+			continue
+		}
+		if a, err := filepath.Abs(d); err == nil && a != "/" {
+			abs = append(abs, a)
+		}
+	}
+	sort.Strings(abs)
+	var out []string
+	for _, d := range abs {
+		if len(out) > 0 && isWithin(d, out[len(out)-1]) {
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// isWithin reports whether path is dir or below it.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+func (b *Backend) lookup(namespace, name string) (*run, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r, ok := b.runs[key(namespace, name)]
+	return r, ok
+}
+
+func (b *Backend) forget(namespace, name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.runs, key(namespace, name))
+}
+
+func (b *Backend) setPhase(r *run, phase, reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r.phase, r.reason = phase, reason
+}
+
+func (b *Backend) GetPodStatus(_ context.Context, namespace, podName string) (string, string, error) {
+	r, ok := b.lookup(namespace, podName)
+	if !ok {
+		return "not_found", "pod_missing", nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return r.phase, r.reason, nil
+}
+
+func (b *Backend) GetPodLogs(_ context.Context, namespace, podName string) (string, error) {
+	r, ok := b.lookup(namespace, podName)
+	if !ok {
+		return "", fmt.Errorf("run %s not found", podName)
+	}
+	f, err := os.Open(filepath.Join(r.dir, "output.log"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil && fi.Size() > maxLogBytes {
+		_, _ = f.Seek(-maxLogBytes, io.SeekEnd)
+	}
+	raw, err := io.ReadAll(f)
+	return string(raw), err
+}
+
+// DeletePod kills the sandbox and removes its state, like deleting a pod
+// with a zero grace period.
+func (b *Backend) DeletePod(_ context.Context, namespace, podName string) error {
+	r, ok := b.lookup(namespace, podName)
+	if !ok {
+		return nil
+	}
+	r.cancel()
+	select {
+	case <-r.done:
+	case <-time.After(10 * time.Second):
+		return fmt.Errorf("run %s did not exit", podName)
+	}
+	b.forget(namespace, podName)
+	return os.RemoveAll(r.dir)
+}
+
+// GetPodIP returns the run's unix socket as "unix:<path>" while it runs.
+func (b *Backend) GetPodIP(_ context.Context, namespace, podName string) (string, error) {
+	r, ok := b.lookup(namespace, podName)
+	if !ok {
+		return "", fmt.Errorf("run %s not found", podName)
+	}
+	b.mu.Lock()
+	running := r.phase == "Running" && !r.quarantined
+	b.mu.Unlock()
+	if _, err := os.Stat(r.socket); err != nil || !running {
+		return "", nil
+	}
+	return "unix:" + r.socket, nil
+}
+
+// InvokeTool posts to the server through the sandbox's socket proxy, which
+// forwards to the downstream port inside the run's network namespace.
+func (b *Backend) InvokeTool(ctx context.Context, addr string, port int, toolName string, payload []byte) ([]byte, int, error) {
+	return b.PostJSON(ctx, addr, port, "/tools/"+toolName, payload)
+}
+
+func (b *Backend) PostJSON(ctx context.Context, addr string, _ int, path string, payload []byte) ([]byte, int, error) {
+	socket, ok := strings.CutPrefix(addr, "unix:")
+	if !ok {
+		return nil, 0, fmt.Errorf("unexpected sandbox address %q", addr)
+	}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://sandbox"+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return body, resp.StatusCode, nil
+}
+
+// PodReady mirrors the TCP readiness probe. The socket proxy dials the
+// server as soon as it accepts a connection and hangs up if that fails, so a
+// connection that stays open for a moment means the port is accepting.
+func (b *Backend) PodReady(_ context.Context, namespace, podName string) (bool, error) {
+	r, ok := b.lookup(namespace, podName)
+	if !ok {
+		return false, nil
+	}
+	b.mu.Lock()
+	running, probe := r.phase == "Running", r.probe
+	b.mu.Unlock()
+	if !running || !probe {
+		return running, nil
+	}
+	conn, err := net.DialTimeout("unix", r.socket, time.Second)
+	if err != nil {
+		return false, nil
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, err = conn.Read(make([]byte, 1))
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout(), nil
+}
+
+// ClaimPod restarts a warm run's deadline with the claiming run's timeout.
+// There are no labels or network policies to update locally.
+func (b *Backend) ClaimPod(_ context.Context, namespace, podName string, claim k8s.Claim) error {
+	r, ok := b.lookup(namespace, podName)
+	if !ok {
+		return fmt.Errorf("run %s not found", podName)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !r.warm || r.phase != "Running" {
+		return fmt.Errorf("run %s is not a running, unclaimed warm run", podName)
+	}
+	if r.timer != nil && !r.timer.Stop() {
+		return fmt.Errorf("run %s reached its deadline", podName)
+	}
+	r.warm, r.runID = false, claim.RunID
+	if r.timer != nil {
+		r.timer.Reset(time.Duration(claim.TimeoutSeconds) * time.Second)
+	}
+	return nil
+}
+
+func (b *Backend) ListRunPods(_ context.Context, namespace string) ([]k8s.RunPod, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := []k8s.RunPod{}
+	for k, r := range b.runs {
+		ns, name, _ := strings.Cut(k, "/")
+		if ns != namespace {
+			continue
+		}
+		out = append(out, k8s.RunPod{Name: name, RunID: r.runID, Phase: r.phase, Reason: r.reason, Warm: r.warm, Quarantined: r.quarantined, CreatedAt: r.created, FinishedAt: r.finished, SecretsExpireAt: r.secretsExpire})
+	}
+	return out, nil
+}
+
+// QuarantinePod withholds the run's socket from tool calls. The sandbox has
+// no network to cut; its state stays on disk until DeletePod.
+func (b *Backend) QuarantinePod(_ context.Context, namespace, podName string) error {
+	r, ok := b.lookup(namespace, podName)
+	if !ok {
+		return fmt.Errorf("run %s not found", podName)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r.quarantined = true
+	return nil
+}
+
+func (b *Backend) ReleasePod(_ context.Context, namespace, podName, _ string) error {
+	r, ok := b.lookup(namespace, podName)
+	if !ok {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r.quarantined = false
+	return nil
+}
+
+// DescribePod reports the sandbox process as the run's only container.
+// There are no events locally.
+func (b *Backend) DescribePod(_ context.Context, namespace, podName string) (k8s.PodDiagnostics, error) {
+	d := k8s.PodDiagnostics{Containers: []k8s.ContainerState{}, Events: []k8s.Event{}}
+	r, ok := b.lookup(namespace, podName)
+	if !ok {
+		return d, nil
+	}
+	b.mu.Lock()
+	d.Containers = append(d.Containers, containerState(r))
+	b.mu.Unlock()
+	return d, nil
+}
+
+// containerState maps the run's phase to a container state. b.mu must be
+// held.
+func containerState(r *run) k8s.ContainerState {
+	cs := k8s.ContainerState{Name: "untrusted-mcp", Reason: r.reason}
+	switch r.phase {
+	case "Pending":
+		cs.State = "waiting"
+	case "Running":
+		cs.State = "running"
+	default:
+		code := int32(r.exitCode)
+		cs.State, cs.ExitCode = "terminated", &code
+	}
+	return cs
+}
+
+// CollectEvidence captures the run's container state and output log.
+func (b *Backend) CollectEvidence(ctx context.Context, namespace, podName string) (k8s.Evidence, error) {
+	r, ok := b.lookup(namespace, podName)
+	if !ok {
+		return k8s.Evidence{}, fmt.Errorf("run %s not found", podName)
+	}
+	ev := k8s.Evidence{CollectedAt: time.Now().UTC(), Events: []k8s.Event{}}
+	b.mu.Lock()
+	ev.ContainerStatuses, _ = json.Marshal([]k8s.ContainerState{containerState(r)})
+	b.mu.Unlock()
+	logs, err := b.GetPodLogs(ctx, namespace, podName)
+	if err != nil {
+		ev.Errors = append(ev.Errors, "logs: "+err.Error())
+	}
+	ev.Logs = logs
+	return ev, nil
+}
+
+// DeleteRunSecret removes the run's secret files; name is
+// k8s.RunSecretName(podName).
+func (b *Backend) DeleteRunSecret(_ context.Context, namespace, name string) error {
+	r, ok := b.lookup(namespace, strings.TrimSuffix(name, "-secrets"))
+	if !ok {
+		return nil
+	}
+	return os.RemoveAll(filepath.Join(r.dir, "secrets"))
+}
+
+func sandboxEnv(img image, in k8s.PodSpecInput) []string {
+	env := []string{}
+	hasPath := false
+	for _, kv := range img.Env {
+		if _, ok := in.EnvAllowlist[strings.SplitN(kv, "=", 2)[0]]; ok {
+			continue
+		}
+		hasPath = hasPath || strings.HasPrefix(kv, "PATH=")
+		env = append(env, kv)
+	}
+	for k, v := range in.EnvAllowlist {
+		env = append(env, k+"="+v)
+	}
+	if !hasPath {
+		env = append(env, "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin")
+	}
+	return env
+}
+
+// writeSecrets lays secrets out as <dir>/<name>/<key>, the same shape as the
+// projected volume on Kubernetes.
+func writeSecrets(dir string, list []secrets.Secret) error {
+	for _, s := range list {
+		sd := filepath.Join(dir, s.Name)
+		if err := os.MkdirAll(sd, 0o700); err != nil {
+			return err
+		}
+		for k, v := range s.Data {
+			if err := os.WriteFile(filepath.Join(sd, k), v, 0o400); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mcp-orc/runner/internal/k8s"
+	"github.com/mcp-orc/runner/internal/secrets"
+)
+
+// The sandbox re-executes the test binary for its init and exec stages.
+func TestMain(m *testing.M) {
+	MaybeRunSandbox()
+	os.Exit(m.Run())
+}
+
+// newTestBackend returns a backend whose image dir holds one binary image,
+// ghcr.io/acme/tool, running script.
+func newTestBackend(t *testing.T, script string, hidden ...string) *Backend {
+	t.Helper()
+	images := t.TempDir()
+	repo := filepath.Join(images, "ghcr.io", "acme", "tool")
+	if err := os.MkdirAll(repo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "entrypoint"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{ImageDir: images, StateDir: t.TempDir(), HiddenDirs: hidden}
+	if err := checkSupport(opts); err != nil {
+		t.Skipf("no sandbox support: %v", err)
+	}
+	b, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCreateRunPodRejects(t *testing.T) {
+	b := newTestBackend(t, "#!/bin/sh\n")
+	ctx := context.Background()
+	for label, in := range map[string]k8s.PodSpecInput{
+		"job mode":      {RunID: "a", RunMode: k8s.RunModeJob, ImageRef: "ghcr.io/acme/tool@" + testDigest},
+		"missing image": {RunID: "b", ImageRef: "ghcr.io/acme/other@" + testDigest},
+		"bad limit":     {RunID: "c", ImageRef: "ghcr.io/acme/tool@" + testDigest, CPULimit: "fast"},
+	} {
+		if _, err := b.CreateRunPod(ctx, in); err == nil {
+			t.Errorf("%s: expected error", label)
+		}
+	}
+	entries, _ := os.ReadDir(b.opts.StateDir)
+	if len(entries) != 0 {
+		t.Errorf("rejected runs left state behind: %v", entries)
+	}
+}
+
+func TestCreateRunPodHidesHostState(t *testing.T) {
+	home, extra := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	script := `#!/bin/sh
+for f in "$@"; do
+  if [ -e "$f" ]; then echo "visible $f"; else echo "hidden $f"; fi
+done
+cat "$MCP_SECRETS_DIR/api/token"
+`
+	b := newTestBackend(t, script, extra)
+	other := filepath.Join(b.opts.StateDir, "run-other")
+	for _, f := range []string{filepath.Join(home, ".netrc"), filepath.Join(extra, "db.json"), filepath.Join(other, "secrets")} {
+		if err := os.MkdirAll(filepath.Dir(f), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	in := k8s.PodSpecInput{
+		Namespace: "mcp-runs",
+		RunID:     "hide",
+		ImageRef:  "ghcr.io/acme/tool@" + testDigest,
+		Args:      []string{filepath.Join(home, ".netrc"), filepath.Join(extra, "db.json"), filepath.Join(other, "secrets"), "/bin/sh"},
+		Secrets:   []secrets.Secret{{Name: "api", Data: map[string][]byte{"token": []byte("s3cr3t")}}},
+	}
+	name, err := b.CreateRunPod(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.DeletePod(ctx, "mcp-runs", name)
+	phase := ""
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if phase, _, _ = b.GetPodStatus(ctx, "mcp-runs", name); phase != "Running" {
+			break
+		}
+	}
+	logs, _ := b.GetPodLogs(ctx, "mcp-runs", name)
+	if phase != "Succeeded" {
+		t.Fatalf("run %s: %s", phase, logs)
+	}
+	want := []string{
+		"hidden " + filepath.Join(home, ".netrc"),
+		"hidden " + filepath.Join(extra, "db.json"),
+		"hidden " + filepath.Join(other, "secrets"),
+		"visible /bin/sh",
+		"s3cr3t",
+	}
+	if got := strings.Split(strings.TrimSpace(logs), "\n"); !slices.Equal(got, want) {
+		t.Errorf("sandbox saw:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestHiddenDirs(t *testing.T) {
+	t.Setenv("HOME", "/home/dev")
+	b := &Backend{opts: Options{StateDir: "/home/dev/.cache/mcp-runner", HiddenDirs: []string{"", "/srv/secrets", "/srv/secrets-old"}}}
+	want := []string{"/home/dev", "/srv/secrets", "/srv/secrets-old"}
+	if got := b.hiddenDirs(); !slices.Equal(got, want) {
+		t.Errorf("hiddenDirs = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// The sandbox re-executes the runner binary: InitArg runs as PID 1 inside the
+// new namespaces (mounts, loopback, socket proxy), execArg drops privileges,
+// installs the seccomp filter and execs the server.
+const (
+	InitArg = "sandbox-init"
+	execArg = "sandbox-exec"
+)
+
+type sandboxSpec struct {
+	RunID      string   `json:"run_id"`
+	Rootfs     string   `json:"rootfs,omitempty"`
+	Argv       []string `json:"argv"`
+	Env        []string `json:"env"`
+	WorkingDir string   `json:"working_dir,omitempty"`
+	Socket     string   `json:"socket"`
+	Port       int      `json:"port"`
+	SecretsDir string   `json:"secrets_dir,omitempty"`
+	TmpfsBytes int64    `json:"tmpfs_bytes"`
+	// Hidden are host directories covered with an empty tmpfs when there is
+	// no rootfs; the run's own state dir is mounted back inside.
+	Hidden []string `json:"hidden,omitempty"`
+}
+
+// binaryTmpDir is the scratch directory of a run without a rootfs.
+func binaryTmpDir(spec sandboxSpec) string {
+	return filepath.Join(filepath.Dir(spec.Socket), "tmp")
+}
+
+type process interface {
+	// wait blocks until the sandbox exits and reports its exit code and
+	// whether the cgroup recorded an OOM kill.
+	wait() (code int, oom bool, err error)
+}
+
+// MaybeRunSandbox must be the first call in main. When the process was
+// started as a sandbox stage it runs that stage and never returns.
+func MaybeRunSandbox() {
+	if len(os.Args) < 2 {
+		return
+	}
+	switch os.Args[1] {
+	case InitArg:
+		runInit(os.Args[2:])
+	case execArg:
+		runExec(os.Args[2:])
+	}
+}
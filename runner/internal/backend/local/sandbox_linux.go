@@ -0,0 +1,497 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	cloneFlags = unix.CLONE_NEWUSER | unix.CLONE_NEWNS | unix.CLONE_NEWPID | unix.CLONE_NEWNET | unix.CLONE_NEWIPC | unix.CLONE_NEWUTS
+	// supervisorCgroup holds the runner itself so the delegated cgroup can
+	// enable controllers for run cgroups (cgroup v2 "no internal processes").
+	supervisorCgroup = "supervisor"
+)
+
+var sandboxDevices = []string{"null", "zero", "full", "random", "urandom", "tty"}
+
+func checkSupport(opts Options) error {
+	if _, err := os.Stat("/proc/self/ns/user"); err != nil {
+		return errors.New("kernel lacks user namespaces")
+	}
+	if b, err := os.ReadFile("/proc/sys/kernel/unprivileged_userns_clone"); err == nil && strings.TrimSpace(string(b)) == "0" && os.Getuid() != 0 {
+		return errors.New("unprivileged user namespaces are disabled (kernel.unprivileged_userns_clone=0)")
+	}
+	if auditArch == 0 {
+		return fmt.Errorf("no seccomp profile for %s", runtime.GOARCH)
+	}
+	if opts.Cgroup != "" {
+		return prepareCgroup(opts.Cgroup)
+	}
+	return nil
+}
+
+// prepareCgroup moves any processes in the delegated cgroup (typically the
+// runner's own scope) into a leaf and enables the cpu, memory and pids
+// controllers for run cgroups.
+func prepareCgroup(dir string) error {
+	procs, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return fmt.Errorf("cgroup %s: %w", dir, err)
+	}
+	if pids := strings.Fields(string(procs)); len(pids) > 0 {
+		leaf := filepath.Join(dir, supervisorCgroup)
+		if err := os.MkdirAll(leaf, 0o755); err != nil {
+			return fmt.Errorf("cgroup %s: %w", dir, err)
+		}
+		for _, pid := range pids {
+			// Processes may exit in between; only the runner must move.
+			_ = os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(pid), 0o644)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+cpu +memory +pids"), 0o644); err != nil {
+		return fmt.Errorf("cgroup %s: enable controllers: %w", dir, err)
+	}
+	return nil
+}
+
+type sandboxProcess struct {
+	cmd    *exec.Cmd
+	cgroup string
+}
+
+func startSandbox(ctx context.Context, opts Options, spec sandboxSpec, l limits, out *os.File) (process, error) {
+	specPath := filepath.Join(filepath.Dir(spec.Socket), "spec.json")
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(specPath, raw, 0o600); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, "/proc/self/exe", InitArg, specPath)
+	cmd.Stdout, cmd.Stderr = out, out
+	cmd.Env = []string{}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  cloneFlags,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		Pdeathsig:   syscall.SIGKILL,
+	}
+
+	p := &sandboxProcess{cmd: cmd}
+	if opts.Cgroup != "" {
+		p.cgroup = filepath.Join(opts.Cgroup, "run-"+spec.RunID)
+		fd, err := createRunCgroup(p.cgroup, l)
+		if err != nil {
+			return nil, err
+		}
+		defer unix.Close(fd)
+		cmd.SysProcAttr.UseCgroupFD, cmd.SysProcAttr.CgroupFD = true, fd
+	}
+	if err := cmd.Start(); err != nil {
+		if p.cgroup != "" {
+			_ = os.Remove(p.cgroup)
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+func createRunCgroup(dir string, l limits) (int, error) {
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return -1, fmt.Errorf("create cgroup: %w", err)
+	}
+	cpu := "max"
+	if l.cpuQuotaMicros > 0 {
+		cpu = strconv.FormatInt(l.cpuQuotaMicros, 10)
+	}
+	settings := map[string]string{
+		"cpu.max":  fmt.Sprintf("%s %d", cpu, cpuPeriodMicros),
+		"pids.max": strconv.Itoa(maxPids),
+	}
+	if l.memoryBytes > 0 {
+		settings["memory.max"] = strconv.FormatInt(l.memoryBytes, 10)
+		settings["memory.swap.max"] = "0"
+	}
+	for file, v := range settings {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(v), 0o644); err != nil && !(file == "memory.swap.max" && errors.Is(err, os.ErrNotExist)) {
+			_ = os.Remove(dir)
+			return -1, fmt.Errorf("set %s: %w", file, err)
+		}
+	}
+	fd, err := unix.Open(dir, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		_ = os.Remove(dir)
+		return -1, err
+	}
+	return fd, nil
+}
+
+func (p *sandboxProcess) wait() (int, bool, error) {
+	err := p.cmd.Wait()
+	code := p.cmd.ProcessState.ExitCode()
+	oom := false
+	if p.cgroup != "" {
+		if events, rerr := os.ReadFile(filepath.Join(p.cgroup, "memory.events")); rerr == nil {
+			for _, line := range strings.Split(string(events), "\n") {
+				if f := strings.Fields(line); len(f) == 2 && f[0] == "oom_kill" && f[1] != "0" {
+					oom = true
+				}
+			}
+		}
+		_ = os.Remove(p.cgroup)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		err = nil
+	}
+	return code, oom, err
+}
+
+func fail(stage string, err error) {
+	fmt.Fprintf(os.Stderr, "sandbox: %s: %v\n", stage, err)
+	os.Exit(125)
+}
+
+// runInit is PID 1 of the sandbox. It finishes namespace setup, starts the
+// server through the exec stage, proxies the unix socket to the server's
+// port on loopback and reaps every process until the server exits.
+func runInit(args []string) {
+	runtime.LockOSThread()
+	if len(args) != 1 {
+		fail("init", errors.New("usage: sandbox-init <spec>"))
+	}
+	raw, err := os.ReadFile(args[0])
+	if err != nil {
+		fail("read spec", err)
+	}
+	var spec sandboxSpec
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		fail("parse spec", err)
+	}
+
+	hostname := "mcp-" + spec.RunID
+	if len(hostname) > 63 {
+		hostname = hostname[:63]
+	}
+	if err := unix.Sethostname([]byte(hostname)); err != nil {
+		fail("hostname", err)
+	}
+	if err := loopbackUp(); err != nil {
+		fail("loopback", err)
+	}
+	// The listener is bound on the host path before the root changes; the
+	// open socket keeps working afterwards.
+	ln, err := net.Listen("unix", spec.Socket)
+	if err != nil {
+		fail("listen", err)
+	}
+	_ = os.Chmod(spec.Socket, 0o600)
+	if err := setupMounts(spec); err != nil {
+		fail("mounts", err)
+	}
+
+	workDir := spec.WorkingDir
+	if workDir == "" {
+		workDir = "/"
+	}
+	// The exec stage pivots into the rootfs itself: the runner binary (and,
+	// when built with cgo, its loader) only exists on the host root.
+	child, err := os.StartProcess("/proc/self/exe", append([]string{"mcp-sandbox", execArg, spec.Rootfs, workDir, "--"}, spec.Argv...), &os.ProcAttr{
+		Dir:   "/",
+		Env:   spec.Env,
+		Files: []*os.File{nil, os.Stdout, os.Stderr},
+	})
+	if err != nil {
+		fail("start server", err)
+	}
+	go proxy(ln, spec.Port)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, unix.SIGTERM, unix.SIGINT)
+	go func() {
+		for s := range sigs {
+			_ = child.Signal(s)
+		}
+	}()
+	for {
+		var status unix.WaitStatus
+		pid, err := unix.Wait4(-1, &status, 0, nil)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			fail("wait", err)
+		}
+		if pid != child.Pid {
+			continue
+		}
+		if status.Signaled() {
+			os.Exit(128 + int(status.Signal()))
+		}
+		os.Exit(status.ExitStatus())
+	}
+}
+
+// runExec is the last step before the server: it pivots into the rootfs (if
+// any), empties the capability sets, sets no_new_privs, installs the seccomp
+// filter and execs argv.
+func runExec(args []string) {
+	runtime.LockOSThread()
+	if len(args) < 4 || args[2] != "--" {
+		fail("exec", errors.New("usage: sandbox-exec <rootfs> <workdir> -- <argv>"))
+	}
+	rootfs, workDir, argv := args[0], args[1], args[3:]
+	if rootfs != "" {
+		if err := enterRoot(rootfs); err != nil {
+			fail("rootfs", err)
+		}
+	}
+	if err := unix.Chdir(workDir); err != nil {
+		fail("workdir", err)
+	}
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		fail("exec", err)
+	}
+	if err := dropCapabilities(); err != nil {
+		fail("capabilities", err)
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		fail("no_new_privs", err)
+	}
+	if err := installSeccomp(); err != nil {
+		fail("seccomp", err)
+	}
+	if err := unix.Exec(path, argv, os.Environ()); err != nil {
+		fail("exec "+argv[0], err)
+	}
+}
+
+func loopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	ifr, err := unix.NewIfreq("lo")
+	if err != nil {
+		return err
+	}
+	if err := unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifr); err != nil {
+		return err
+	}
+	ifr.SetUint16(ifr.Uint16() | unix.IFF_UP)
+	return unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr)
+}
+
+// setupMounts gives the server a private /proc and /tmp. With a rootfs it
+// also adds minimal /dev and /run and mounts secrets read-only inside it;
+// enterRoot then switches to it. Without one, the host filesystem stays
+// visible read-only except for spec.Hidden.
+func setupMounts(spec sandboxSpec) error {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+	tmpfsOpts := fmt.Sprintf("size=%d,mode=1777", spec.TmpfsBytes)
+	if spec.Rootfs == "" {
+		// The host filesystem stays visible, read-only; the run's scratch
+		// space is a tmpfs in its state dir (TMPDIR) rather than one over
+		// /tmp, which could hide the image and state directories.
+		attr := unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY}
+		if err := unix.MountSetattr(-1, "/", unix.AT_RECURSIVE, &attr); err != nil {
+			return fmt.Errorf("make / read-only: %w", err)
+		}
+		if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+			return fmt.Errorf("mount /proc: %w", err)
+		}
+		if err := hideDirs(spec.Hidden, filepath.Dir(spec.Socket)); err != nil {
+			return err
+		}
+		if err := unix.Mount("tmpfs", binaryTmpDir(spec), "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, tmpfsOpts); err != nil {
+			return fmt.Errorf("mount TMPDIR: %w", err)
+		}
+		return nil
+	}
+
+	root := spec.Rootfs
+	if err := unix.Mount(root, root, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind rootfs: %w", err)
+	}
+	for _, d := range []string{"proc", "tmp", "dev", "run"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			return err
+		}
+	}
+	mounts := []struct {
+		source, target, fstype string
+		flags                  uintptr
+		data                   string
+	}{
+		{"proc", "proc", "proc", unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC, ""},
+		{"tmpfs", "tmp", "tmpfs", unix.MS_NOSUID | unix.MS_NODEV, tmpfsOpts},
+		{"tmpfs", "dev", "tmpfs", unix.MS_NOSUID | unix.MS_NOEXEC, "size=64k,mode=755"},
+		{"tmpfs", "run", "tmpfs", unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC, "size=1m,mode=755"},
+	}
+	for _, m := range mounts {
+		if err := unix.Mount(m.source, filepath.Join(root, m.target), m.fstype, m.flags, m.data); err != nil {
+			return fmt.Errorf("mount /%s: %w", m.target, err)
+		}
+	}
+	for _, dev := range sandboxDevices {
+		target := filepath.Join(root, "dev", dev)
+		if err := os.WriteFile(target, nil, 0o666); err != nil {
+			return err
+		}
+		if err := unix.Mount("/dev/"+dev, target, "", unix.MS_BIND, ""); err != nil {
+			return fmt.Errorf("bind /dev/%s: %w", dev, err)
+		}
+	}
+	if spec.SecretsDir != "" {
+		target := filepath.Join(root, "run", "secrets", "mcp")
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return err
+		}
+		if err := bindReadOnly(spec.SecretsDir, target); err != nil {
+			return fmt.Errorf("mount secrets: %w", err)
+		}
+	}
+	return nil
+}
+
+// hideDirs covers each of dirs with an empty read-only tmpfs, so a run
+// without a rootfs sees neither other runs' state nor the user's files. The
+// run's own dir is cloned first and moved back on top.
+func hideDirs(dirs []string, runDir string) error {
+	own, err := unix.OpenTree(unix.AT_FDCWD, runDir, unix.OPEN_TREE_CLONE|unix.OPEN_TREE_CLOEXEC|unix.AT_RECURSIVE)
+	if err != nil {
+		return fmt.Errorf("clone run dir: %w", err)
+	}
+	defer unix.Close(own)
+	var hidden []string
+	for _, d := range dirs {
+		if _, err := os.Stat(d); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := unix.Mount("tmpfs", d, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "size=64k,mode=755"); err != nil {
+			return fmt.Errorf("hide %s: %w", d, err)
+		}
+		hidden = append(hidden, d)
+	}
+	if err := os.MkdirAll(runDir, 0o700); err != nil {
+		return err
+	}
+	if err := unix.MoveMount(own, "", unix.AT_FDCWD, runDir, unix.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+		return fmt.Errorf("restore run dir: %w", err)
+	}
+	for _, d := range hidden {
+		if err := remountReadOnly(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enterRoot pivots the mount namespace into root and makes it read-only,
+// mirroring the pod's readOnlyRootFilesystem.
+func enterRoot(root string) error {
+	if err := unix.Chdir(root); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("pivot_root: %w", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach old root: %w", err)
+	}
+	if err := unix.Chdir("/"); err != nil {
+		return err
+	}
+	return remountReadOnly("/")
+}
+
+func bindReadOnly(source, target string) error {
+	if err := unix.Mount(source, target, "", unix.MS_BIND, ""); err != nil {
+		return err
+	}
+	return remountReadOnly(target)
+}
+
+// remountReadOnly keeps the flags the kernel locks on mounts inherited by a
+// user namespace; dropping any of them makes the remount fail with EPERM.
+func remountReadOnly(target string) error {
+	var st unix.Statfs_t
+	if err := unix.Statfs(target, &st); err != nil {
+		return err
+	}
+	locked := uintptr(st.Flags) & (unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC | unix.MS_NOATIME | unix.MS_NODIRATIME | unix.MS_RELATIME)
+	if err := unix.Mount("", target, "", unix.MS_REMOUNT|unix.MS_BIND|unix.MS_RDONLY|locked, ""); err != nil {
+		return fmt.Errorf("remount %s read-only: %w", target, err)
+	}
+	return nil
+}
+
+func proxy(ln net.Listener, port int) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			upstream, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+			if err != nil {
+				return
+			}
+			defer upstream.Close()
+			go func() {
+				_, _ = io.Copy(upstream, conn)
+				if c, ok := upstream.(*net.TCPConn); ok {
+					_ = c.CloseWrite()
+				}
+			}()
+			_, _ = io.Copy(conn, upstream)
+		}()
+	}
+}
+
+// dropCapabilities empties the bounding, ambient and inheritable sets, so
+// the server starts with no capabilities even as uid 0 in the namespace.
+func dropCapabilities() error {
+	last := 40
+	if b, err := os.ReadFile("/proc/sys/kernel/cap_last_cap"); err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil {
+			last = n
+		}
+	}
+	for c := 0; c <= last; c++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil && !errors.Is(err, unix.EINVAL) {
+			return fmt.Errorf("drop bounding cap %d: %w", c, err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil && !errors.Is(err, unix.EINVAL) {
+		return err
+	}
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&hdr, &data[0]); err != nil {
+		return err
+	}
+	data[0].Inheritable, data[1].Inheritable = 0, 0
+	return unix.Capset(&hdr, &data[0])
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var errUnsupported = errors.New("the local sandbox requires Linux")
+
+func checkSupport(Options) error { return errUnsupported }
+
+func startSandbox(context.Context, Options, sandboxSpec, limits, *os.File) (process, error) {
+	return nil, errUnsupported
+}
+
+func runInit([]string) {
+	fmt.Fprintln(os.Stderr, errUnsupported)
+	os.Exit(125)
+}
+
+func runExec([]string) {
+	fmt.Fprintln(os.Stderr, errUnsupported)
+	os.Exit(125)
+}
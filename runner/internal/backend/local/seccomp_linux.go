@@ -0,0 +1,83 @@
+package local
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// deniedSyscalls fail with EPERM: namespace and mount manipulation, kernel
+// module, keyring, BPF and tracing interfaces a tool server never needs.
+var deniedSyscalls = []uintptr{
+	unix.SYS_ACCT, unix.SYS_ADD_KEY, unix.SYS_BPF, unix.SYS_DELETE_MODULE,
+	unix.SYS_FANOTIFY_INIT, unix.SYS_FINIT_MODULE, unix.SYS_FSCONFIG, unix.SYS_FSMOUNT,
+	unix.SYS_FSOPEN, unix.SYS_FSPICK, unix.SYS_INIT_MODULE, unix.SYS_KEXEC_FILE_LOAD,
+	unix.SYS_KEXEC_LOAD, unix.SYS_KEYCTL, unix.SYS_LOOKUP_DCOOKIE, unix.SYS_MOUNT,
+	unix.SYS_MOVE_MOUNT, unix.SYS_NAME_TO_HANDLE_AT, unix.SYS_OPEN_BY_HANDLE_AT,
+	unix.SYS_OPEN_TREE, unix.SYS_PERF_EVENT_OPEN, unix.SYS_PIVOT_ROOT,
+	unix.SYS_PROCESS_VM_READV, unix.SYS_PROCESS_VM_WRITEV, unix.SYS_PTRACE,
+	unix.SYS_REBOOT, unix.SYS_REQUEST_KEY, unix.SYS_SETHOSTNAME, unix.SYS_SETNS,
+	unix.SYS_SWAPOFF, unix.SYS_SWAPON, unix.SYS_SYSLOG, unix.SYS_UMOUNT2,
+	unix.SYS_UNSHARE, unix.SYS_USERFAULTFD,
+}
+
+const (
+	nsCloneFlags = unix.CLONE_NEWNS | unix.CLONE_NEWUTS | unix.CLONE_NEWIPC | unix.CLONE_NEWUSER |
+		unix.CLONE_NEWPID | unix.CLONE_NEWNET | unix.CLONE_NEWCGROUP
+
+	// Offsets into struct seccomp_data.
+	offsetNr   = 0
+	offsetArch = 4
+	offsetArg0 = 16
+	retAllow   = unix.SECCOMP_RET_ALLOW
+	retKill    = unix.SECCOMP_RET_KILL_PROCESS
+	retEPERM   = unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)
+	retENOSYS  = unix.SECCOMP_RET_ERRNO | uint32(unix.ENOSYS)
+	bpfLoad    = unix.BPF_LD | unix.BPF_W | unix.BPF_ABS
+	bpfJumpEq  = unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K
+	bpfJumpGe  = unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K
+	bpfJumpSet = unix.BPF_JMP | unix.BPF_JSET | unix.BPF_K
+	bpfReturn  = unix.BPF_RET | unix.BPF_K
+)
+
+// seccompProgram builds a denylist filter. Foreign architectures are killed;
+// clone3 reports ENOSYS so libc falls back to clone, whose flags are checked
+// for namespace creation.
+func seccompProgram() []unix.SockFilter {
+	stmt := func(code uint16, k uint32) unix.SockFilter { return unix.SockFilter{Code: code, K: k} }
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k, Jt: jt, Jf: jf}
+	}
+	prog := []unix.SockFilter{
+		stmt(bpfLoad, offsetArch),
+		jump(bpfJumpEq, auditArch, 1, 0),
+		stmt(bpfReturn, retKill),
+		stmt(bpfLoad, offsetNr),
+	}
+	if syscallFloor != 0 {
+		prog = append(prog, jump(bpfJumpGe, syscallFloor, 0, 1), stmt(bpfReturn, retEPERM))
+	}
+	for _, nr := range append(deniedSyscalls, archDeniedSyscalls...) {
+		prog = append(prog, jump(bpfJumpEq, uint32(nr), 0, 1), stmt(bpfReturn, retEPERM))
+	}
+	prog = append(prog,
+		jump(bpfJumpEq, uint32(unix.SYS_CLONE3), 0, 1),
+		stmt(bpfReturn, retENOSYS),
+		jump(bpfJumpEq, uint32(unix.SYS_CLONE), 0, 3),
+		stmt(bpfLoad, offsetArg0),
+		jump(bpfJumpSet, nsCloneFlags, 0, 1),
+		stmt(bpfReturn, retEPERM),
+		stmt(bpfReturn, retAllow),
+	)
+	return prog
+}
+
+func installSeccomp() error {
+	if auditArch == 0 {
+		return fmt.Errorf("no seccomp profile for this architecture")
+	}
+	filter := seccompProgram()
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	return unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&prog)), 0, 0)
+}
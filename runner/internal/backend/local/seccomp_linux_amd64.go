@@ -0,0 +1,11 @@
+package local
+
+import "golang.org/x/sys/unix"
+
+const (
+	auditArch = unix.AUDIT_ARCH_X86_64
+	// syscallFloor rejects the x32 ABI, which would bypass the numbers below.
+	syscallFloor = 0x40000000
+)
+
+var archDeniedSyscalls = []uintptr{unix.SYS_IOPL, unix.SYS_IOPERM}
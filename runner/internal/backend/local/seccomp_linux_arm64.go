@@ -0,0 +1,10 @@
+package local
+
+import "golang.org/x/sys/unix"
+
+const (
+	auditArch    = unix.AUDIT_ARCH_AARCH64
+	syscallFloor = 0
+)
+
+var archDeniedSyscalls = []uintptr{}
@@ -0,0 +1,11 @@
+//go:build linux && !amd64 && !arm64
+
+package local
+
+// No profile: checkSupport refuses to start the local backend.
+const (
+	auditArch    = 0
+	syscallFloor = 0
+)
+
+var archDeniedSyscalls = []uintptr{}
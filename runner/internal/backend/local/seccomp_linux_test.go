@@ -0,0 +1,82 @@
+package local
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// runFilter interprets the classic BPF subset seccompProgram emits against
+// one struct seccomp_data.
+func runFilter(t *testing.T, prog []unix.SockFilter, arch, nr uint32, arg0 uint64) uint32 {
+	t.Helper()
+	var acc uint32
+	for pc := 0; pc < len(prog); pc++ {
+		ins := prog[pc]
+		switch ins.Code {
+		case bpfLoad:
+			switch ins.K {
+			case offsetNr:
+				acc = nr
+			case offsetArch:
+				acc = arch
+			case offsetArg0:
+				acc = uint32(arg0)
+			default:
+				t.Fatalf("pc %d: load from offset %d", pc, ins.K)
+			}
+		case bpfJumpEq, bpfJumpGe, bpfJumpSet:
+			taken := (ins.Code == bpfJumpEq && acc == ins.K) ||
+				(ins.Code == bpfJumpGe && acc >= ins.K) ||
+				(ins.Code == bpfJumpSet && acc&ins.K != 0)
+			if taken {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case bpfReturn:
+			return ins.K
+		default:
+			t.Fatalf("pc %d: unexpected opcode %#x", pc, ins.Code)
+		}
+	}
+	t.Fatal("filter fell off the end")
+	return 0
+}
+
+func TestSeccompProgram(t *testing.T) {
+	if auditArch == 0 {
+		t.Skip("no seccomp profile for this architecture")
+	}
+	prog := seccompProgram()
+	for label, c := range map[string]struct {
+		arch, nr uint32
+		arg0     uint64
+		want     uint32
+	}{
+		"read":          {auditArch, unix.SYS_READ, 0, retAllow},
+		"mount":         {auditArch, unix.SYS_MOUNT, 0, retEPERM},
+		"ptrace":        {auditArch, unix.SYS_PTRACE, 0, retEPERM},
+		"unshare":       {auditArch, unix.SYS_UNSHARE, 0, retEPERM},
+		"open_tree":     {auditArch, unix.SYS_OPEN_TREE, 0, retEPERM},
+		"clone3":        {auditArch, unix.SYS_CLONE3, 0, retENOSYS},
+		"clone thread":  {auditArch, unix.SYS_CLONE, unix.CLONE_VM | unix.CLONE_THREAD, retAllow},
+		"clone user ns": {auditArch, unix.SYS_CLONE, unix.CLONE_NEWUSER, retEPERM},
+		"clone net ns":  {auditArch, unix.SYS_CLONE, unix.CLONE_NEWNET | uint64(unix.SIGCHLD), retEPERM},
+		"foreign arch":  {auditArch + 1, unix.SYS_READ, 0, retKill},
+	} {
+		if got := runFilter(t, prog, c.arch, c.nr, c.arg0); got != c.want {
+			t.Errorf("%s: returned %#x, want %#x", label, got, c.want)
+		}
+	}
+	for _, nr := range append(deniedSyscalls, archDeniedSyscalls...) {
+		if got := runFilter(t, prog, auditArch, uint32(nr), 0); got != retEPERM {
+			t.Errorf("syscall %d: returned %#x, want EPERM", nr, got)
+		}
+	}
+	if syscallFloor != 0 {
+		if got := runFilter(t, prog, auditArch, syscallFloor|unix.SYS_READ, 0); got != retEPERM {
+			t.Errorf("syscall above the floor: returned %#x, want EPERM", got)
+		}
+	}
+}
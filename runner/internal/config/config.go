@@ -1,12 +1,34 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
+// NetworkProfiles are the egress profiles backed by NetworkPolicies shipped in
+// infra/k8s/networkpolicies. RUNNER_NETWORK_PROFILES may narrow, not extend, them.
+var NetworkProfiles = []string{"deny-all", "dns-only"}
+
+// QuarantineTriggers are the events RUNNER_QUARANTINE_TRIGGERS may name to
+// quarantine a run automatically.
+var QuarantineTriggers = []string{"tool_scope_violation", "pod_failed"}
+
 type Config struct {
-	Addr             string
+	Addr string
+	// TLSCertFile and TLSKeyFile serve the API over TLS. With TLSClientCAFile
+	// every client must present a certificate that CA issued; its identity is
+	// the caller's mtls subject.
+	TLSCertFile      string
+	TLSKeyFile       string
+	TLSClientCAFile  string
+	Backend          string
 	Namespace        string
 	RuntimeClassName string
 	ImagePullPolicy  string
@@ -14,37 +36,483 @@ type Config struct {
 	DefaultMemory    string
 	DefaultTimeout   int64
 	CleanupSeconds   int64
+	NetworkProfiles  []string
+
+	JobBackoffLimit int64
+	JobTTLSeconds   int64
+
+	ReadinessProbe         bool
+	ReadyTimeoutSeconds    int64
+	MaxReadyTimeoutSeconds int64
+	MCPHandshakePath       string
+
+	DefaultIdleTimeoutSeconds int64
+	MaxIdleTimeoutSeconds     int64
+	IdleReapSeconds           int64
+
+	GCIntervalSeconds int64
+	GCOrphanSeconds   int64
+	// GCPodRetention and GCRecordRetention map a terminal run status to how
+	// long its workload, and later its run record, are kept after it ends.
+	GCPodRetention    map[string]int64
+	GCRecordRetention map[string]int64
+
+	// QuarantineDir holds evidence bundles; empty keeps them in memory only.
+	QuarantineDir      string
+	QuarantineTriggers []string
+
+	// SelfCheckMode is off, warn (report failures) or strict (refuse to
+	// start, and to create runs, while a cluster prerequisite is missing).
+	SelfCheckMode            string
+	SelfCheckIntervalSeconds int64
+	// SelfCheckPodSecurity is the least restrictive enforce level the runs
+	// namespace may carry.
+	SelfCheckPodSecurity string
+
+	WarmPoolSize        int64
+	WarmPoolIdleSeconds int64
+	WarmPoolMaxImages   int64
+
+	DefaultEphemeralStorage string
+	MinCPU                  resource.Quantity
+	MaxCPU                  resource.Quantity
+	MinMemory               resource.Quantity
+	MaxMemory               resource.Quantity
+	MaxEphemeralStorage     resource.Quantity
+	MinTimeout              int64
+	MaxTimeout              int64
+
+	EnvMaxVars       int64
+	EnvMaxValueBytes int64
+	EnvMaxTotalBytes int64
+	EnvDenylist      []string
+	EnvSecretScan    bool
+
+	SecretsBackend   string
+	SecretsNamespace string
+	SecretsDir       string
+	SecretTTLSeconds int64
+	MaxSecretsPerRun int64
+
+	LocalImageDir string
+	LocalStateDir string
+	LocalCgroup   string
+
+	AdminToken             string
+	ExceptionsFile         string
+	MaxExceptionTTLSeconds int64
+}
+
+func FromEnv() (Config, error) {
+	return From(os.Getenv)
 }
 
-func FromEnv() Config {
+// From builds the runner config from RUNNER_* keys resolved by lookup.
+func From(lookup func(string) string) (Config, error) {
+	defaultTimeout, err := getInt64(lookup, "RUNNER_DEFAULT_TIMEOUT_SECONDS", 300, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	minTimeout, err := getInt64(lookup, "RUNNER_MIN_TIMEOUT_SECONDS", 1, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	maxTimeout, err := getInt64(lookup, "RUNNER_MAX_TIMEOUT_SECONDS", 3600, minTimeout)
+	if err != nil {
+		return Config{}, err
+	}
+	if defaultTimeout < minTimeout || defaultTimeout > maxTimeout {
+		return Config{}, fmt.Errorf("RUNNER_DEFAULT_TIMEOUT_SECONDS must be within %d-%d", minTimeout, maxTimeout)
+	}
+	cleanupSeconds, err := getInt64(lookup, "RUNNER_CLEANUP_SECONDS", 120, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	jobBackoffLimit, err := getInt64(lookup, "RUNNER_JOB_BACKOFF_LIMIT", 2, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	jobTTL, err := getInt64(lookup, "RUNNER_JOB_TTL_SECONDS", 300, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	readinessProbe, err := getBool(lookup, "RUNNER_READINESS_PROBE", true)
+	if err != nil {
+		return Config{}, err
+	}
+	maxReadyTimeout, err := getInt64(lookup, "RUNNER_MAX_READY_TIMEOUT_SECONDS", 300, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	readyTimeout, err := getInt64(lookup, "RUNNER_READY_TIMEOUT_SECONDS", 60, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	if readyTimeout > maxReadyTimeout {
+		return Config{}, fmt.Errorf("RUNNER_READY_TIMEOUT_SECONDS must be <= RUNNER_MAX_READY_TIMEOUT_SECONDS")
+	}
+	handshakePath := getEnv(lookup, "RUNNER_MCP_HANDSHAKE_PATH", "/mcp")
+	if !strings.HasPrefix(handshakePath, "/") {
+		return Config{}, fmt.Errorf("RUNNER_MCP_HANDSHAKE_PATH must start with /")
+	}
+	maxIdleTimeout, err := getInt64(lookup, "RUNNER_MAX_IDLE_TIMEOUT_SECONDS", 3600, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	defaultIdleTimeout, err := getInt64(lookup, "RUNNER_DEFAULT_IDLE_TIMEOUT_SECONDS", 0, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	if defaultIdleTimeout > maxIdleTimeout {
+		return Config{}, fmt.Errorf("RUNNER_DEFAULT_IDLE_TIMEOUT_SECONDS must be <= RUNNER_MAX_IDLE_TIMEOUT_SECONDS")
+	}
+	idleReap, err := getInt64(lookup, "RUNNER_IDLE_REAP_SECONDS", 15, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	gcInterval, err := getInt64(lookup, "RUNNER_GC_INTERVAL_SECONDS", 30, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	gcOrphan, err := getInt64(lookup, "RUNNER_GC_ORPHAN_SECONDS", 300, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	podRetention, err := getRetention(lookup, "RUNNER_GC_POD_RETENTION", map[string]int64{
+		"succeeded": cleanupSeconds, "failed": cleanupSeconds, "stopped": 0, "idle_expired": 0,
+	})
+	if err != nil {
+		return Config{}, err
+	}
+	recordRetention, err := getRetention(lookup, "RUNNER_GC_RECORD_RETENTION", map[string]int64{
+		"succeeded": 3600, "failed": 86400, "stopped": 3600, "idle_expired": 3600, "not_found": 3600, "quarantined": 604800,
+	})
+	if err != nil {
+		return Config{}, err
+	}
+	for status, keep := range podRetention {
+		if recordRetention[status] < keep {
+			return Config{}, fmt.Errorf("RUNNER_GC_RECORD_RETENTION for %s must be >= its RUNNER_GC_POD_RETENTION", status)
+		}
+	}
+	// Automatic quarantine is opt-in; "none" also turns it off.
+	quarantineTriggers := splitList(lookup("RUNNER_QUARANTINE_TRIGGERS"))
+	if slices.Equal(quarantineTriggers, []string{"none"}) {
+		quarantineTriggers = nil
+	}
+	for _, t := range quarantineTriggers {
+		if !slices.Contains(QuarantineTriggers, t) {
+			return Config{}, fmt.Errorf("RUNNER_QUARANTINE_TRIGGERS: unknown trigger %q (known: %s)", t, strings.Join(QuarantineTriggers, ", "))
+		}
+	}
+	tlsCert, tlsKey, tlsClientCA := lookup("RUNNER_TLS_CERT_FILE"), lookup("RUNNER_TLS_KEY_FILE"), lookup("RUNNER_TLS_CLIENT_CA_FILE")
+	if (tlsCert == "") != (tlsKey == "") {
+		return Config{}, fmt.Errorf("RUNNER_TLS_CERT_FILE and RUNNER_TLS_KEY_FILE must be set together")
+	}
+	if tlsClientCA != "" && tlsCert == "" {
+		return Config{}, fmt.Errorf("RUNNER_TLS_CLIENT_CA_FILE requires RUNNER_TLS_CERT_FILE and RUNNER_TLS_KEY_FILE")
+	}
+	selfCheckMode := getEnv(lookup, "RUNNER_SELFCHECK_MODE", "warn")
+	if !slices.Contains([]string{"off", "warn", "strict"}, selfCheckMode) {
+		return Config{}, fmt.Errorf("RUNNER_SELFCHECK_MODE must be off, warn or strict")
+	}
+	selfCheckInterval, err := getInt64(lookup, "RUNNER_SELFCHECK_INTERVAL_SECONDS", 300, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	podSecurity := getEnv(lookup, "RUNNER_SELFCHECK_POD_SECURITY", "restricted")
+	if !slices.Contains([]string{"baseline", "restricted"}, podSecurity) {
+		return Config{}, fmt.Errorf("RUNNER_SELFCHECK_POD_SECURITY must be baseline or restricted")
+	}
+	warmPoolSize, err := getInt64(lookup, "RUNNER_WARM_POOL_SIZE", 0, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	warmPoolIdle, err := getInt64(lookup, "RUNNER_WARM_POOL_IDLE_SECONDS", 600, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	warmPoolMaxImages, err := getInt64(lookup, "RUNNER_WARM_POOL_MAX_IMAGES", 4, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	maxExceptionTTL, err := getInt64(lookup, "RUNNER_MAX_EXCEPTION_TTL_SECONDS", 86400, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	q := quantities{lookup: lookup}
+	defaultCPU := q.get("RUNNER_DEFAULT_CPU", "100m")
+	defaultMemory := q.get("RUNNER_DEFAULT_MEMORY", "128Mi")
+	defaultEphemeral := q.get("RUNNER_DEFAULT_EPHEMERAL_STORAGE", "")
+	minCPU, maxCPU := q.get("RUNNER_MIN_CPU", "50m"), q.get("RUNNER_MAX_CPU", "8")
+	minMemory, maxMemory := q.get("RUNNER_MIN_MEMORY", "64Mi"), q.get("RUNNER_MAX_MEMORY", "16Gi")
+	maxEphemeral := q.get("RUNNER_MAX_EPHEMERAL_STORAGE", "1Gi")
+	q.within("RUNNER_DEFAULT_CPU", defaultCPU, minCPU, maxCPU)
+	q.within("RUNNER_DEFAULT_MEMORY", defaultMemory, minMemory, maxMemory)
+	if !maxEphemeral.IsZero() {
+		q.within("RUNNER_DEFAULT_EPHEMERAL_STORAGE", defaultEphemeral, resource.Quantity{}, maxEphemeral)
+	}
+	if q.err != nil {
+		return Config{}, q.err
+	}
+
+	envMaxVars, err := getInt64(lookup, "RUNNER_ENV_MAX_VARS", 32, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	envMaxValueBytes, err := getInt64(lookup, "RUNNER_ENV_MAX_VALUE_BYTES", 4096, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	envMaxTotalBytes, err := getInt64(lookup, "RUNNER_ENV_MAX_TOTAL_BYTES", 32768, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	envSecretScan, err := getBool(lookup, "RUNNER_ENV_SECRET_SCAN", true)
+	if err != nil {
+		return Config{}, err
+	}
+
+	secretsBackend := getEnv(lookup, "RUNNER_SECRETS_BACKEND", "none")
+	if !slices.Contains([]string{"none", "kubernetes", "file"}, secretsBackend) {
+		return Config{}, fmt.Errorf("RUNNER_SECRETS_BACKEND must be none, kubernetes or file")
+	}
+	secretsDir := lookup("RUNNER_SECRETS_DIR")
+	if secretsBackend == "file" && secretsDir == "" {
+		return Config{}, fmt.Errorf("RUNNER_SECRETS_DIR is required when RUNNER_SECRETS_BACKEND=file")
+	}
+	secretTTL, err := getInt64(lookup, "RUNNER_SECRET_TTL_SECONDS", 0, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	maxSecrets, err := getInt64(lookup, "RUNNER_MAX_SECRETS_PER_RUN", 8, 0)
+	if err != nil {
+		return Config{}, err
+	}
+
+	backend := getEnv(lookup, "RUNNER_BACKEND", "kubernetes")
+	if !slices.Contains([]string{"kubernetes", "local"}, backend) {
+		return Config{}, fmt.Errorf("RUNNER_BACKEND must be kubernetes or local")
+	}
+	localImageDir := lookup("RUNNER_LOCAL_IMAGE_DIR")
+	if backend == "local" && localImageDir == "" {
+		return Config{}, fmt.Errorf("RUNNER_LOCAL_IMAGE_DIR is required when RUNNER_BACKEND=local")
+	}
+	if backend == "local" && secretsBackend == "kubernetes" {
+		return Config{}, fmt.Errorf("RUNNER_SECRETS_BACKEND=kubernetes needs RUNNER_BACKEND=kubernetes")
+	}
+
+	pullPolicy := getEnv(lookup, "RUNNER_IMAGE_PULL_POLICY", "IfNotPresent")
+	if !slices.Contains([]string{"Always", "IfNotPresent", "Never"}, pullPolicy) {
+		return Config{}, fmt.Errorf("RUNNER_IMAGE_PULL_POLICY must be Always, IfNotPresent or Never")
+	}
+	profiles := splitList(lookup("RUNNER_NETWORK_PROFILES"))
+	if len(profiles) == 0 {
+		profiles = NetworkProfiles
+	}
+	for _, p := range profiles {
+		if !slices.Contains(NetworkProfiles, p) {
+			return Config{}, fmt.Errorf("RUNNER_NETWORK_PROFILES: unknown profile %q (known: %s)", p, strings.Join(NetworkProfiles, ", "))
+		}
+	}
+
 	return Config{
-		Addr:             getEnv("RUNNER_ADDR", ":8080"),
-		Namespace:        getEnv("RUNNER_NAMESPACE", "mcp-runs"),
-		RuntimeClassName: getEnv("RUNNER_RUNTIMECLASS", "gvisor"),
-		ImagePullPolicy:  getEnv("RUNNER_IMAGE_PULL_POLICY", "IfNotPresent"),
-		DefaultCPU:       getEnv("RUNNER_DEFAULT_CPU", "100m"),
-		DefaultMemory:    getEnv("RUNNER_DEFAULT_MEMORY", "128Mi"),
-		DefaultTimeout:   getEnvInt64("RUNNER_DEFAULT_TIMEOUT_SECONDS", 300),
-		CleanupSeconds:   getEnvInt64("RUNNER_CLEANUP_SECONDS", 120),
+		Addr:             getEnv(lookup, "RUNNER_ADDR", ":8080"),
+		TLSCertFile:      tlsCert,
+		TLSKeyFile:       tlsKey,
+		TLSClientCAFile:  tlsClientCA,
+		Backend:          backend,
+		Namespace:        getEnv(lookup, "RUNNER_NAMESPACE", "mcp-runs"),
+		RuntimeClassName: getEnv(lookup, "RUNNER_RUNTIMECLASS", "gvisor"),
+		ImagePullPolicy:  pullPolicy,
+		DefaultCPU:       defaultCPU.String(),
+		DefaultMemory:    defaultMemory.String(),
+		DefaultTimeout:   defaultTimeout,
+		CleanupSeconds:   cleanupSeconds,
+		NetworkProfiles:  profiles,
+
+		JobBackoffLimit: jobBackoffLimit,
+		JobTTLSeconds:   jobTTL,
+
+		ReadinessProbe:         readinessProbe,
+		ReadyTimeoutSeconds:    readyTimeout,
+		MaxReadyTimeoutSeconds: maxReadyTimeout,
+		MCPHandshakePath:       handshakePath,
+
+		DefaultIdleTimeoutSeconds: defaultIdleTimeout,
+		MaxIdleTimeoutSeconds:     maxIdleTimeout,
+		IdleReapSeconds:           idleReap,
+
+		GCIntervalSeconds: gcInterval,
+		GCOrphanSeconds:   gcOrphan,
+		GCPodRetention:    podRetention,
+		GCRecordRetention: recordRetention,
+
+		QuarantineDir:      lookup("RUNNER_QUARANTINE_DIR"),
+		QuarantineTriggers: quarantineTriggers,
+
+		SelfCheckMode:            selfCheckMode,
+		SelfCheckIntervalSeconds: selfCheckInterval,
+		SelfCheckPodSecurity:     podSecurity,
+
+		WarmPoolSize:        warmPoolSize,
+		WarmPoolIdleSeconds: warmPoolIdle,
+		WarmPoolMaxImages:   warmPoolMaxImages,
+
+		DefaultEphemeralStorage: quantityString(defaultEphemeral),
+		MinCPU:                  minCPU,
+		MaxCPU:                  maxCPU,
+		MinMemory:               minMemory,
+		MaxMemory:               maxMemory,
+		MaxEphemeralStorage:     maxEphemeral,
+		MinTimeout:              minTimeout,
+		MaxTimeout:              maxTimeout,
+
+		EnvMaxVars:       envMaxVars,
+		EnvMaxValueBytes: envMaxValueBytes,
+		EnvMaxTotalBytes: envMaxTotalBytes,
+		EnvDenylist:      splitList(lookup("RUNNER_ENV_DENYLIST")),
+		EnvSecretScan:    envSecretScan,
+
+		SecretsBackend:   secretsBackend,
+		SecretsNamespace: getEnv(lookup, "RUNNER_SECRETS_NAMESPACE", "mcp-secrets"),
+		SecretsDir:       secretsDir,
+		SecretTTLSeconds: secretTTL,
+		MaxSecretsPerRun: maxSecrets,
+
+		LocalImageDir: localImageDir,
+		LocalStateDir: lookup("RUNNER_LOCAL_STATE_DIR"),
+		LocalCgroup:   lookup("RUNNER_LOCAL_CGROUP"),
+
+		AdminToken:             lookup("RUNNER_ADMIN_TOKEN"),
+		ExceptionsFile:         lookup("RUNNER_EXCEPTIONS_FILE"),
+		MaxExceptionTTLSeconds: maxExceptionTTL,
+	}, nil
+}
+
+// TLS loads the listener's TLS config; it is nil when TLS is not configured.
+// With a client CA, connections without a certificate it verifies are refused.
+func (c Config) TLS() (*tls.Config, error) {
+	if c.TLSCertFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("RUNNER_TLS_CERT_FILE: %w", err)
+	}
+	out := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if c.TLSClientCAFile == "" {
+		return out, nil
+	}
+	pem, err := os.ReadFile(c.TLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("RUNNER_TLS_CLIENT_CA_FILE: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("RUNNER_TLS_CLIENT_CA_FILE: no PEM certificates in %s", c.TLSClientCAFile)
 	}
+	out.ClientCAs, out.ClientAuth = pool, tls.RequireAndVerifyClientCert
+	return out, nil
 }
 
-func getEnv(key, fallback string) string {
-	v := os.Getenv(key)
+func getEnv(lookup func(string) string, key, fallback string) string {
+	v := strings.TrimSpace(lookup(key))
 	if v == "" {
 		return fallback
 	}
 	return v
 }
 
-func getEnvInt64(key string, fallback int64) int64 {
-	v := os.Getenv(key)
+func getInt64(lookup func(string) string, key string, fallback, min int64) (int64, error) {
+	v := strings.TrimSpace(lookup(key))
 	if v == "" {
-		return fallback
+		return fallback, nil
 	}
 	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < min {
+		return 0, fmt.Errorf("%s must be an integer >= %d", key, min)
+	}
+	return n, nil
+}
+
+// getRetention parses "status=seconds" entries over defaults. Only statuses
+// present in defaults may be set.
+func getRetention(lookup func(string) string, key string, defaults map[string]int64) (map[string]int64, error) {
+	out := make(map[string]int64, len(defaults))
+	for status, v := range defaults {
+		out[status] = v
+	}
+	for _, entry := range splitList(lookup(key)) {
+		status, v, _ := strings.Cut(entry, "=")
+		status = strings.TrimSpace(status)
+		if _, ok := defaults[status]; !ok {
+			statuses := make([]string, 0, len(defaults))
+			for s := range defaults {
+				statuses = append(statuses, s)
+			}
+			slices.Sort(statuses)
+			return nil, fmt.Errorf("%s: unknown status %q (known: %s)", key, status, strings.Join(statuses, ", "))
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s: %s must be status=seconds with seconds >= 0", key, entry)
+		}
+		out[status] = n
+	}
+	return out, nil
+}
+
+func getBool(lookup func(string) string, key string, fallback bool) (bool, error) {
+	v := strings.TrimSpace(lookup(key))
+	if v == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(v)
 	if err != nil {
-		return fallback
+		return false, fmt.Errorf("%s must be true or false", key)
+	}
+	return b, nil
+}
+
+// quantities parses resource settings, keeping the first error so From can
+// report it once all values have been read.
+type quantities struct {
+	lookup func(string) string
+	err    error
+}
+
+func (q *quantities) get(key, fallback string) resource.Quantity {
+	v := getEnv(q.lookup, key, fallback)
+	if v == "" {
+		return resource.Quantity{}
+	}
+	parsed, err := resource.ParseQuantity(v)
+	if err != nil && q.err == nil {
+		q.err = fmt.Errorf("%s: %q is not a valid quantity", key, v)
+	}
+	return parsed
+}
+
+func (q *quantities) within(key string, v, min, max resource.Quantity) {
+	if q.err == nil && (v.Cmp(min) < 0 || v.Cmp(max) > 0) {
+		q.err = fmt.Errorf("%s %s must be within %s-%s", key, v.String(), min.String(), max.String())
+	}
+}
+
+func quantityString(q resource.Quantity) string {
+	if q.IsZero() {
+		return ""
+	}
+	return q.String()
+}
+
+func splitList(raw string) []string {
+	parts := []string{}
+	for _, p := range strings.Split(raw, ",") {
+		if v := strings.TrimSpace(p); v != "" {
+			parts = append(parts, v)
+		}
 	}
-	return n
+	return parts
 }
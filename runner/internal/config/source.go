@@ -0,0 +1,302 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// fileKeys maps each config file section and key to the RUNNER_* variable it
+// sets. Environment variables override file values. Secrets such as
+// RUNNER_ADMIN_TOKEN are environment-only.
+var fileKeys = map[string]map[string]string{
+	"server": {
+		"addr":               "RUNNER_ADDR",
+		"tls_cert_file":      "RUNNER_TLS_CERT_FILE",
+		"tls_key_file":       "RUNNER_TLS_KEY_FILE",
+		"tls_client_ca_file": "RUNNER_TLS_CLIENT_CA_FILE",
+		"backend":            "RUNNER_BACKEND",
+		"namespace":          "RUNNER_NAMESPACE",
+		"runtime_class":      "RUNNER_RUNTIMECLASS",
+		"image_pull_policy":  "RUNNER_IMAGE_PULL_POLICY",
+		"exceptions_file":    "RUNNER_EXCEPTIONS_FILE",
+	},
+	"readiness": {
+		"probe":               "RUNNER_READINESS_PROBE",
+		"timeout_seconds":     "RUNNER_READY_TIMEOUT_SECONDS",
+		"max_timeout_seconds": "RUNNER_MAX_READY_TIMEOUT_SECONDS",
+		"handshake_path":      "RUNNER_MCP_HANDSHAKE_PATH",
+	},
+	"idle": {
+		"default_timeout_seconds": "RUNNER_DEFAULT_IDLE_TIMEOUT_SECONDS",
+		"max_timeout_seconds":     "RUNNER_MAX_IDLE_TIMEOUT_SECONDS",
+		"reap_seconds":            "RUNNER_IDLE_REAP_SECONDS",
+	},
+	"gc": {
+		"interval_seconds": "RUNNER_GC_INTERVAL_SECONDS",
+		"orphan_seconds":   "RUNNER_GC_ORPHAN_SECONDS",
+		"pod_retention":    "RUNNER_GC_POD_RETENTION",
+		"record_retention": "RUNNER_GC_RECORD_RETENTION",
+	},
+	"quarantine": {
+		"dir":      "RUNNER_QUARANTINE_DIR",
+		"triggers": "RUNNER_QUARANTINE_TRIGGERS",
+	},
+	"selfcheck": {
+		"mode":             "RUNNER_SELFCHECK_MODE",
+		"interval_seconds": "RUNNER_SELFCHECK_INTERVAL_SECONDS",
+		"pod_security":     "RUNNER_SELFCHECK_POD_SECURITY",
+	},
+	"pool": {
+		"size":         "RUNNER_WARM_POOL_SIZE",
+		"idle_seconds": "RUNNER_WARM_POOL_IDLE_SECONDS",
+		"max_images":   "RUNNER_WARM_POOL_MAX_IMAGES",
+	},
+	"local": {
+		"image_dir": "RUNNER_LOCAL_IMAGE_DIR",
+		"state_dir": "RUNNER_LOCAL_STATE_DIR",
+		"cgroup":    "RUNNER_LOCAL_CGROUP",
+	},
+	"secrets": {
+		"backend":             "RUNNER_SECRETS_BACKEND",
+		"namespace":           "RUNNER_SECRETS_NAMESPACE",
+		"dir":                 "RUNNER_SECRETS_DIR",
+		"ttl_seconds":         "RUNNER_SECRET_TTL_SECONDS",
+		"max_secrets_per_run": "RUNNER_MAX_SECRETS_PER_RUN",
+	},
+	"limits": {
+		"default_cpu":               "RUNNER_DEFAULT_CPU",
+		"default_memory":            "RUNNER_DEFAULT_MEMORY",
+		"default_timeout_seconds":   "RUNNER_DEFAULT_TIMEOUT_SECONDS",
+		"cleanup_seconds":           "RUNNER_CLEANUP_SECONDS",
+		"job_backoff_limit":         "RUNNER_JOB_BACKOFF_LIMIT",
+		"job_ttl_seconds":           "RUNNER_JOB_TTL_SECONDS",
+		"max_exception_ttl_seconds": "RUNNER_MAX_EXCEPTION_TTL_SECONDS",
+		"default_ephemeral_storage": "RUNNER_DEFAULT_EPHEMERAL_STORAGE",
+		"min_cpu":                   "RUNNER_MIN_CPU",
+		"max_cpu":                   "RUNNER_MAX_CPU",
+		"min_memory":                "RUNNER_MIN_MEMORY",
+		"max_memory":                "RUNNER_MAX_MEMORY",
+		"max_ephemeral_storage":     "RUNNER_MAX_EPHEMERAL_STORAGE",
+		"min_timeout_seconds":       "RUNNER_MIN_TIMEOUT_SECONDS",
+		"max_timeout_seconds":       "RUNNER_MAX_TIMEOUT_SECONDS",
+		"env_max_vars":              "RUNNER_ENV_MAX_VARS",
+		"env_max_value_bytes":       "RUNNER_ENV_MAX_VALUE_BYTES",
+		"env_max_total_bytes":       "RUNNER_ENV_MAX_TOTAL_BYTES",
+		"env_denylist":              "RUNNER_ENV_DENYLIST",
+		"env_secret_scan":           "RUNNER_ENV_SECRET_SCAN",
+	},
+	"profiles": {
+		"network": "RUNNER_NETWORK_PROFILES",
+	},
+	"policy": {
+		"allowlisted_registries":   "RUNNER_ALLOWLISTED_REGISTRIES",
+		"denylisted_images":        "RUNNER_DENYLISTED_IMAGES",
+		"require_cosign":           "RUNNER_REQUIRE_COSIGN",
+		"cosign_key_path":          "RUNNER_COSIGN_KEY_PATH",
+		"cosign_identity":          "RUNNER_COSIGN_IDENTITY",
+		"cosign_issuer":            "RUNNER_COSIGN_ISSUER",
+		"trust_roots_file":         "RUNNER_TRUST_ROOTS_FILE",
+		"command_policy_file":      "RUNNER_COMMAND_POLICY_FILE",
+		"rules_dir":                "RUNNER_POLICY_DIR",
+		"reload_seconds":           "RUNNER_POLICY_RELOAD_SECONDS",
+		"verify_cache_ttl_seconds": "RUNNER_VERIFY_CACHE_TTL_SECONDS",
+		"verify_cache_max_entries": "RUNNER_VERIFY_CACHE_MAX_ENTRIES",
+		"require_provenance":       "RUNNER_REQUIRE_PROVENANCE",
+		"allowed_builder_ids":      "RUNNER_ALLOWED_BUILDER_IDS",
+		"allowed_source_repos":     "RUNNER_ALLOWED_SOURCE_REPOS",
+		"require_sbom":             "RUNNER_REQUIRE_SBOM",
+		"require_vuln_scan":        "RUNNER_REQUIRE_VULN_SCAN",
+		"vuln_max_critical":        "RUNNER_VULN_MAX_CRITICAL",
+		"vuln_max_high":            "RUNNER_VULN_MAX_HIGH",
+		"vuln_max_scan_age_hours":  "RUNNER_VULN_MAX_SCAN_AGE_HOURS",
+		"vuln_exceptions_file":     "RUNNER_VULN_EXCEPTIONS_FILE",
+		"inspect_image_config":     "RUNNER_INSPECT_IMAGE_CONFIG",
+		"max_image_size_mb":        "RUNNER_MAX_IMAGE_SIZE_MB",
+		"image_platform":           "RUNNER_IMAGE_PLATFORM",
+		"insecure_registries":      "RUNNER_INSECURE_REGISTRIES",
+		"require_tool_manifest":    "RUNNER_REQUIRE_TOOL_MANIFEST",
+	},
+}
+
+// restartKeys are wired into long-lived components at startup (listener,
+// watchers, caches, registry client). A reload that changes them is reported
+// but the running values are kept until restart.
+var restartKeys = map[string]bool{
+	"RUNNER_ADDR":                       true,
+	"RUNNER_TLS_CERT_FILE":              true,
+	"RUNNER_TLS_KEY_FILE":               true,
+	"RUNNER_TLS_CLIENT_CA_FILE":         true,
+	"RUNNER_BACKEND":                    true,
+	"RUNNER_LOCAL_IMAGE_DIR":            true,
+	"RUNNER_IDLE_REAP_SECONDS":          true,
+	"RUNNER_GC_INTERVAL_SECONDS":        true,
+	"RUNNER_QUARANTINE_DIR":             true,
+	"RUNNER_SELFCHECK_INTERVAL_SECONDS": true,
+	"RUNNER_WARM_POOL_SIZE":             true,
+	"RUNNER_WARM_POOL_IDLE_SECONDS":     true,
+	"RUNNER_WARM_POOL_MAX_IMAGES":       true,
+	"RUNNER_LOCAL_STATE_DIR":            true,
+	"RUNNER_LOCAL_CGROUP":               true,
+	"RUNNER_NAMESPACE":                  true,
+	"RUNNER_RUNTIMECLASS":               true,
+	"RUNNER_IMAGE_PULL_POLICY":          true,
+	"RUNNER_EXCEPTIONS_FILE":            true,
+	"RUNNER_SECRETS_BACKEND":            true,
+	"RUNNER_SECRETS_NAMESPACE":          true,
+	"RUNNER_SECRETS_DIR":                true,
+	"RUNNER_TRUST_ROOTS_FILE":           true,
+	"RUNNER_POLICY_DIR":                 true,
+	"RUNNER_POLICY_RELOAD_SECONDS":      true,
+	"RUNNER_VERIFY_CACHE_TTL_SECONDS":   true,
+	"RUNNER_VERIFY_CACHE_MAX_ENTRIES":   true,
+	"RUNNER_IMAGE_PLATFORM":             true,
+	"RUNNER_INSECURE_REGISTRIES":        true,
+}
+
+// Source resolves RUNNER_* keys from an optional config file with
+// environment overrides. It is immutable; Reload returns a new Source.
+type Source struct {
+	Path     string
+	Hash     string
+	LoadedAt time.Time
+	values   map[string]string
+}
+
+// Load reads the config file at path (YAML or JSON). An empty path yields a
+// Source backed by the environment alone.
+func Load(path string) (*Source, error) {
+	values := map[string]string{}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+		if values, err = parseFile(b); err != nil {
+			return nil, fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+	for _, section := range fileKeys {
+		for _, key := range section {
+			if v, ok := os.LookupEnv(key); ok && strings.TrimSpace(v) != "" {
+				values[key] = v
+			}
+		}
+	}
+	return &Source{Path: path, Hash: hashValues(values), LoadedAt: time.Now().UTC(), values: values}, nil
+}
+
+// Lookup returns the effective value for key. Keys that cannot be set from the
+// file fall through to the environment.
+func (s *Source) Lookup(key string) string {
+	if v, ok := s.values[key]; ok {
+		return v
+	}
+	if isFileKey(key) {
+		return ""
+	}
+	return os.Getenv(key)
+}
+
+// Reload re-reads the file. Restart-only keys keep their current values in
+// the returned Source; the ones that differ on disk are listed in pending.
+func (s *Source) Reload() (next *Source, pending []string, err error) {
+	next, err = Load(s.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	for key := range restartKeys {
+		if next.values[key] == s.values[key] {
+			continue
+		}
+		pending = append(pending, key)
+		if v, ok := s.values[key]; ok {
+			next.values[key] = v
+		} else {
+			delete(next.values, key)
+		}
+	}
+	sort.Strings(pending)
+	next.Hash = hashValues(next.values)
+	return next, pending, nil
+}
+
+func parseFile(b []byte) (map[string]string, error) {
+	var raw map[string]map[string]any
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for section, entries := range raw {
+		keys, ok := fileKeys[section]
+		if !ok {
+			return nil, fmt.Errorf("unknown section %q", section)
+		}
+		for name, v := range entries {
+			key, ok := keys[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown key %s.%s", section, name)
+			}
+			s, err := scalar(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", section, name, err)
+			}
+			values[key] = s
+		}
+	}
+	return values, nil
+}
+
+// scalar renders a decoded file value in the string form the RUNNER_*
+// variables use: lists become comma-separated.
+func scalar(v any) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case float64:
+		if t != math.Trunc(t) {
+			return "", fmt.Errorf("must be an integer")
+		}
+		return strconv.FormatInt(int64(t), 10), nil
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, item := range t {
+			s, ok := item.(string)
+			if !ok || strings.Contains(s, ",") {
+				return "", fmt.Errorf("list entries must be strings without commas")
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+func hashValues(values map[string]string) string {
+	b, _ := json.Marshal(values)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func isFileKey(key string) bool {
+	for _, section := range fileKeys {
+		for _, k := range section {
+			if k == key {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadFileWithEnvOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runner.yaml")
+	writeConfig(t, path, `
+server:
+  namespace: mcp-prod
+limits:
+  default_timeout_seconds: 600
+profiles:
+  network: [deny-all]
+policy:
+  allowlisted_registries: [ghcr.io/our-org, cgr.dev/chainguard]
+  require_sbom: true
+`)
+	t.Setenv("RUNNER_DEFAULT_TIMEOUT_SECONDS", "900")
+
+	src, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := From(src.Lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Namespace != "mcp-prod" || cfg.DefaultTimeout != 900 || len(cfg.NetworkProfiles) != 1 {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if got := src.Lookup("RUNNER_ALLOWLISTED_REGISTRIES"); got != "ghcr.io/our-org,cgr.dev/chainguard" {
+		t.Fatalf("list not flattened: %q", got)
+	}
+	if got := src.Lookup("RUNNER_REQUIRE_SBOM"); got != "true" {
+		t.Fatalf("bool not flattened: %q", got)
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runner.yaml")
+	for name, content := range map[string]string{
+		"unknown section": "serverr: {addr: ':8080'}",
+		"unknown key":     "server: {adress: ':8080'}",
+		"fractional":      "limits: {default_timeout_seconds: 1.5}",
+		"nested value":    "policy: {allowlisted_registries: [{host: ghcr.io}]}",
+		"secret in file":  "server: {admin_token: hunter2}",
+	} {
+		writeConfig(t, path, content)
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestFromRejectsMalformedValues(t *testing.T) {
+	for key, value := range map[string]string{
+		"RUNNER_DEFAULT_TIMEOUT_SECONDS":      "5m",
+		"RUNNER_CLEANUP_SECONDS":              "-1",
+		"RUNNER_IMAGE_PULL_POLICY":            "Sometimes",
+		"RUNNER_NETWORK_PROFILES":             "allow-all",
+		"RUNNER_BACKEND":                      "docker",
+		"RUNNER_READY_TIMEOUT_SECONDS":        "900",
+		"RUNNER_MCP_HANDSHAKE_PATH":           "mcp",
+		"RUNNER_DEFAULT_IDLE_TIMEOUT_SECONDS": "7200",
+		"RUNNER_GC_POD_RETENTION":             "crashed=60",
+		"RUNNER_GC_RECORD_RETENTION":          "failed=10",
+		"RUNNER_QUARANTINE_TRIGGERS":          "tool_scope_violation,oom",
+		"RUNNER_SELFCHECK_MODE":               "enforce",
+		"RUNNER_SELFCHECK_POD_SECURITY":       "privileged",
+		"RUNNER_TLS_CERT_FILE":                "/etc/runner/tls.crt",
+		"RUNNER_TLS_CLIENT_CA_FILE":           "/etc/runner/ca.crt",
+	} {
+		lookup := func(k string) string {
+			if k == key {
+				return value
+			}
+			return ""
+		}
+		if _, err := From(lookup); err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("%s=%s: expected error naming the key, got %v", key, value, err)
+		}
+	}
+}
+
+func TestReloadPinsRestartKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runner.yaml")
+	writeConfig(t, path, "server: {addr: ':8080'}\nlimits: {default_cpu: 100m}")
+	src, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfig(t, path, "server: {addr: ':9090'}\nlimits: {default_cpu: 250m}")
+	next, pending, err := src.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Lookup("RUNNER_DEFAULT_CPU") != "250m" {
+		t.Fatalf("reloadable key not applied")
+	}
+	if next.Lookup("RUNNER_ADDR") != ":8080" {
+		t.Fatalf("restart-only key must keep its running value, got %q", next.Lookup("RUNNER_ADDR"))
+	}
+	if len(pending) != 1 || pending[0] != "RUNNER_ADDR" {
+		t.Fatalf("expected RUNNER_ADDR to be pending restart, got %v", pending)
+	}
+	if next.Hash == src.Hash {
+		t.Fatalf("hash must change with the applied config")
+	}
+}
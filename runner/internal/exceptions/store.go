@@ -0,0 +1,132 @@
+package exceptions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mcp-orc/runner/internal/policy"
+)
+
+var ErrNotFound = errors.New("exception not found")
+
+// Store holds break-glass policy exceptions. With a path every change is
+// written to disk before it is acknowledged, so exceptions survive restarts.
+type Store struct {
+	mu    sync.RWMutex
+	path  string
+	items map[string]policy.Exception
+}
+
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path, items: map[string]policy.Exception{}}
+	if path == "" {
+		return s, nil
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read exceptions file: %w", err)
+	}
+	var items []policy.Exception
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("parse exceptions file %s: %w", path, err)
+	}
+	for _, x := range items {
+		s.items[x.ID] = x
+	}
+	return s, nil
+}
+
+func (s *Store) Create(x policy.Exception) (policy.Exception, error) {
+	if err := x.Validate(); err != nil {
+		return policy.Exception{}, err
+	}
+	x.ID = uuid.NewString()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[x.ID] = x
+	if err := s.persist(); err != nil {
+		delete(s.items, x.ID)
+		return policy.Exception{}, err
+	}
+	return x, nil
+}
+
+func (s *Store) Revoke(id, by string, at time.Time) (policy.Exception, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.items[id]
+	if !ok {
+		return policy.Exception{}, ErrNotFound
+	}
+	if prev.RevokedAt != nil {
+		return prev, nil
+	}
+	x := prev
+	x.RevokedAt = &at
+	x.RevokedBy = by
+	s.items[id] = x
+	if err := s.persist(); err != nil {
+		s.items[id] = prev
+		return policy.Exception{}, err
+	}
+	return x, nil
+}
+
+// Exceptions returns every stored exception, oldest first. Expired and
+// revoked entries are kept for audit; policy.Exception.Covers ignores them.
+func (s *Store) Exceptions() []policy.Exception {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]policy.Exception, 0, len(s.items))
+	for _, x := range s.items {
+		out = append(out, x)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+func (s *Store) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	items := make([]policy.Exception, 0, len(s.items))
+	for _, x := range s.items {
+		items = append(items, x)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	raw, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".exceptions-*")
+	if err != nil {
+		return fmt.Errorf("write exceptions file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write exceptions file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write exceptions file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write exceptions file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("write exceptions file: %w", err)
+	}
+	return nil
+}
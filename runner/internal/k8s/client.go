@@ -8,33 +8,89 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/mcp-orc/runner/internal/secrets"
 )
 
 type Client struct {
 	clientset *kubernetes.Clientset
 }
 
+// Run modes. A pod run is a bare Pod for long-lived servers; a job run is a
+// batch/v1 Job whose pod Kubernetes recreates on failure, for one-shot tools.
+const (
+	RunModePod = "pod"
+	RunModeJob = "job"
+)
+
+// Pod labels. The network profile label selects the run's egress
+// NetworkPolicy; the pool label marks unclaimed warm pods. QuarantineLabel
+// is in quarantine.go.
+const (
+	NetworkProfileLabel = "runner.mcp-orc.io/network-profile"
+	PoolLabel           = "runner.mcp-orc.io/pool"
+)
+
+// SecretsExpireAnnotation records, in RFC 3339, when a run's Secret is due
+// for deletion. It is set on the Secret and on the workload, so the garbage
+// collector finds expired secrets with the pods it already lists.
+const SecretsExpireAnnotation = "runner.mcp-orc.io/expires-at"
+
 type PodSpecInput struct {
-	Namespace        string
-	RunID            string
+	Namespace      string
+	RunID          string
+	RunMode        string
+	NetworkProfile string
+	// Warm marks a pool pod created ahead of any run; see Client.ClaimPod.
+	Warm bool
+	// ReadinessProbe adds a TCP readiness probe on DownstreamPort, so the
+	// pod is Ready only once the server accepts connections.
+	ReadinessProbe   bool
 	ImageRef         string
 	Command          []string
 	Args             []string
 	EnvAllowlist     map[string]string
-	CPU              string
-	Memory           string
+	CPURequest       string
+	CPULimit         string
+	MemoryRequest    string
+	MemoryLimit      string
+	EphemeralStorage string
 	TimeoutSeconds   int64
+	DownstreamPort   int
+	Secrets          []secrets.Secret
+	SecretTTLSeconds int64
 	RuntimeClassName string
 	ImagePullPolicy  corev1.PullPolicy
+	// BackoffLimit and TTLSecondsAfterFinished apply to job runs only; a zero
+	// TTL leaves finished Jobs to the runner's own cleanup.
+	BackoffLimit            int32
+	TTLSecondsAfterFinished int32
+}
+
+// RunName is the workload name for a run: run-<id> for a Pod, job-<id> for a
+// Job. The Backend methods take it as podName and dispatch on the prefix.
+func RunName(runID, mode string) string {
+	if mode == RunModeJob {
+		return "job-" + runID
+	}
+	return "run-" + runID
+}
+
+func isJob(name string) bool {
+	return strings.HasPrefix(name, "job-")
 }
 
 func NewClient() (*Client, error) {
@@ -55,59 +111,132 @@ func NewClient() (*Client, error) {
 }
 
 func (c *Client) CreateRunPod(ctx context.Context, in PodSpecInput) (string, error) {
-	podName := "run-" + in.RunID
-	env := make([]corev1.EnvVar, 0, len(in.EnvAllowlist))
+	podName := RunName(in.RunID, in.RunMode)
+	resources, err := podResources(in)
+	if err != nil {
+		return "", err
+	}
+	env := make([]corev1.EnvVar, 0, len(in.EnvAllowlist)+1)
 	for k, v := range in.EnvAllowlist {
 		env = append(env, corev1.EnvVar{Name: k, Value: v})
 	}
 
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+	var runSecret *corev1.Secret
+	var annotations map[string]string
+	if len(in.Secrets) > 0 {
+		runSecret, volumes, mounts = runSecretVolume(in, podName)
+		annotations = runSecret.Annotations
+		env = append(env, corev1.EnvVar{Name: "MCP_SECRETS_DIR", Value: secrets.MountPath})
+		if _, err := c.clientset.CoreV1().Secrets(in.Namespace).Create(ctx, runSecret, metav1.CreateOptions{}); err != nil {
+			return "", fmt.Errorf("create run secret: %w", err)
+		}
+	}
+
 	readOnly := true
 	allowPrivEsc := false
 	runAsNonRoot := true
 	automountSAToken := false
-	pod := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      podName,
-			Namespace: in.Namespace,
-			Labels: map[string]string{
-				"app":    "mcp-run",
-				"run_id": in.RunID,
-			},
+	meta := metav1.ObjectMeta{
+		Name:        podName,
+		Namespace:   in.Namespace,
+		Annotations: annotations,
+		Labels: map[string]string{
+			"app":               "mcp-run",
+			"run_id":            in.RunID,
+			NetworkProfileLabel: in.NetworkProfile,
 		},
-		Spec: corev1.PodSpec{
-			RuntimeClassName:             &in.RuntimeClassName,
-			RestartPolicy:                corev1.RestartPolicyNever,
-			ActiveDeadlineSeconds:        &in.TimeoutSeconds,
-			AutomountServiceAccountToken: &automountSAToken,
-			SecurityContext: &corev1.PodSecurityContext{
-				SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
-			},
-			Containers: []corev1.Container{{
-				Name:            "untrusted-mcp",
-				Image:           in.ImageRef,
-				ImagePullPolicy: in.ImagePullPolicy,
-				Command:         in.Command,
-				Args:            in.Args,
-				Env:             env,
-				SecurityContext: &corev1.SecurityContext{
-					ReadOnlyRootFilesystem:   &readOnly,
-					AllowPrivilegeEscalation: &allowPrivEsc,
-					RunAsNonRoot:             &runAsNonRoot,
-					Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
-				},
-				Resources: corev1.ResourceRequirements{Requests: mustRes(in.CPU, in.Memory), Limits: mustRes(in.CPU, in.Memory)},
-			}},
+	}
+	if in.Warm {
+		meta.Labels[PoolLabel] = "warm"
+	}
+	spec := corev1.PodSpec{
+		RuntimeClassName:             &in.RuntimeClassName,
+		RestartPolicy:                corev1.RestartPolicyNever,
+		ActiveDeadlineSeconds:        &in.TimeoutSeconds,
+		AutomountServiceAccountToken: &automountSAToken,
+		Volumes:                      volumes,
+		SecurityContext: &corev1.PodSecurityContext{
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
 		},
+		Containers: []corev1.Container{{
+			Name:            "untrusted-mcp",
+			Image:           in.ImageRef,
+			ImagePullPolicy: in.ImagePullPolicy,
+			Command:         in.Command,
+			Args:            in.Args,
+			Env:             env,
+			Ports:           []corev1.ContainerPort{{Name: "mcp", ContainerPort: int32(in.DownstreamPort)}},
+			VolumeMounts:    mounts,
+			SecurityContext: &corev1.SecurityContext{
+				ReadOnlyRootFilesystem:   &readOnly,
+				AllowPrivilegeEscalation: &allowPrivEsc,
+				RunAsNonRoot:             &runAsNonRoot,
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+			},
+			Resources: resources,
+		}},
 	}
 
-	_, err := c.clientset.CoreV1().Pods(in.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if in.ReadinessProbe {
+		spec.Containers[0].ReadinessProbe = &corev1.Probe{
+			ProbeHandler:     corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("mcp")}},
+			PeriodSeconds:    2,
+			FailureThreshold: 3,
+		}
+	}
+
+	var owner metav1.OwnerReference
+	if in.RunMode == RunModeJob {
+		// The deadline covers every attempt, so it moves from the pod to the Job.
+		spec.ActiveDeadlineSeconds = nil
+		job := &batchv1.Job{
+			ObjectMeta: meta,
+			Spec: batchv1.JobSpec{
+				BackoffLimit:          &in.BackoffLimit,
+				ActiveDeadlineSeconds: &in.TimeoutSeconds,
+				Template:              corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: meta.Labels}, Spec: spec},
+			},
+		}
+		if in.TTLSecondsAfterFinished > 0 {
+			job.Spec.TTLSecondsAfterFinished = &in.TTLSecondsAfterFinished
+		}
+		var created *batchv1.Job
+		created, err = c.clientset.BatchV1().Jobs(in.Namespace).Create(ctx, job, metav1.CreateOptions{})
+		if err == nil {
+			owner = metav1.OwnerReference{APIVersion: "batch/v1", Kind: "Job", Name: created.Name, UID: created.UID}
+		}
+	} else {
+		var created *corev1.Pod
+		created, err = c.clientset.CoreV1().Pods(in.Namespace).Create(ctx, &corev1.Pod{ObjectMeta: meta, Spec: spec}, metav1.CreateOptions{})
+		if err == nil {
+			owner = metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: created.Name, UID: created.UID}
+		}
+	}
 	if err != nil {
+		if runSecret != nil {
+			_ = c.DeleteRunSecret(context.Background(), in.Namespace, runSecret.Name)
+		}
 		return "", err
 	}
+	if runSecret != nil {
+		// Owning the secret by the pod (or Job) lets the garbage collector
+		// remove it with the run however it goes away; the runner's own GC
+		// deletes it earlier, once SecretsExpireAnnotation has passed.
+		if err := c.adoptSecret(ctx, in.Namespace, runSecret.Name, owner); err != nil {
+			_ = c.DeletePod(context.Background(), in.Namespace, podName)
+			_ = c.DeleteRunSecret(context.Background(), in.Namespace, runSecret.Name)
+			return "", fmt.Errorf("bind run secret to pod: %w", err)
+		}
+	}
 	return podName, nil
 }
 
 func (c *Client) GetPodStatus(ctx context.Context, namespace, podName string) (string, string, error) {
+	if isJob(podName) {
+		return c.getJobStatus(ctx, namespace, podName)
+	}
 	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
@@ -119,6 +248,16 @@ func (c *Client) GetPodStatus(ctx context.Context, namespace, podName string) (s
 }
 
 func (c *Client) GetPodLogs(ctx context.Context, namespace, podName string) (string, error) {
+	if isJob(podName) {
+		pod, err := c.jobPod(ctx, namespace, podName)
+		if err != nil {
+			return "", err
+		}
+		if pod == nil {
+			return "", fmt.Errorf("job %s has no pod yet", podName)
+		}
+		podName = pod.Name
+	}
 	req := c.clientset.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{})
 	stream, err := req.Stream(ctx)
 	if err != nil {
@@ -134,6 +273,9 @@ func (c *Client) GetPodLogs(ctx context.Context, namespace, podName string) (str
 
 func (c *Client) DeletePod(ctx context.Context, namespace, podName string) error {
 	grace := int64(0)
+	if isJob(podName) {
+		return c.deleteJob(ctx, namespace, podName, grace)
+	}
 	err := c.clientset.CoreV1().Pods(namespace).Delete(ctx, podName, metav1.DeleteOptions{GracePeriodSeconds: &grace})
 	if apierrors.IsNotFound(err) {
 		return nil
@@ -141,21 +283,332 @@ func (c *Client) DeletePod(ctx context.Context, namespace, podName string) error
 	return err
 }
 
-func mustRes(cpu, mem string) corev1.ResourceList {
-	return corev1.ResourceList{
-		corev1.ResourceCPU:    resource.MustParse(cpu),
-		corev1.ResourceMemory: resource.MustParse(mem),
+// PodReady reports whether the run's pod (the newest one for a job run) has
+// the Ready condition.
+func (c *Client) PodReady(ctx context.Context, namespace, podName string) (bool, error) {
+	var pod *corev1.Pod
+	var err error
+	if isJob(podName) {
+		pod, err = c.jobPod(ctx, namespace, podName)
+	} else {
+		pod, err = c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil || pod == nil {
+		return false, err
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue, nil
+		}
+	}
+	return false, nil
+}
+
+// Claim hands a warm pool pod to a run.
+type Claim struct {
+	RunID          string
+	NetworkProfile string
+	TimeoutSeconds int64
+}
+
+// ClaimPod relabels a running warm pod for the run and shortens its
+// activeDeadlineSeconds (counted from pod start) to the run's timeout. The
+// update is conditional on the pod's resourceVersion, so two claims for the
+// same pod cannot both succeed.
+func (c *Client) ClaimPod(ctx context.Context, namespace, podName string, claim Claim) error {
+	pods := c.clientset.CoreV1().Pods(namespace)
+	pod, err := pods.Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if pod.Labels[PoolLabel] != "warm" {
+		return fmt.Errorf("pod %s is not an unclaimed warm pod", podName)
+	}
+	if pod.Status.Phase != corev1.PodRunning || pod.Status.StartTime == nil {
+		return fmt.Errorf("pod %s is not running", podName)
+	}
+	deadline := int64(time.Since(pod.Status.StartTime.Time).Seconds()) + 1 + claim.TimeoutSeconds
+	if cur := pod.Spec.ActiveDeadlineSeconds; cur != nil && deadline > *cur {
+		return fmt.Errorf("pod %s cannot fit a %ds run", podName, claim.TimeoutSeconds)
+	}
+	delete(pod.Labels, PoolLabel)
+	pod.Labels["run_id"] = claim.RunID
+	pod.Labels[NetworkProfileLabel] = claim.NetworkProfile
+	pod.Spec.ActiveDeadlineSeconds = &deadline
+	_, err = pods.Update(ctx, pod, metav1.UpdateOptions{})
+	return err
+}
+
+// getJobStatus reports a job run in pod terms: the Job's terminal condition
+// once it has one, otherwise the phase of its newest pod. A failed attempt
+// that Kubernetes is about to retry reads as Pending/Retrying.
+func (c *Client) getJobStatus(ctx context.Context, namespace, name string) (string, string, error) {
+	job, err := c.clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "not_found", "job_missing", nil
+		}
+		return "", "", err
+	}
+	if phase, reason, _, ok := jobFinished(job); ok {
+		return phase, reason, nil
+	}
+	pod, err := c.jobPod(ctx, namespace, name)
+	if err != nil {
+		return "", "", err
+	}
+	if pod == nil {
+		return string(corev1.PodPending), "", nil
+	}
+	if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+		return string(corev1.PodPending), "Retrying", nil
+	}
+	return string(pod.Status.Phase), pod.Status.Reason, nil
+}
+
+// jobFinished maps a Job's Complete or Failed condition to a pod phase.
+func jobFinished(job *batchv1.Job) (phase, reason string, finished time.Time, ok bool) {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return string(corev1.PodSucceeded), "", cond.LastTransitionTime.Time, true
+		case batchv1.JobFailed:
+			return string(corev1.PodFailed), cond.Reason, cond.LastTransitionTime.Time, true
+		}
+	}
+	return "", "", time.Time{}, false
+}
+
+// jobPod returns the Job's newest pod, or nil before the first one exists.
+func (c *Client) jobPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
+	list, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + name})
+	if err != nil {
+		return nil, err
+	}
+	var newest *corev1.Pod
+	for i := range list.Items {
+		p := &list.Items[i]
+		if newest == nil || newest.CreationTimestamp.Before(&p.CreationTimestamp) {
+			newest = p
+		}
+	}
+	return newest, nil
+}
+
+// deleteJob removes the Job and kills its pods without waiting for the
+// garbage collector, which would give them the default grace period.
+func (c *Client) deleteJob(ctx context.Context, namespace, name string, grace int64) error {
+	propagation := metav1.DeletePropagationBackground
+	err := c.clientset.BatchV1().Jobs(namespace).Delete(ctx, name, metav1.DeleteOptions{GracePeriodSeconds: &grace, PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return c.clientset.CoreV1().Pods(namespace).DeleteCollection(ctx, metav1.DeleteOptions{GracePeriodSeconds: &grace}, metav1.ListOptions{LabelSelector: "job-name=" + name})
+}
+
+// podResources builds requests and limits from quantities the API layer has
+// already validated; a parse failure here is still an error, never a panic.
+func podResources(in PodSpecInput) (corev1.ResourceRequirements, error) {
+	out := corev1.ResourceRequirements{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}
+	for _, q := range []struct {
+		list  corev1.ResourceList
+		name  corev1.ResourceName
+		value string
+	}{
+		{out.Requests, corev1.ResourceCPU, in.CPURequest},
+		{out.Limits, corev1.ResourceCPU, in.CPULimit},
+		{out.Requests, corev1.ResourceMemory, in.MemoryRequest},
+		{out.Limits, corev1.ResourceMemory, in.MemoryLimit},
+		{out.Requests, corev1.ResourceEphemeralStorage, in.EphemeralStorage},
+		{out.Limits, corev1.ResourceEphemeralStorage, in.EphemeralStorage},
+	} {
+		if q.value == "" {
+			continue
+		}
+		parsed, err := resource.ParseQuantity(q.value)
+		if err != nil {
+			return out, fmt.Errorf("%s %q: %w", q.name, q.value, err)
+		}
+		q.list[q.name] = parsed
+	}
+	return out, nil
+}
+
+// runSecretVolume copies the brokered secrets into one per-run Secret and
+// projects it read-only at secrets.MountPath/<name>/<key>. Secret volumes are
+// tmpfs-backed, so values never touch the node's disk.
+func runSecretVolume(in PodSpecInput, podName string) (*corev1.Secret, []corev1.Volume, []corev1.VolumeMount) {
+	name := RunSecretName(podName)
+	data := map[string][]byte{}
+	items := []corev1.KeyToPath{}
+	for i, s := range in.Secrets {
+		keys := s.Keys()
+		sort.Strings(keys)
+		for _, k := range keys {
+			dataKey := fmt.Sprintf("%d.%s", i, k)
+			data[dataKey] = s.Data[k]
+			items = append(items, corev1.KeyToPath{Key: dataKey, Path: s.Name + "/" + k})
+		}
+	}
+	expires := time.Now().UTC().Add(time.Duration(in.SecretTTLSeconds) * time.Second)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   in.Namespace,
+			Labels:      map[string]string{"app": "mcp-run", "run_id": in.RunID},
+			Annotations: map[string]string{SecretsExpireAnnotation: expires.Format(time.RFC3339)},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	mode := int32(0o444)
+	volumes := []corev1.Volume{{
+		Name: "mcp-secrets",
+		VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+			DefaultMode: &mode,
+			Sources: []corev1.VolumeProjection{{Secret: &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+				Items:                items,
+			}}},
+		}},
+	}}
+	mounts := []corev1.VolumeMount{{Name: "mcp-secrets", MountPath: secrets.MountPath, ReadOnly: true}}
+	return secret, volumes, mounts
+}
+
+// RunSecretName is the per-run Secret holding a pod's brokered secrets.
+func RunSecretName(podName string) string {
+	return podName + "-secrets"
+}
+
+func (c *Client) adoptSecret(ctx context.Context, namespace, secretName string, owner metav1.OwnerReference) error {
+	secrets := c.clientset.CoreV1().Secrets(namespace)
+	s, err := secrets.Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	s.OwnerReferences = []metav1.OwnerReference{owner}
+	_, err = secrets.Update(ctx, s, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *Client) DeleteRunSecret(ctx context.Context, namespace, name string) error {
+	err := c.clientset.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// SecretBackend serves broker secrets from a dedicated namespace that run
+// pods have no access to.
+type SecretBackend struct {
+	client    *Client
+	namespace string
+}
+
+func (c *Client) SecretBackend(namespace string) *SecretBackend {
+	return &SecretBackend{client: c, namespace: namespace}
+}
+
+func (b *SecretBackend) Get(ctx context.Context, name string) (secrets.Secret, error) {
+	s, err := b.client.clientset.CoreV1().Secrets(b.namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return secrets.Secret{}, secrets.ErrNotFound
+	}
+	if err != nil {
+		return secrets.Secret{}, err
 	}
+	return secrets.Secret{
+		Name:          name,
+		Data:          s.Data,
+		AllowedImages: secrets.SplitAllowedImages(s.Annotations[secrets.AllowedImagesAnnotation]),
+	}, nil
+}
+
+// RunPod is a run workload found by ListRunPods.
+type RunPod struct {
+	Name   string
+	RunID  string
+	Phase  string
+	Reason string
+	// Warm marks an unclaimed warm pool pod.
+	Warm bool
+	// Quarantined marks a workload with QuarantineLabel.
+	Quarantined bool
+	CreatedAt   time.Time
+	// FinishedAt is when the workload became Succeeded or Failed; zero while
+	// it runs or when the API does not record it.
+	FinishedAt time.Time
+	// SecretsExpireAt is when the run's Secret is due for deletion; zero for
+	// runs without secrets.
+	SecretsExpireAt time.Time
 }
 
-func (c *Client) WaitAndDelete(namespace, podName string, waitSeconds int64) {
-	go func() {
-		time.Sleep(time.Duration(waitSeconds) * time.Second)
-		_ = c.DeletePod(context.Background(), namespace, podName)
-	}()
+// secretsExpireAt parses SecretsExpireAnnotation, zero when absent or
+// malformed.
+func secretsExpireAt(annotations map[string]string) time.Time {
+	t, _ := time.Parse(time.RFC3339, annotations[SecretsExpireAnnotation])
+	return t
+}
+
+// ListRunPods returns every run workload in the namespace: bare run pods and
+// Jobs, but not the pods a Job owns.
+func (c *Client) ListRunPods(ctx context.Context, namespace string) ([]RunPod, error) {
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "app=mcp-run,!job-name"})
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := c.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{LabelSelector: "app=mcp-run"})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]RunPod, 0, len(pods.Items)+len(jobs.Items))
+	for _, p := range pods.Items {
+		rp := RunPod{
+			Name:            p.Name,
+			RunID:           p.Labels["run_id"],
+			Phase:           string(p.Status.Phase),
+			Reason:          p.Status.Reason,
+			Warm:            p.Labels[PoolLabel] == "warm",
+			Quarantined:     p.Labels[QuarantineLabel] == "true",
+			CreatedAt:       p.CreationTimestamp.Time,
+			SecretsExpireAt: secretsExpireAt(p.Annotations),
+		}
+		for _, cs := range p.Status.ContainerStatuses {
+			if t := cs.State.Terminated; t != nil && t.FinishedAt.After(rp.FinishedAt) {
+				rp.FinishedAt = t.FinishedAt.Time
+			}
+		}
+		out = append(out, rp)
+	}
+	for _, j := range jobs.Items {
+		rp := RunPod{Name: j.Name, RunID: j.Labels["run_id"], Phase: string(corev1.PodPending), Quarantined: j.Labels[QuarantineLabel] == "true", CreatedAt: j.CreationTimestamp.Time, SecretsExpireAt: secretsExpireAt(j.Annotations)}
+		if j.Status.Active > 0 {
+			rp.Phase = string(corev1.PodRunning)
+		}
+		if phase, reason, finished, ok := jobFinished(&j); ok {
+			rp.Phase, rp.Reason, rp.FinishedAt = phase, reason, finished
+		}
+		out = append(out, rp)
+	}
+	return out, nil
 }
 
 func (c *Client) GetPodIP(ctx context.Context, namespace, podName string) (string, error) {
+	if isJob(podName) {
+		pod, err := c.jobPod(ctx, namespace, podName)
+		if err != nil || pod == nil {
+			return "", err
+		}
+		return pod.Status.PodIP, nil
+	}
 	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
 		return "", err
@@ -164,12 +617,18 @@ func (c *Client) GetPodIP(ctx context.Context, namespace, podName string) (strin
 }
 
 func (c *Client) InvokeTool(ctx context.Context, podIP string, port int, toolName string, payload []byte) ([]byte, int, error) {
-	url := fmt.Sprintf("http://%s:%d/tools/%s", podIP, port, toolName)
+	return c.PostJSON(ctx, podIP, port, "/tools/"+toolName, payload)
+}
+
+// PostJSON posts payload to path on the pod's server port.
+func (c *Client) PostJSON(ctx context.Context, podIP string, port int, path string, payload []byte) ([]byte, int, error) {
+	url := fmt.Sprintf("http://%s:%d%s", podIP, port, path)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
 	if err != nil {
 		return nil, 0, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, 0, err
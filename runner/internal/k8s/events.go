@@ -0,0 +1,134 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// Event is a Kubernetes event about a run's Pod or Job.
+type Event struct {
+	// Object is "Pod/<name>" or "Job/<name>".
+	Object    string    `json:"object"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+	Count     int32     `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// events returns the events recorded for the named objects, oldest first.
+func (c *Client) events(ctx context.Context, namespace string, names ...string) ([]Event, error) {
+	out := []Event{}
+	for _, name := range names {
+		list, err := c.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("involvedObject.name", name).String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range list.Items {
+			out = append(out, toEvent(e))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].LastSeen.Before(out[j].LastSeen) })
+	return out, nil
+}
+
+func toEvent(e corev1.Event) Event {
+	first, last := e.FirstTimestamp.Time, e.LastTimestamp.Time
+	// Events written through events.k8s.io/v1 only set EventTime.
+	if first.IsZero() {
+		first = e.EventTime.Time
+	}
+	if last.IsZero() {
+		last = first
+	}
+	count := e.Count
+	if count == 0 {
+		count = 1
+	}
+	return Event{
+		Object:    e.InvolvedObject.Kind + "/" + e.InvolvedObject.Name,
+		Type:      e.Type,
+		Reason:    e.Reason,
+		Message:   e.Message,
+		Count:     count,
+		FirstSeen: first,
+		LastSeen:  last,
+	}
+}
+
+// ContainerState is a container's current state as the kubelet reports it.
+type ContainerState struct {
+	Name string `json:"name"`
+	// State is "waiting", "running" or "terminated".
+	State        string `json:"state"`
+	Reason       string `json:"reason,omitempty"`
+	Message      string `json:"message,omitempty"`
+	ExitCode     *int32 `json:"exit_code,omitempty"`
+	RestartCount int32  `json:"restart_count"`
+}
+
+// PodDiagnostics is what Kubernetes says about a run's workload beyond its
+// phase: container states and the events for the Pod (or the Job and its
+// newest pod).
+type PodDiagnostics struct {
+	Containers []ContainerState
+	Events     []Event
+}
+
+// DescribePod collects the run's container states and events. A missing
+// workload yields empty diagnostics.
+func (c *Client) DescribePod(ctx context.Context, namespace, podName string) (PodDiagnostics, error) {
+	var pod *corev1.Pod
+	var err error
+	names := []string{podName}
+	if isJob(podName) {
+		pod, err = c.jobPod(ctx, namespace, podName)
+		if pod != nil {
+			names = append(names, pod.Name)
+		}
+	} else {
+		pod, err = c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			pod, err = nil, nil
+		}
+	}
+	if err != nil {
+		return PodDiagnostics{}, err
+	}
+	events, err := c.events(ctx, namespace, names...)
+	if err != nil {
+		return PodDiagnostics{}, err
+	}
+	d := PodDiagnostics{Containers: []ContainerState{}, Events: events}
+	if pod != nil {
+		for _, cs := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+			d.Containers = append(d.Containers, toContainerState(cs))
+		}
+	}
+	return d, nil
+}
+
+func toContainerState(cs corev1.ContainerStatus) ContainerState {
+	out := ContainerState{Name: cs.Name, RestartCount: cs.RestartCount}
+	switch s := cs.State; {
+	case s.Waiting != nil:
+		out.State, out.Reason, out.Message = "waiting", s.Waiting.Reason, s.Waiting.Message
+	case s.Terminated != nil:
+		code := s.Terminated.ExitCode
+		out.State, out.Reason, out.Message, out.ExitCode = "terminated", s.Terminated.Reason, s.Terminated.Message, &code
+	case s.Running != nil:
+		out.State = "running"
+	default:
+		out.State = "waiting"
+	}
+	return out
+}
@@ -0,0 +1,239 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// QuarantineLabel marks a quarantined run's Pod or Job (and the Job's pods).
+// The garbage collector leaves labelled workloads alone.
+const QuarantineLabel = "runner.mcp-orc.io/quarantine"
+
+// maxEvidenceLogBytes caps the logs captured into an evidence bundle.
+const maxEvidenceLogBytes = 1 << 20
+
+// Evidence is the state of a run's workload captured at quarantine time.
+// What could not be collected is listed in Errors rather than failing the
+// whole capture.
+type Evidence struct {
+	CollectedAt time.Time `json:"collected_at"`
+	// Object is the Pod, or the Job for a job run, as returned by the API.
+	Object json.RawMessage `json:"object,omitempty"`
+	// Pod is a job run's newest pod.
+	Pod               json.RawMessage `json:"pod,omitempty"`
+	ContainerStatuses json.RawMessage `json:"container_statuses,omitempty"`
+	Events            []Event         `json:"events"`
+	Logs              string          `json:"logs"`
+	Errors            []string        `json:"errors,omitempty"`
+}
+
+func quarantinePolicyName(podName string) string {
+	return "quarantine-" + podName
+}
+
+// QuarantinePod isolates a run's workload. A NetworkPolicy owned by the Pod
+// or Job denies all ingress and egress to pods with the run's run_id, and
+// the workload is relabelled to the deny-all profile so that allow policies
+// for its original profile no longer select it. A Job also loses its
+// ttlSecondsAfterFinished, so Kubernetes does not delete it. Repeating the
+// call is harmless.
+func (c *Client) QuarantinePod(ctx context.Context, namespace, podName string) error {
+	var owner metav1.OwnerReference
+	var runID string
+	var pods []corev1.Pod
+	if isJob(podName) {
+		job, err := c.clientset.BatchV1().Jobs(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		owner = metav1.OwnerReference{APIVersion: "batch/v1", Kind: "Job", Name: job.Name, UID: job.UID}
+		runID = job.Labels["run_id"]
+		list, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + podName})
+		if err != nil {
+			return err
+		}
+		pods = list.Items
+	} else {
+		pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		owner = metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: pod.Name, UID: pod.UID}
+		runID = pod.Labels["run_id"]
+		pods = []corev1.Pod{*pod}
+	}
+	if runID == "" {
+		return fmt.Errorf("%s has no run_id label", podName)
+	}
+
+	// The policy goes first: it also covers pods a Job creates later.
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            quarantinePolicyName(podName),
+			Namespace:       namespace,
+			Labels:          map[string]string{"app": "mcp-run", "run_id": runID, QuarantineLabel: "true"},
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"run_id": runID}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+	_, err := c.clientset.NetworkingV1().NetworkPolicies(namespace).Create(ctx, policy, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create quarantine network policy: %w", err)
+	}
+
+	if isJob(podName) {
+		err := c.updateJob(ctx, namespace, podName, func(job *batchv1.Job) {
+			job.Labels[QuarantineLabel] = "true"
+			job.Spec.TTLSecondsAfterFinished = nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, p := range pods {
+		err := c.updatePodLabels(ctx, namespace, p.Name, func(labels map[string]string) {
+			labels[QuarantineLabel] = "true"
+			labels[NetworkProfileLabel] = "deny-all"
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleasePod undoes QuarantinePod: the workload gets networkProfile back and
+// the quarantine NetworkPolicy is deleted. A missing workload is not an
+// error. A Job's ttlSecondsAfterFinished is not restored; the runner's
+// garbage collector removes it instead.
+func (c *Client) ReleasePod(ctx context.Context, namespace, podName, networkProfile string) error {
+	var names []string
+	if isJob(podName) {
+		err := c.updateJob(ctx, namespace, podName, func(job *batchv1.Job) {
+			delete(job.Labels, QuarantineLabel)
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		list, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + podName})
+		if err != nil {
+			return err
+		}
+		for _, p := range list.Items {
+			names = append(names, p.Name)
+		}
+	} else {
+		names = []string{podName}
+	}
+	for _, name := range names {
+		err := c.updatePodLabels(ctx, namespace, name, func(labels map[string]string) {
+			delete(labels, QuarantineLabel)
+			labels[NetworkProfileLabel] = networkProfile
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	err := c.clientset.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, quarantinePolicyName(podName), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("delete quarantine network policy: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) updatePodLabels(ctx context.Context, namespace, name string, mutate func(map[string]string)) error {
+	pods := c.clientset.CoreV1().Pods(namespace)
+	pod, err := pods.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if pod.Labels == nil {
+		pod.Labels = map[string]string{}
+	}
+	mutate(pod.Labels)
+	_, err = pods.Update(ctx, pod, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *Client) updateJob(ctx context.Context, namespace, name string, mutate func(*batchv1.Job)) error {
+	jobs := c.clientset.BatchV1().Jobs(namespace)
+	job, err := jobs.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if job.Labels == nil {
+		job.Labels = map[string]string{}
+	}
+	mutate(job)
+	_, err = jobs.Update(ctx, job, metav1.UpdateOptions{})
+	return err
+}
+
+// CollectEvidence captures the workload object, its newest pod for a job
+// run, container statuses, events and up to 1 MiB of logs. It fails only if
+// the workload itself cannot be read.
+func (c *Client) CollectEvidence(ctx context.Context, namespace, podName string) (Evidence, error) {
+	ev := Evidence{CollectedAt: time.Now().UTC(), Events: []Event{}}
+	fail := func(what string, err error) {
+		ev.Errors = append(ev.Errors, fmt.Sprintf("%s: %v", what, err))
+	}
+	var pod *corev1.Pod
+	names := []string{podName}
+	if isJob(podName) {
+		job, err := c.clientset.BatchV1().Jobs(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return Evidence{}, err
+		}
+		job.ManagedFields = nil
+		ev.Object, _ = json.Marshal(job)
+		if pod, err = c.jobPod(ctx, namespace, podName); err != nil {
+			fail("job pod", err)
+		} else if pod != nil {
+			pod.ManagedFields = nil
+			ev.Pod, _ = json.Marshal(pod)
+			names = append(names, pod.Name)
+		}
+	} else {
+		var err error
+		if pod, err = c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{}); err != nil {
+			return Evidence{}, err
+		}
+		pod.ManagedFields = nil
+		ev.Object, _ = json.Marshal(pod)
+	}
+
+	if pod != nil {
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		ev.ContainerStatuses, _ = json.Marshal(statuses)
+		limit := int64(maxEvidenceLogBytes)
+		stream, err := c.clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{LimitBytes: &limit, Timestamps: true}).Stream(ctx)
+		if err != nil {
+			fail("logs", err)
+		} else {
+			raw, err := io.ReadAll(stream)
+			stream.Close()
+			if err != nil {
+				fail("logs", err)
+			}
+			ev.Logs = string(raw)
+		}
+	}
+	if events, err := c.events(ctx, namespace, names...); err != nil {
+		fail("events", err)
+	} else {
+		ev.Events = events
+	}
+	return ev, nil
+}
@@ -0,0 +1,234 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodSecurityEnforceLabel sets a namespace's Pod Security Admission level.
+const PodSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+
+// podSecurityLevels are the Pod Security Admission levels, least restrictive
+// first.
+var podSecurityLevels = []string{"privileged", "baseline", "restricted"}
+
+// Check is the outcome of one cluster prerequisite check.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+const (
+	CheckPass = "pass"
+	CheckFail = "fail"
+)
+
+// Prerequisites is the cluster state the runner's isolation guarantees rest
+// on: the sandbox RuntimeClass (G1), default-deny egress in the runs
+// namespace (G2) and Pod Security Admission enforcing the pod hardening (G5).
+type Prerequisites struct {
+	RuntimeClass string
+	Namespace    string
+	// SecretsNamespace is checked for read access when secrets are brokered
+	// from Kubernetes; empty otherwise.
+	SecretsNamespace string
+	// PodSecurityLevel is the least restrictive enforce level accepted.
+	PodSecurityLevel string
+}
+
+// Access is one permission, checked with a SelfSubjectAccessReview. An
+// empty Namespace is cluster-wide.
+type Access struct {
+	Namespace   string
+	Group       string
+	Resource    string
+	Subresource string
+	Verb        string
+	Name        string
+}
+
+func (a Access) String() string {
+	res := a.Resource
+	if a.Subresource != "" {
+		res += "/" + a.Subresource
+	}
+	if a.Group != "" {
+		res += "." + a.Group
+	}
+	if a.Name != "" {
+		res += "/" + a.Name
+	}
+	where := "cluster"
+	if a.Namespace != "" {
+		where = a.Namespace
+	}
+	return fmt.Sprintf("%s %s in %s", a.Verb, res, where)
+}
+
+// RequiredAccess is what infra/k8s/runner/01-rbac.yaml grants the runner.
+// Keep the two in sync.
+func RequiredAccess(p Prerequisites) []Access {
+	var out []Access
+	add := func(ns, group, resource, sub string, verbs ...string) {
+		for _, v := range verbs {
+			out = append(out, Access{Namespace: ns, Group: group, Resource: resource, Subresource: sub, Verb: v})
+		}
+	}
+	add(p.Namespace, "", "pods", "", "create", "get", "list", "watch", "update", "delete", "deletecollection")
+	add(p.Namespace, "", "pods", "log", "get")
+	add(p.Namespace, "batch", "jobs", "", "create", "get", "list", "update", "delete")
+	add(p.Namespace, "networking.k8s.io", "networkpolicies", "", "create", "list", "delete")
+	add(p.Namespace, "", "events", "", "list")
+	add(p.Namespace, "", "secrets", "", "create", "get", "update", "delete")
+	if p.SecretsNamespace != "" {
+		add(p.SecretsNamespace, "", "secrets", "", "get")
+	}
+	out = append(out,
+		Access{Group: "node.k8s.io", Resource: "runtimeclasses", Verb: "get", Name: p.RuntimeClass},
+		Access{Resource: "namespaces", Verb: "get", Name: p.Namespace},
+	)
+	return out
+}
+
+// ExcessAccess lists permissions the runner must not hold: a compromised
+// runner with any of them could read secrets it does not broker, exec into
+// run pods or grant itself more.
+func ExcessAccess(p Prerequisites) []Access {
+	return []Access{
+		{Namespace: p.Namespace, Resource: "pods", Subresource: "exec", Verb: "create"},
+		{Namespace: p.Namespace, Resource: "secrets", Verb: "list"},
+		{Namespace: p.Namespace, Group: "rbac.authorization.k8s.io", Resource: "rolebindings", Verb: "create"},
+		{Resource: "secrets", Verb: "list"},
+	}
+}
+
+// CheckPrerequisites verifies the cluster state in p and the runner's RBAC.
+// A check that cannot read what it needs fails with the API error.
+func (c *Client) CheckPrerequisites(ctx context.Context, p Prerequisites) []Check {
+	return []Check{
+		c.checkRuntimeClass(ctx, p.RuntimeClass),
+		c.checkDefaultDenyEgress(ctx, p.Namespace),
+		c.checkPodSecurity(ctx, p.Namespace, p.PodSecurityLevel),
+		c.checkRBAC(ctx, p),
+	}
+}
+
+func (c *Client) checkRuntimeClass(ctx context.Context, name string) Check {
+	check := Check{Name: "runtime_class", Status: CheckFail}
+	rc, err := c.clientset.NodeV1().RuntimeClasses().Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		check.Detail = fmt.Sprintf("RuntimeClass %q not found", name)
+	case err != nil:
+		check.Detail = err.Error()
+	default:
+		check.Status, check.Detail = CheckPass, fmt.Sprintf("RuntimeClass %q uses handler %q", name, rc.Handler)
+	}
+	return check
+}
+
+// checkDefaultDenyEgress looks for a policy selecting every pod in the
+// namespace with Egress in its types and no egress rules; the per-profile
+// policies then only add allowances to it.
+func (c *Client) checkDefaultDenyEgress(ctx context.Context, namespace string) Check {
+	check := Check{Name: "default_deny_egress", Status: CheckFail}
+	list, err := c.clientset.NetworkingV1().NetworkPolicies(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	for _, np := range list.Items {
+		sel := np.Spec.PodSelector
+		if len(sel.MatchLabels) == 0 && len(sel.MatchExpressions) == 0 &&
+			slices.Contains(np.Spec.PolicyTypes, networkingv1.PolicyTypeEgress) && len(np.Spec.Egress) == 0 {
+			check.Status, check.Detail = CheckPass, fmt.Sprintf("NetworkPolicy %q", np.Name)
+			return check
+		}
+	}
+	check.Detail = fmt.Sprintf("no NetworkPolicy in %q denies egress for all pods", namespace)
+	return check
+}
+
+func (c *Client) checkPodSecurity(ctx context.Context, namespace, minLevel string) Check {
+	check := Check{Name: "pod_security", Status: CheckFail}
+	ns, err := c.clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	level, ok := ns.Labels[PodSecurityEnforceLabel]
+	switch {
+	case !ok:
+		check.Detail = fmt.Sprintf("namespace %q has no %s label", namespace, PodSecurityEnforceLabel)
+	case slices.Index(podSecurityLevels, level) < slices.Index(podSecurityLevels, minLevel):
+		check.Detail = fmt.Sprintf("namespace %q enforces %q, want at least %q", namespace, level, minLevel)
+	default:
+		check.Status, check.Detail = CheckPass, fmt.Sprintf("namespace %q enforces %q", namespace, level)
+	}
+	return check
+}
+
+// checkRBAC compares the runner's own permissions with RequiredAccess and
+// ExcessAccess.
+func (c *Client) checkRBAC(ctx context.Context, p Prerequisites) Check {
+	check := Check{Name: "rbac", Status: CheckFail}
+	var missing, excess []string
+	for _, a := range RequiredAccess(p) {
+		ok, err := c.allowed(ctx, a)
+		if err != nil {
+			check.Detail = err.Error()
+			return check
+		}
+		if !ok {
+			missing = append(missing, a.String())
+		}
+	}
+	for _, a := range ExcessAccess(p) {
+		ok, err := c.allowed(ctx, a)
+		if err != nil {
+			check.Detail = err.Error()
+			return check
+		}
+		if ok {
+			excess = append(excess, a.String())
+		}
+	}
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "missing: "+strings.Join(missing, ", "))
+	}
+	if len(excess) > 0 {
+		problems = append(problems, "excess: "+strings.Join(excess, ", "))
+	}
+	if len(problems) > 0 {
+		check.Detail = strings.Join(problems, "; ")
+		return check
+	}
+	check.Status = CheckPass
+	return check
+}
+
+func (c *Client) allowed(ctx context.Context, a Access) (bool, error) {
+	review, err := c.clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &authorizationv1.ResourceAttributes{
+			Namespace:   a.Namespace,
+			Verb:        a.Verb,
+			Group:       a.Group,
+			Resource:    a.Resource,
+			Subresource: a.Subresource,
+			Name:        a.Name,
+		}},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("access review for %s: %w", a, err)
+	}
+	return review.Status.Allowed, nil
+}
@@ -0,0 +1,268 @@
+package policy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	PredicateSLSAProvenanceV02 = "https://slsa.dev/provenance/v0.2"
+	PredicateSLSAProvenanceV1  = "https://slsa.dev/provenance/v1"
+	PredicateCycloneDX         = "https://cyclonedx.org/bom"
+	PredicateSPDX              = "https://spdx.dev/Document"
+)
+
+// cosign --type names tried in order for each requirement.
+var (
+	provenanceTypes = []string{"slsaprovenance1", "slsaprovenance"}
+	sbomTypes       = []string{"cyclonedx", "spdxjson"}
+)
+
+type AttestationEvidence struct {
+	PredicateType string `json:"predicate_type"`
+	Digest        string `json:"digest"`
+	BuilderID     string `json:"builder_id,omitempty"`
+	SourceRepo    string `json:"source_repo,omitempty"`
+}
+
+type inTotoStatement struct {
+	Type          string `json:"_type"`
+	PredicateType string `json:"predicateType"`
+	Subject       []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+	Predicate json.RawMessage `json:"predicate"`
+}
+
+type dsseEnvelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+}
+
+type slsaPredicate struct {
+	Builder struct {
+		ID string `json:"id"`
+	} `json:"builder"`
+	Invocation struct {
+		ConfigSource struct {
+			URI string `json:"uri"`
+		} `json:"configSource"`
+	} `json:"invocation"`
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+	} `json:"runDetails"`
+	BuildDefinition struct {
+		ExternalParameters struct {
+			Workflow struct {
+				Repository string `json:"repository"`
+			} `json:"workflow"`
+		} `json:"externalParameters"`
+		ResolvedDependencies []struct {
+			URI string `json:"uri"`
+		} `json:"resolvedDependencies"`
+	} `json:"buildDefinition"`
+}
+
+// checkAttestations enforces the configured provenance and SBOM requirements
+// for a pinned image. Attestations are verified by cosign against the same
+// trust config as the image signature.
+func (e *Enforcer) checkAttestations(cfg Config, roots []TrustRoot, pinnedRef, digest string) ([]AttestationEvidence, error) {
+	evidence := []AttestationEvidence{}
+	if cfg.RequireProvenance {
+		stmts, err := e.verifiedStatements(cfg, roots, pinnedRef, digest, provenanceTypes)
+		if err != nil {
+			return evidence, &stageFailure{reason: "attestation_missing", err: fmt.Errorf("slsa provenance: %w", err)}
+		}
+		var lastErr error
+		matched := false
+		for _, st := range stmts {
+			ev, err := provenanceEvidence(cfg, st)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			evidence = append(evidence, ev)
+			matched = true
+			break
+		}
+		if !matched {
+			return evidence, &stageFailure{reason: "provenance_untrusted", err: lastErr}
+		}
+	}
+	if cfg.RequireSBOM {
+		stmts, err := e.verifiedStatements(cfg, roots, pinnedRef, digest, sbomTypes)
+		if err != nil {
+			return evidence, &stageFailure{reason: "attestation_missing", err: fmt.Errorf("sbom: %w", err)}
+		}
+		evidence = append(evidence, AttestationEvidence{PredicateType: stmts[0].PredicateType, Digest: stmts[0].digest})
+	}
+	return evidence, nil
+}
+
+type verifiedStatement struct {
+	inTotoStatement
+	digest string
+}
+
+// verifiedStatements fetches attestations signed by any of roots. When the
+// image signature was verified, roots is narrowed to the root that did so.
+func (e *Enforcer) verifiedStatements(cfg Config, roots []TrustRoot, pinnedRef, digest string, cosignTypes []string) ([]verifiedStatement, error) {
+	if len(roots) == 0 {
+		return nil, errors.New("no trust root is valid for this image")
+	}
+	var lastErr error
+	for _, typ := range cosignTypes {
+		v, _, err := e.cache.Do(trustHash(cfg), "attestation:"+typ+":"+pinnedRef+"|"+rootNames(roots), e.cache.ttl > 0, func() (verification, error) {
+			errs := []string{}
+			for _, root := range roots {
+				payloads, err := fetchAttestations(root, pinnedRef, typ)
+				if err == nil {
+					return verification{Digest: digest, Root: root.Name, Payloads: payloads, VerifiedAt: time.Now().UTC()}, nil
+				}
+				errs = append(errs, root.Name+": "+err.Error())
+			}
+			return verification{}, errors.New(strings.Join(errs, "; "))
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		stmts, err := parseStatements(v.Payloads, digest)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return stmts, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no attestation types configured")
+	}
+	return nil, lastErr
+}
+
+func fetchAttestations(root TrustRoot, pinnedRef, cosignType string) ([][]byte, error) {
+	args := append([]string{"verify-attestation", pinnedRef, "--type", cosignType, "--output", "json"}, root.cosignArgs()...)
+	var stderr bytes.Buffer
+	cmd := exec.Command("cosign", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cosign verify-attestation --type %s failed: %v: %s", cosignType, err, strings.TrimSpace(stderr.String()))
+	}
+
+	payloads := [][]byte{}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var env dsseEnvelope
+		if err := json.Unmarshal(line, &env); err != nil {
+			return nil, fmt.Errorf("parse attestation envelope: %w", err)
+		}
+		payload, err := base64.StdEncoding.DecodeString(env.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("decode attestation payload: %w", err)
+		}
+		payloads = append(payloads, payload)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read cosign output: %w", err)
+	}
+	if len(payloads) == 0 {
+		return nil, fmt.Errorf("no %s attestations returned by cosign", cosignType)
+	}
+	return payloads, nil
+}
+
+// parseStatements keeps only statements whose subject is the admitted digest,
+// so an attestation copied from another image cannot satisfy the policy.
+func parseStatements(payloads [][]byte, digest string) ([]verifiedStatement, error) {
+	want := strings.TrimPrefix(digest, "sha256:")
+	stmts := []verifiedStatement{}
+	for _, p := range payloads {
+		var st inTotoStatement
+		if err := json.Unmarshal(p, &st); err != nil {
+			return nil, fmt.Errorf("parse in-toto statement: %w", err)
+		}
+		for _, sub := range st.Subject {
+			if sub.Digest["sha256"] == want {
+				sum := sha256.Sum256(p)
+				stmts = append(stmts, verifiedStatement{inTotoStatement: st, digest: "sha256:" + hex.EncodeToString(sum[:])})
+				break
+			}
+		}
+	}
+	if len(stmts) == 0 {
+		return nil, fmt.Errorf("no attestation subject matches %s", digest)
+	}
+	return stmts, nil
+}
+
+func provenanceEvidence(cfg Config, st verifiedStatement) (AttestationEvidence, error) {
+	ev := AttestationEvidence{PredicateType: st.PredicateType, Digest: st.digest}
+	var pred slsaPredicate
+	if err := json.Unmarshal(st.Predicate, &pred); err != nil {
+		return ev, fmt.Errorf("parse slsa predicate: %w", err)
+	}
+	switch st.PredicateType {
+	case PredicateSLSAProvenanceV1:
+		ev.BuilderID = pred.RunDetails.Builder.ID
+		ev.SourceRepo = pred.BuildDefinition.ExternalParameters.Workflow.Repository
+		if ev.SourceRepo == "" && len(pred.BuildDefinition.ResolvedDependencies) > 0 {
+			ev.SourceRepo = pred.BuildDefinition.ResolvedDependencies[0].URI
+		}
+	case PredicateSLSAProvenanceV02:
+		ev.BuilderID = pred.Builder.ID
+		ev.SourceRepo = pred.Invocation.ConfigSource.URI
+	default:
+		return ev, fmt.Errorf("unsupported provenance predicate %s", st.PredicateType)
+	}
+	ev.SourceRepo = normalizeSourceRepo(ev.SourceRepo)
+
+	if len(cfg.AllowedBuilderIDs) > 0 && !matchesAny(ev.BuilderID, cfg.AllowedBuilderIDs) {
+		return ev, fmt.Errorf("builder %q is not allowed", ev.BuilderID)
+	}
+	if len(cfg.AllowedSourceRepos) > 0 && !matchesAny(ev.SourceRepo, cfg.AllowedSourceRepos) {
+		return ev, fmt.Errorf("source repo %q is not allowed", ev.SourceRepo)
+	}
+	return ev, nil
+}
+
+// normalizeSourceRepo turns "git+https://github.com/org/repo@refs/heads/main"
+// into "https://github.com/org/repo".
+func normalizeSourceRepo(uri string) string {
+	uri = strings.TrimPrefix(uri, "git+")
+	if at := strings.LastIndex(uri, "@"); at > strings.Index(uri, "://")+3 {
+		uri = uri[:at]
+	}
+	return strings.TrimSuffix(uri, ".git")
+}
+
+// matchesAny supports exact entries and trailing-"*" prefix entries.
+func matchesAny(v string, patterns []string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(v, prefix) {
+				return true
+			}
+		} else if v == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+func statementJSON(t *testing.T, predicateType, subjectDigest string, predicate map[string]any) []byte {
+	t.Helper()
+	b, err := json.Marshal(map[string]any{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"predicateType": predicateType,
+		"subject":       []map[string]any{{"name": "ghcr.io/org/mcp", "digest": map[string]string{"sha256": strings.TrimPrefix(subjectDigest, "sha256:")}}},
+		"predicate":     predicate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseStatementsRequiresMatchingSubject(t *testing.T) {
+	other := "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	if _, err := parseStatements([][]byte{statementJSON(t, PredicateSPDX, other, nil)}, testDigest); err == nil {
+		t.Fatalf("expected statement for a different digest to be rejected")
+	}
+	stmts, err := parseStatements([][]byte{statementJSON(t, PredicateSPDX, testDigest, nil)}, testDigest)
+	if err != nil || len(stmts) != 1 || !strings.HasPrefix(stmts[0].digest, "sha256:") {
+		t.Fatalf("unexpected result %+v %v", stmts, err)
+	}
+}
+
+func TestProvenanceEvidence(t *testing.T) {
+	v1 := statementJSON(t, PredicateSLSAProvenanceV1, testDigest, map[string]any{
+		"runDetails":      map[string]any{"builder": map[string]any{"id": "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_container_slsa3.yml@refs/tags/v2.0.0"}},
+		"buildDefinition": map[string]any{"externalParameters": map[string]any{"workflow": map[string]any{"repository": "https://github.com/our-org/mcp-echo"}}},
+	})
+	v02 := statementJSON(t, PredicateSLSAProvenanceV02, testDigest, map[string]any{
+		"builder":    map[string]any{"id": "https://example.com/builder"},
+		"invocation": map[string]any{"configSource": map[string]any{"uri": "git+https://github.com/evil/repo@refs/heads/main"}},
+	})
+	cfg := Config{
+		AllowedBuilderIDs:  []string{"https://github.com/slsa-framework/slsa-github-generator/*"},
+		AllowedSourceRepos: []string{"https://github.com/our-org/*"},
+	}
+
+	stmts, err := parseStatements([][]byte{v1, v02}, testDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, err := provenanceEvidence(cfg, stmts[0])
+	if err != nil || ev.SourceRepo != "https://github.com/our-org/mcp-echo" {
+		t.Fatalf("v1 provenance: %+v %v", ev, err)
+	}
+	ev, err = provenanceEvidence(cfg, stmts[1])
+	if err == nil {
+		t.Fatalf("expected untrusted builder to be rejected")
+	}
+	if ev.SourceRepo != "https://github.com/evil/repo" {
+		t.Fatalf("source repo not normalized: %q", ev.SourceRepo)
+	}
+}
@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	CacheHit       = "hit"
+	CacheMiss      = "miss"
+	CacheCoalesced = "coalesced"
+)
+
+type verification struct {
+	Digest     string
+	Identity   string
+	Root       string
+	Payloads   [][]byte
+	VerifiedAt time.Time
+}
+
+type cacheEntry struct {
+	key     string
+	value   verification
+	expires time.Time
+}
+
+type inflightVerify struct {
+	done  chan struct{}
+	value verification
+	err   error
+}
+
+// VerifyCache memoizes successful signature verifications per digest-pinned
+// image and trust configuration. Failures are never cached. Concurrent
+// verifications of the same image share a single cosign invocation.
+type VerifyCache struct {
+	ttl time.Duration
+	max int
+	now func() time.Time
+	// joined, when set, is called as a caller joins an in-flight
+	// verification; tests use it to know every caller is waiting.
+	joined func()
+
+	mu        sync.Mutex
+	trustHash string
+	entries   map[string]*list.Element
+	lru       *list.List
+	inflight  map[string]*inflightVerify
+}
+
+func NewVerifyCache(ttl time.Duration, maxEntries int) *VerifyCache {
+	return &VerifyCache{
+		ttl:      ttl,
+		max:      maxEntries,
+		now:      time.Now,
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+		inflight: map[string]*inflightVerify{},
+	}
+}
+
+// Do returns the cached verification for key when cacheable, otherwise runs
+// fn (or joins an in-flight run of it). A trust hash different from the one
+// the cache was filled under drops every entry.
+func (c *VerifyCache) Do(trustHash, key string, cacheable bool, fn func() (verification, error)) (verification, string, error) {
+	if c == nil {
+		v, err := fn()
+		return v, "", err
+	}
+	c.mu.Lock()
+	if trustHash != c.trustHash {
+		c.entries = map[string]*list.Element{}
+		c.lru.Init()
+		c.trustHash = trustHash
+	}
+	if cacheable {
+		if el, ok := c.entries[key]; ok {
+			ent := el.Value.(*cacheEntry)
+			if c.now().Before(ent.expires) {
+				c.lru.MoveToFront(el)
+				c.mu.Unlock()
+				return ent.value, CacheHit, nil
+			}
+			c.lru.Remove(el)
+			delete(c.entries, key)
+		}
+	}
+	flightKey := trustHash + "|" + key
+	if call, ok := c.inflight[flightKey]; ok {
+		c.mu.Unlock()
+		if c.joined != nil {
+			c.joined()
+		}
+		<-call.done
+		return call.value, CacheCoalesced, call.err
+	}
+	call := &inflightVerify{done: make(chan struct{})}
+	c.inflight[flightKey] = call
+	c.mu.Unlock()
+
+	call.value, call.err = fn()
+
+	c.mu.Lock()
+	delete(c.inflight, flightKey)
+	if call.err == nil && cacheable && trustHash == c.trustHash {
+		c.store(key, call.value)
+	}
+	c.mu.Unlock()
+	close(call.done)
+	return call.value, CacheMiss, call.err
+}
+
+func (c *VerifyCache) store(key string, v verification) {
+	if el, ok := c.entries[key]; ok {
+		c.lru.Remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, value: v, expires: c.now().Add(c.ttl)})
+	for c.max > 0 && c.lru.Len() > c.max {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *VerifyCache) Purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+}
+
+func (c *VerifyCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
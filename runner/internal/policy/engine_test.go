@@ -0,0 +1,71 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSamplePolicies(t *testing.T) {
+	results, err := RunTests(filepath.Join("..", "..", "policies"))
+	if err != nil {
+		t.Fatalf("run policy tests: %v", err)
+	}
+	for _, r := range results {
+		if !r.Passed {
+			t.Errorf("%s: %s: %s", r.Suite, r.Case, r.Detail)
+		}
+	}
+}
+
+func TestEngineReload(t *testing.T) {
+	dir := t.TempDir()
+	writeRules(t, dir, `rules: [{name: tagged, expression: 'image.tag != ""'}]`)
+
+	e, err := NewEngine(dir)
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	in := Input{Image: ImageInput{Tag: ""}}
+	if d := e.Evaluate(in); d.Allowed {
+		t.Fatalf("expected untagged image to be denied")
+	}
+
+	writeRules(t, dir, `rules: [{name: tagged, expression: 'true'}]`)
+	changed, err := e.Reload()
+	if err != nil || !changed {
+		t.Fatalf("reload: changed=%t err=%v", changed, err)
+	}
+	if d := e.Evaluate(in); !d.Allowed {
+		t.Fatalf("expected reloaded rules to allow, got %+v", d.Results)
+	}
+
+	hash := e.SetHash()
+	writeRules(t, dir, `rules: [{name: broken, expression: 'image.tag +'}]`)
+	if _, err := e.Reload(); err == nil {
+		t.Fatalf("expected compile error")
+	}
+	if e.SetHash() != hash {
+		t.Fatalf("failed reload must keep the previous rule set")
+	}
+}
+
+func TestEngineFailsClosedOnEvaluationError(t *testing.T) {
+	dir := t.TempDir()
+	writeRules(t, dir, `rules: [{name: needs-field, expression: 'request.missing == "x"'}]`)
+	e, err := NewEngine(dir)
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	d := e.Evaluate(Input{Request: map[string]any{}})
+	if d.Allowed || len(d.Results) != 1 || d.Results[0].Allowed {
+		t.Fatalf("expected evaluation error to deny, got %+v", d)
+	}
+}
+
+func writeRules(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "rules.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,188 @@
+package policy
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// deniedEnvNames can change how the runtime, loader or network stack of the
+// MCP server behaves, so callers may never set them.
+var deniedEnvNames = []string{
+	"PATH", "HOME", "SHELL", "BASH_ENV", "ENV", "IFS",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "ALL_PROXY", "FTP_PROXY",
+	"PYTHONPATH", "PYTHONSTARTUP", "PYTHONHOME", "NODE_OPTIONS", "NODE_PATH",
+	"JAVA_TOOL_OPTIONS", "_JAVA_OPTIONS", "JDK_JAVA_OPTIONS",
+	"PERL5OPT", "PERL5LIB", "RUBYOPT", "RUBYLIB", "GODEBUG",
+	"SSL_CERT_FILE", "SSL_CERT_DIR", "REQUESTS_CA_BUNDLE", "NODE_EXTRA_CA_CERTS",
+}
+
+var deniedEnvPrefixes = []string{"LD_", "DYLD_", "KUBERNETES_", "GCONV_"}
+
+// secretEnvNameWords and secretEnvNamePhrases mark names of values the env
+// allowlist is documented never to carry. Words match whole "_" segments so
+// MAX_TOKENS is not mistaken for a token.
+var (
+	secretEnvNameWords   = []string{"SECRET", "PASSWORD", "PASSWD", "TOKEN", "APIKEY", "CREDENTIAL", "CREDENTIALS"}
+	secretEnvNamePhrases = []string{"API_KEY", "PRIVATE_KEY", "ACCESS_KEY", "CLIENT_SECRET"}
+)
+
+var (
+	envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	secretPatterns = []struct {
+		rule string
+		re   *regexp.Regexp
+	}{
+		{"private_key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+		{"aws_access_key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+		{"github_token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+		{"slack_token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+		{"google_api_key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+		{"stripe_key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{16,}`)},
+		{"api_secret_key", regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)},
+		{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]*`)},
+		{"url_credentials", regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`)},
+	}
+	tokenPattern = regexp.MustCompile(`^[A-Za-z0-9+/=_\-.]{20,}$`)
+)
+
+type EnvLimits struct {
+	MaxVars       int64
+	MaxValueBytes int64
+	MaxTotalBytes int64
+	Denylist      []string
+	ScanSecrets   bool
+}
+
+// EnvFinding names a rejected variable and the rule it broke. Values are
+// never recorded.
+type EnvFinding struct {
+	Name string `json:"name"`
+	Rule string `json:"rule"`
+}
+
+type EnvEvidence struct {
+	Count      int          `json:"count"`
+	TotalBytes int          `json:"total_bytes"`
+	Rejected   []EnvFinding `json:"rejected,omitempty"`
+}
+
+// CheckEnv validates the caller's env allowlist. The error, like the
+// evidence, mentions names and rules only.
+func CheckEnv(env map[string]string, limits EnvLimits) (*EnvEvidence, error) {
+	ev := &EnvEvidence{Count: len(env)}
+	reject := func(name, rule string) {
+		ev.Rejected = append(ev.Rejected, EnvFinding{Name: name, Rule: rule})
+	}
+	if limits.MaxVars > 0 && int64(len(env)) > limits.MaxVars {
+		reject("*", fmt.Sprintf("too_many_vars (max %d)", limits.MaxVars))
+	}
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := env[name]
+		ev.TotalBytes += len(name) + len(value)
+		if rule := envNameRule(name, limits.Denylist); rule != "" {
+			reject(name, rule)
+			continue
+		}
+		if limits.MaxValueBytes > 0 && int64(len(value)) > limits.MaxValueBytes {
+			reject(name, fmt.Sprintf("value_too_large (max %d bytes)", limits.MaxValueBytes))
+			continue
+		}
+		if limits.ScanSecrets {
+			if rule := secretRule(value); rule != "" {
+				reject(name, "likely_secret:"+rule)
+			}
+		}
+	}
+	if limits.MaxTotalBytes > 0 && int64(ev.TotalBytes) > limits.MaxTotalBytes {
+		reject("*", fmt.Sprintf("total_too_large (max %d bytes)", limits.MaxTotalBytes))
+	}
+
+	if len(ev.Rejected) == 0 {
+		return ev, nil
+	}
+	msgs := make([]string, len(ev.Rejected))
+	for i, f := range ev.Rejected {
+		msgs[i] = f.Name + ": " + f.Rule
+	}
+	return ev, fmt.Errorf("env_allowlist rejected: %s", strings.Join(msgs, "; "))
+}
+
+func envNameRule(name string, extra []string) string {
+	if !envNamePattern.MatchString(name) {
+		return "invalid_name"
+	}
+	upper := strings.ToUpper(name)
+	for _, d := range append(deniedEnvNames, extra...) {
+		if upper == strings.ToUpper(d) {
+			return "denied_name"
+		}
+	}
+	for _, p := range deniedEnvPrefixes {
+		if strings.HasPrefix(upper, p) {
+			return "denied_prefix"
+		}
+	}
+	for _, seg := range strings.Split(upper, "_") {
+		if slices.Contains(secretEnvNameWords, seg) {
+			return "secret_name"
+		}
+	}
+	for _, phrase := range secretEnvNamePhrases {
+		if strings.Contains(upper, phrase) {
+			return "secret_name"
+		}
+	}
+	return ""
+}
+
+func secretRule(value string) string {
+	for _, p := range secretPatterns {
+		if p.re.MatchString(value) {
+			return p.rule
+		}
+	}
+	// Long unbroken tokens with near-random character distribution look like
+	// generated credentials rather than configuration.
+	for _, word := range strings.Fields(value) {
+		if tokenPattern.MatchString(word) && shannonEntropy(word) >= highEntropyThreshold(word) {
+			return "high_entropy"
+		}
+	}
+	return ""
+}
+
+// highEntropyThreshold is lower for hex, whose alphabet caps entropy at 4
+// bits per character.
+func highEntropyThreshold(s string) float64 {
+	if strings.Trim(strings.ToLower(s), "0123456789abcdef") == "" {
+		if len(s) >= 32 {
+			return 3.0
+		}
+		return math.Inf(1)
+	}
+	return 4.0
+}
+
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	var h float64
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// WaivableStages are the checks a break-glass exception may waive. Image
+// allow/deny rules and digest pinning are never waivable.
+var WaivableStages = []string{"signature", "image_config", "attestations", "vulnerabilities"}
+
+type Exception struct {
+	ID         string     `json:"id"`
+	Digest     string     `json:"digest,omitempty"`
+	Repository string     `json:"repository,omitempty"`
+	Principal  string     `json:"principal"`
+	Waive      []string   `json:"waive"`
+	Reason     string     `json:"reason"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy  string     `json:"revoked_by,omitempty"`
+}
+
+type ExceptionSource interface {
+	Exceptions() []Exception
+}
+
+type ExceptionEvidence struct {
+	ID        string `json:"id"`
+	Check     string `json:"check"`
+	Reason    string `json:"reason"`
+	CreatedBy string `json:"created_by"`
+	ExpiresAt string `json:"expires_at"`
+	Waived    string `json:"waived_failure"`
+}
+
+func (x Exception) Active(now time.Time) bool {
+	return x.RevokedAt == nil && now.Before(x.ExpiresAt)
+}
+
+// anonymousPrincipal is the identity of a caller with neither a client
+// certificate nor a header; an exception for it would cover everyone.
+const anonymousPrincipal = "anonymous"
+
+// Covers reports whether x waives stage for the image and principal. The
+// principal must be authenticated; an empty one is never covered.
+func (x Exception) Covers(imageName, digest, principal, stage string, now time.Time) bool {
+	if principal == "" || !x.Active(now) || x.Principal != principal || !slices.Contains(x.Waive, stage) {
+		return false
+	}
+	if x.Digest != "" {
+		return digest != "" && x.Digest == digest
+	}
+	rules, err := ParseImageRules([]string{x.Repository})
+	if err != nil || len(rules) == 0 {
+		return false
+	}
+	_, ok := matchRule(imageName, rules)
+	return ok
+}
+
+func (x Exception) Validate() error {
+	if (x.Digest == "") == (x.Repository == "") {
+		return fmt.Errorf("exactly one of digest or repository is required")
+	}
+	if x.Digest != "" && !digestPattern.MatchString(x.Digest) {
+		return fmt.Errorf("digest must be sha256:<64 hex>")
+	}
+	if x.Repository != "" {
+		if _, err := ParseImageRules([]string{x.Repository}); err != nil {
+			return err
+		}
+	}
+	if x.Principal == "" {
+		return fmt.Errorf("principal is required")
+	}
+	if x.Principal == anonymousPrincipal {
+		return fmt.Errorf("principal must be an mTLS client identity")
+	}
+	if x.Reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+	if len(x.Waive) == 0 {
+		return fmt.Errorf("waive must name at least one check")
+	}
+	for _, w := range x.Waive {
+		if !slices.Contains(WaivableStages, w) {
+			return fmt.Errorf("check %q cannot be waived (waivable: %v)", w, WaivableStages)
+		}
+	}
+	return nil
+}
+
+// waive looks for an active exception covering a failed stage. When one
+// applies the stage is recorded as waived instead of failed.
+func (e *Enforcer) waive(res *Result, req Request, ref Reference, digest, stage string, cause error) bool {
+	if e.exceptions == nil {
+		return false
+	}
+	now := time.Now().UTC()
+	for _, x := range e.exceptions.Exceptions() {
+		if !x.Covers(ref.Name(), digest, req.Principal, stage, now) {
+			continue
+		}
+		res.Evidence.ExceptionsUsed = append(res.Evidence.ExceptionsUsed, ExceptionEvidence{
+			ID:        x.ID,
+			Check:     stage,
+			Reason:    x.Reason,
+			CreatedBy: x.CreatedBy,
+			ExpiresAt: x.ExpiresAt.Format(time.RFC3339),
+			Waived:    cause.Error(),
+		})
+		res.record(stage, CheckWaived, fmt.Sprintf("waived by exception %s (expires %s): %v", x.ID, x.ExpiresAt.Format(time.RFC3339), cause))
+		return true
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mcp-orc/runner/internal/registry"
+)
+
+type staticExceptions []Exception
+
+func (s staticExceptions) Exceptions() []Exception { return s }
+
+func TestExceptionCovers(t *testing.T) {
+	now := time.Now()
+	x := Exception{Repository: "ghcr.io/org", Principal: "svc", Waive: []string{"vulnerabilities"}, ExpiresAt: now.Add(time.Hour)}
+	if !x.Covers("ghcr.io/org/mcp", testDigest, "svc", "vulnerabilities", now) {
+		t.Fatalf("expected repository exception to cover image")
+	}
+	if x.Covers("ghcr.io/org-evil/mcp", testDigest, "svc", "vulnerabilities", now) {
+		t.Fatalf("repository scope must match path segments")
+	}
+	if x.Covers("ghcr.io/org/mcp", testDigest, "other", "vulnerabilities", now) {
+		t.Fatalf("exception must be bound to its principal")
+	}
+	if x.Covers("ghcr.io/org/mcp", testDigest, "", "vulnerabilities", now) {
+		t.Fatalf("an unauthenticated caller must not be covered")
+	}
+	if x.Covers("ghcr.io/org/mcp", testDigest, "svc", "signature", now) {
+		t.Fatalf("exception must only waive listed checks")
+	}
+	if x.Covers("ghcr.io/org/mcp", testDigest, "svc", "vulnerabilities", now.Add(2*time.Hour)) {
+		t.Fatalf("expired exception must not apply")
+	}
+	revoked := now
+	x.RevokedAt = &revoked
+	if x.Covers("ghcr.io/org/mcp", testDigest, "svc", "vulnerabilities", now) {
+		t.Fatalf("revoked exception must not apply")
+	}
+}
+
+func TestExceptionValidate(t *testing.T) {
+	base := Exception{Digest: testDigest, Principal: "svc", Reason: "CVE fix pending", Waive: []string{"signature"}}
+	if err := base.Validate(); err != nil {
+		t.Fatalf("valid exception rejected: %v", err)
+	}
+	both := base
+	both.Repository = "ghcr.io/org"
+	unwaivable := base
+	unwaivable.Waive = []string{"registry"}
+	anonymous := base
+	anonymous.Principal = "anonymous"
+	for name, x := range map[string]Exception{"digest and repository": both, "unwaivable check": unwaivable, "anonymous principal": anonymous} {
+		if err := x.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestEvaluateWaivesCoveredFailure(t *testing.T) {
+	cfg := Config{AllowRules: []ImageRule{{Pattern: "ghcr.io/org"}}, InspectImageConfig: true}
+	inspector := fakeInspector{cfg: registry.ImageConfig{User: "0", ExposedPorts: []string{"8080/tcp"}}}
+	req := Request{ImageRef: "ghcr.io/org/mcp@" + testDigest, DownstreamPort: 8080, Principal: "svc"}
+
+	if res := NewEnforcer(cfg, inspector, nil).Evaluate(req); res.Err == nil {
+		t.Fatalf("expected root image to be denied without an exception")
+	}
+
+	x := Exception{ID: "x1", Digest: testDigest, Principal: "svc", Waive: []string{"image_config"}, Reason: "legacy image", ExpiresAt: time.Now().Add(time.Hour)}
+	res := NewEnforcer(cfg, inspector, staticExceptions{x}).Evaluate(req)
+	if res.Err != nil {
+		t.Fatalf("expected exception to waive image_config, got %v", res.Err)
+	}
+	if len(res.Evidence.ExceptionsUsed) != 1 || res.Evidence.ExceptionsUsed[0].ID != "x1" {
+		t.Fatalf("exception not recorded in evidence: %+v", res.Evidence.ExceptionsUsed)
+	}
+	for _, c := range res.Checks {
+		if c.Name == "image_config" && c.Status != CheckWaived {
+			t.Fatalf("image_config check should be waived, got %s", c.Status)
+		}
+	}
+
+	req.Principal = "someone-else"
+	if res := NewEnforcer(cfg, inspector, staticExceptions{x}).Evaluate(req); res.Err == nil {
+		t.Fatalf("exception must not apply to other principals")
+	}
+	req.Principal = ""
+	if res := NewEnforcer(cfg, inspector, staticExceptions{x}).Evaluate(req); res.Err == nil {
+		t.Fatalf("exception must not apply to unauthenticated callers")
+	}
+}
@@ -0,0 +1,64 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mcp-orc/runner/internal/registry"
+)
+
+type ImageInspector interface {
+	ImageConfig(registry, repository, digest string) (*registry.ImageConfig, error)
+	ResolveDigest(registry, repository, tag string) (string, error)
+}
+
+type ImageConfigEvidence struct {
+	ConfigDigest string   `json:"config_digest"`
+	User         string   `json:"user"`
+	ExposedPorts []string `json:"exposed_ports,omitempty"`
+	SizeBytes    int64    `json:"size_bytes"`
+}
+
+// checkImageConfig rejects images that would only fail once scheduled: the
+// pod runs with runAsNonRoot, which the kubelet can only honour for a numeric
+// non-zero UID.
+func (e *Enforcer) checkImageConfig(cfg Config, ref Reference, digest string, port int) (*registry.ImageConfig, *ImageConfigEvidence, error) {
+	if e.inspector == nil {
+		return nil, nil, &stageFailure{reason: "image_config_unavailable", err: fmt.Errorf("no image inspector configured")}
+	}
+	ic, err := e.inspector.ImageConfig(ref.Registry, ref.Repository, digest)
+	if err != nil {
+		return nil, nil, &stageFailure{reason: "image_config_unavailable", err: err}
+	}
+	ev := &ImageConfigEvidence{ConfigDigest: ic.ConfigDigest, User: ic.User, ExposedPorts: ic.ExposedPorts, SizeBytes: ic.SizeBytes}
+
+	uid, _, _ := strings.Cut(ic.User, ":")
+	switch {
+	case uid == "" || uid == "root" || uid == "0":
+		return ic, ev, &stageFailure{reason: "image_user_root", err: fmt.Errorf("image user %q is root or unset", ic.User)}
+	default:
+		if n, err := strconv.ParseInt(uid, 10, 64); err != nil || n < 0 {
+			return ic, ev, &stageFailure{reason: "image_user_non_numeric", err: fmt.Errorf("image user %q is not a numeric UID", ic.User)}
+		}
+	}
+
+	if port > 0 && !exposesPort(ic.ExposedPorts, port) {
+		return ic, ev, &stageFailure{reason: "downstream_port_not_exposed", err: fmt.Errorf("image does not expose port %d/tcp (exposes %v)", port, ic.ExposedPorts)}
+	}
+	if cfg.MaxImageSizeMB > 0 && ic.SizeBytes > cfg.MaxImageSizeMB<<20 {
+		return ic, ev, &stageFailure{reason: "image_too_large", err: fmt.Errorf("image is %d bytes, limit is %d MiB", ic.SizeBytes, cfg.MaxImageSizeMB)}
+	}
+	return ic, ev, nil
+}
+
+func exposesPort(exposed []string, port int) bool {
+	want := strconv.Itoa(port)
+	for _, p := range exposed {
+		num, proto, _ := strings.Cut(p, "/")
+		if num == want && (proto == "" || proto == "tcp") {
+			return true
+		}
+	}
+	return false
+}
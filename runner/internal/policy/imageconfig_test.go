@@ -0,0 +1,47 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/mcp-orc/runner/internal/registry"
+)
+
+type fakeInspector struct{ cfg registry.ImageConfig }
+
+func (f fakeInspector) ImageConfig(_, _, _ string) (*registry.ImageConfig, error) {
+	c := f.cfg
+	return &c, nil
+}
+
+func (f fakeInspector) ResolveDigest(_, _, _ string) (string, error) {
+	return f.cfg.ConfigDigest, nil
+}
+
+func TestCheckImageConfig(t *testing.T) {
+	ref := Reference{Registry: "ghcr.io", Repository: "org/mcp"}
+	cases := []struct {
+		name   string
+		cfg    registry.ImageConfig
+		reason string
+	}{
+		{"ok", registry.ImageConfig{User: "65532", ExposedPorts: []string{"8080/tcp"}, SizeBytes: 1 << 20}, ""},
+		{"unset user", registry.ImageConfig{User: "", ExposedPorts: []string{"8080/tcp"}}, "image_user_root"},
+		{"root uid with group", registry.ImageConfig{User: "0:1000", ExposedPorts: []string{"8080/tcp"}}, "image_user_root"},
+		{"named user", registry.ImageConfig{User: "nonroot", ExposedPorts: []string{"8080/tcp"}}, "image_user_non_numeric"},
+		{"port missing", registry.ImageConfig{User: "1000", ExposedPorts: []string{"9090/tcp", "8080/udp"}}, "downstream_port_not_exposed"},
+		{"too large", registry.ImageConfig{User: "1000", ExposedPorts: []string{"8080"}, SizeBytes: 3 << 20}, "image_too_large"},
+	}
+	for _, c := range cases {
+		e := NewEnforcer(Config{MaxImageSizeMB: 2}, fakeInspector{cfg: c.cfg}, nil)
+		_, _, err := e.checkImageConfig(e.Config(), ref, "sha256:aa", 8080)
+		got := ""
+		if sf, ok := err.(*stageFailure); ok {
+			got = sf.reason
+		} else if err != nil {
+			t.Fatalf("%s: unexpected error type %v", c.name, err)
+		}
+		if got != c.reason {
+			t.Errorf("%s: got reason %q, want %q", c.name, got, c.reason)
+		}
+	}
+}
@@ -1,136 +1,494 @@
 package policy
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/mcp-orc/runner/internal/registry"
 )
 
 type Config struct {
-	AllowlistedRegistries []string
-	RequireCosignVerify   bool
-	CosignKeyPath         string
-	CosignIdentity        string
-	CosignIssuer          string
+	AllowRules          []ImageRule
+	DenyRules           []ImageRule
+	RequireCosignVerify bool
+	TrustRoots          []TrustRoot
+	TrustRootsFile      string
+	RulesDir            string
+	RulesReloadSeconds  int64
+	VerifyCacheTTL      int64
+	VerifyCacheEntries  int64
+	RequireProvenance   bool
+	AllowedBuilderIDs   []string
+	AllowedSourceRepos  []string
+	RequireSBOM         bool
+	RequireVulnScan     bool
+	VulnMaxCritical     int64
+	VulnMaxHigh         int64
+	VulnMaxScanAgeHours int64
+	VulnExceptionsFile  string
+	InspectImageConfig  bool
+	MaxImageSizeMB      int64
+	ImagePlatform       string
+	InsecureRegistries  []string
+	RequireToolManifest bool
+	CommandRules        []CommandRule
+	CommandPolicyFile   string
 }
 
 type Evidence struct {
-	RegistryAllowed   bool   `json:"registry_allowed"`
-	SignatureVerified bool   `json:"signature_verified"`
-	Verifier          string `json:"verifier"`
-	Identity          string `json:"identity,omitempty"`
-	ResolvedDigest    string `json:"resolved_digest"`
-	DenialReason      string `json:"denial_reason,omitempty"`
+	RegistryAllowed   bool                  `json:"registry_allowed"`
+	MatchedRule       string                `json:"matched_rule,omitempty"`
+	SignatureVerified bool                  `json:"signature_verified"`
+	Verifier          string                `json:"verifier"`
+	Identity          string                `json:"identity,omitempty"`
+	TrustRoot         string                `json:"trust_root,omitempty"`
+	ResolvedDigest    string                `json:"resolved_digest"`
+	DenialReason      string                `json:"denial_reason,omitempty"`
+	PolicySetHash     string                `json:"policy_set_hash,omitempty"`
+	RuleResults       []RuleResult          `json:"rule_results,omitempty"`
+	VerificationCache string                `json:"verification_cache,omitempty"`
+	VerifiedAt        string                `json:"verified_at,omitempty"`
+	Attestations      []AttestationEvidence `json:"attestations,omitempty"`
+	Vulnerabilities   *VulnSummary          `json:"vulnerabilities,omitempty"`
+	ImageConfig       *ImageConfigEvidence  `json:"image_config,omitempty"`
+	ExceptionsUsed    []ExceptionEvidence   `json:"exceptions_used,omitempty"`
+	Resources         *ResourceEvidence     `json:"resources,omitempty"`
+	Env               *EnvEvidence          `json:"env,omitempty"`
+	DeclaredTools     []string              `json:"declared_tools,omitempty"`
+	EffectiveTools    []string              `json:"effective_tools,omitempty"`
+	Command           *CommandEvidence      `json:"command,omitempty"`
+}
+
+func ConfigFromEnv() (Config, error) {
+	return ConfigFrom(os.Getenv)
+}
+
+// ConfigFrom builds the policy config from RUNNER_* keys resolved by lookup,
+// which is os.Getenv or a config.Source. Malformed values are errors, never
+// silently replaced by defaults.
+func ConfigFrom(lookup func(string) string) (Config, error) {
+	// There is no default: any registry-wide default would admit every
+	// image its users publish.
+	allowEntries := splitList(lookup("RUNNER_ALLOWLISTED_REGISTRIES"))
+	if len(allowEntries) == 0 {
+		return Config{}, fmt.Errorf("RUNNER_ALLOWLISTED_REGISTRIES is required, e.g. ghcr.io/our-org/mcp-*")
+	}
+	allow, err := ParseImageRules(allowEntries)
+	if err != nil {
+		return Config{}, fmt.Errorf("RUNNER_ALLOWLISTED_REGISTRIES: %w", err)
+	}
+	deny, err := ParseImageRules(splitList(lookup("RUNNER_DENYLISTED_IMAGES")))
+	if err != nil {
+		return Config{}, fmt.Errorf("RUNNER_DENYLISTED_IMAGES: %w", err)
+	}
+	reloadSeconds, err := envInt64(lookup, "RUNNER_POLICY_RELOAD_SECONDS", 10, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	cacheTTL, err := envInt64(lookup, "RUNNER_VERIFY_CACHE_TTL_SECONDS", 600, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	cacheEntries, err := envInt64(lookup, "RUNNER_VERIFY_CACHE_MAX_ENTRIES", 512, 1)
+	if err != nil {
+		return Config{}, err
+	}
+	maxCritical, err := envInt64(lookup, "RUNNER_VULN_MAX_CRITICAL", 0, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	maxHigh, err := envInt64(lookup, "RUNNER_VULN_MAX_HIGH", 0, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	maxScanAge, err := envInt64(lookup, "RUNNER_VULN_MAX_SCAN_AGE_HOURS", 0, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	maxImageSize, err := envInt64(lookup, "RUNNER_MAX_IMAGE_SIZE_MB", 0, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	requireCosignVerify, err := envBool(lookup, "RUNNER_REQUIRE_COSIGN", true)
+	if err != nil {
+		return Config{}, err
+	}
+	requireProvenance, err := envBool(lookup, "RUNNER_REQUIRE_PROVENANCE", false)
+	if err != nil {
+		return Config{}, err
+	}
+	requireSBOM, err := envBool(lookup, "RUNNER_REQUIRE_SBOM", false)
+	if err != nil {
+		return Config{}, err
+	}
+	requireVulnScan, err := envBool(lookup, "RUNNER_REQUIRE_VULN_SCAN", false)
+	if err != nil {
+		return Config{}, err
+	}
+	inspectImageConfig, err := envBool(lookup, "RUNNER_INSPECT_IMAGE_CONFIG", true)
+	if err != nil {
+		return Config{}, err
+	}
+	requireToolManifest, err := envBool(lookup, "RUNNER_REQUIRE_TOOL_MANIFEST", false)
+	if err != nil {
+		return Config{}, err
+	}
+	trustFile := strings.TrimSpace(lookup("RUNNER_TRUST_ROOTS_FILE"))
+	roots, err := trustRootsFromEnv(
+		trustFile,
+		strings.TrimSpace(lookup("RUNNER_COSIGN_KEY_PATH")),
+		strings.TrimSpace(lookup("RUNNER_COSIGN_IDENTITY")),
+		strings.TrimSpace(lookup("RUNNER_COSIGN_ISSUER")),
+	)
+	if err != nil {
+		return Config{}, err
+	}
+	var commandRules []CommandRule
+	commandFile := strings.TrimSpace(lookup("RUNNER_COMMAND_POLICY_FILE"))
+	if commandFile != "" {
+		if commandRules, err = LoadCommandPolicy(commandFile); err != nil {
+			return Config{}, err
+		}
+	}
+	return Config{
+		AllowRules:          allow,
+		DenyRules:           deny,
+		RequireCosignVerify: requireCosignVerify,
+		TrustRoots:          roots,
+		TrustRootsFile:      trustFile,
+		RulesDir:            strings.TrimSpace(lookup("RUNNER_POLICY_DIR")),
+		RulesReloadSeconds:  reloadSeconds,
+		VerifyCacheTTL:      cacheTTL,
+		VerifyCacheEntries:  cacheEntries,
+		RequireProvenance:   requireProvenance,
+		AllowedBuilderIDs:   splitList(lookup("RUNNER_ALLOWED_BUILDER_IDS")),
+		AllowedSourceRepos:  splitList(lookup("RUNNER_ALLOWED_SOURCE_REPOS")),
+		RequireSBOM:         requireSBOM,
+		RequireVulnScan:     requireVulnScan,
+		VulnMaxCritical:     maxCritical,
+		VulnMaxHigh:         maxHigh,
+		VulnMaxScanAgeHours: maxScanAge,
+		VulnExceptionsFile:  strings.TrimSpace(lookup("RUNNER_VULN_EXCEPTIONS_FILE")),
+		InspectImageConfig:  inspectImageConfig,
+		MaxImageSizeMB:      maxImageSize,
+		ImagePlatform:       strings.TrimSpace(lookup("RUNNER_IMAGE_PLATFORM")),
+		InsecureRegistries:  splitList(lookup("RUNNER_INSECURE_REGISTRIES")),
+		RequireToolManifest: requireToolManifest,
+		CommandRules:        commandRules,
+		CommandPolicyFile:   commandFile,
+	}, nil
+}
+
+func envInt64(lookup func(string) string, key string, fallback, min int64) (int64, error) {
+	v := strings.TrimSpace(lookup(key))
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < min {
+		return 0, fmt.Errorf("%s must be an integer >= %d", key, min)
+	}
+	return n, nil
+}
+
+func envBool(lookup func(string) string, key string, fallback bool) (bool, error) {
+	v := strings.TrimSpace(lookup(key))
+	if v == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", key)
+	}
+	return b, nil
 }
 
-func ConfigFromEnv() Config {
-	allow := strings.TrimSpace(os.Getenv("RUNNER_ALLOWLISTED_REGISTRIES"))
+func splitList(raw string) []string {
 	parts := []string{}
-	if allow != "" {
-		for _, p := range strings.Split(allow, ",") {
-			v := strings.TrimSpace(p)
-			if v != "" {
-				parts = append(parts, v)
-			}
+	for _, p := range strings.Split(raw, ",") {
+		if v := strings.TrimSpace(p); v != "" {
+			parts = append(parts, v)
 		}
 	}
-	if len(parts) == 0 {
-		parts = []string{"cgr.dev", "ghcr.io"}
+	return parts
+}
+
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+const (
+	CheckPass    = "pass"
+	CheckFail    = "fail"
+	CheckSkipped = "skipped"
+	CheckWaived  = "waived"
+)
+
+var enforceStages = []string{"reference", "registry", "signature", "digest", "image_config", "tools", "command", "attestations", "vulnerabilities"}
+
+type Result struct {
+	PinnedRef    string
+	AllowedTools []string
+	Evidence     Evidence
+	Checks       []Check
+	Err          error
+}
+
+func (r *Result) record(stage, status, detail string) {
+	r.Checks = append(r.Checks, Check{Name: stage, Status: status, Detail: detail})
+}
+
+// deny records the failing stage and marks every later stage as skipped so
+// explain callers see the full pipeline.
+func (r Result) deny(stage, reason string, err error) Result {
+	r.Evidence.DenialReason = reason
+	r.Err = err
+	r.record(stage, CheckFail, reason+": "+err.Error())
+	after := false
+	for _, s := range enforceStages {
+		if after {
+			r.record(s, CheckSkipped, "blocked by "+stage)
+		}
+		after = after || s == stage
 	}
-	return Config{
-		AllowlistedRegistries: parts,
-		RequireCosignVerify:   strings.ToLower(os.Getenv("RUNNER_REQUIRE_COSIGN")) != "false",
-		CosignKeyPath:         strings.TrimSpace(os.Getenv("RUNNER_COSIGN_KEY_PATH")),
-		CosignIdentity:        strings.TrimSpace(os.Getenv("RUNNER_COSIGN_IDENTITY")),
-		CosignIssuer:          strings.TrimSpace(os.Getenv("RUNNER_COSIGN_ISSUER")),
+	return r
+}
+
+// Request carries the parts of a run request the image pipeline needs.
+type Request struct {
+	ImageRef       string
+	DownstreamPort int
+	AllowedTools   []string
+	Command        []string
+	Args           []string
+	// Principal is the caller's mTLS identity, or empty for a caller that
+	// only named itself in a header. No exception covers an empty principal.
+	Principal string
+}
+
+// stageFailure lets a stage pick its own denial reason.
+type stageFailure struct {
+	reason string
+	err    error
+}
+
+func (f *stageFailure) Error() string { return f.err.Error() }
+
+func (r Result) denyFailure(stage, fallbackReason string, err error) Result {
+	var sf *stageFailure
+	if errors.As(err, &sf) {
+		return r.deny(stage, sf.reason, sf.err)
 	}
+	return r.deny(stage, fallbackReason, err)
+}
+
+type Enforcer struct {
+	mu         sync.RWMutex
+	cfg        Config
+	cache      *VerifyCache
+	inspector  ImageInspector
+	exceptions ExceptionSource
+}
+
+// NewEnforcer builds the admission pipeline. A zero VerifyCacheTTL disables
+// verification caching but keeps concurrent verifications coalesced.
+func NewEnforcer(cfg Config, inspector ImageInspector, exceptions ExceptionSource) *Enforcer {
+	cache := NewVerifyCache(time.Duration(cfg.VerifyCacheTTL)*time.Second, int(cfg.VerifyCacheEntries))
+	return &Enforcer{cfg: cfg, cache: cache, inspector: inspector, exceptions: exceptions}
+}
+
+// SetConfig swaps the active policy config. Evaluations already in flight
+// finish with the config they started with.
+func (e *Enforcer) SetConfig(cfg Config) {
+	e.mu.Lock()
+	e.cfg = cfg
+	e.mu.Unlock()
 }
 
-func Enforce(cfg Config, imageRef string) (string, Evidence, error) {
-	ev := Evidence{Verifier: "cosign"}
-	registry, err := registryOf(imageRef)
+func (e *Enforcer) Config() Config {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.cfg
+}
+
+func (e *Enforcer) Enforce(req Request) (string, Evidence, error) {
+	res := e.Evaluate(req)
+	return res.PinnedRef, res.Evidence, res.Err
+}
+
+func (e *Enforcer) Evaluate(req Request) Result {
+	cfg := e.Config()
+	res := Result{Evidence: Evidence{Verifier: "cosign"}}
+	ref, err := ParseReference(req.ImageRef)
 	if err != nil {
-		ev.DenialReason = "invalid_image_ref"
-		return "", ev, err
+		return res.deny("reference", "invalid_image_ref", err)
 	}
-	if !isAllowlisted(registry, cfg.AllowlistedRegistries) {
-		ev.DenialReason = "registry_not_allowlisted"
-		return "", ev, errors.New("registry not allowlisted")
+	res.record("reference", CheckPass, ref.String())
+
+	if rule, denied := matchRule(ref.Name(), cfg.DenyRules); denied {
+		res.Evidence.MatchedRule = "deny:" + rule.Pattern
+		return res.deny("registry", "image_denylisted", fmt.Errorf("image %s matches deny rule %s", ref.Name(), rule.Pattern))
+	}
+	rule, allowed := matchRule(ref.Name(), cfg.AllowRules)
+	if !allowed {
+		return res.deny("registry", "registry_not_allowlisted", fmt.Errorf("image %s not covered by any allow rule", ref.Name()))
 	}
-	ev.RegistryAllowed = true
+	res.Evidence.RegistryAllowed = true
+	res.Evidence.MatchedRule = "allow:" + rule.Pattern
+	res.record("registry", CheckPass, "matched "+res.Evidence.MatchedRule)
 
-	digest := digestFromRef(imageRef)
+	digest := ref.Digest
+	roots, rootsErr := candidateRoots(cfg, ref.Name(), time.Now().UTC())
 	if digest == "" || cfg.RequireCosignVerify {
-		dg, verifyIdentity, verr := verifyAndResolveDigest(cfg, imageRef)
-		if verr != nil {
-			ev.DenialReason = "cosign_verify_failed"
-			return "", ev, verr
+		// The candidate root names are part of the key so an entry verified by
+		// a root that has since expired or been removed is never served.
+		key, cacheable := ref.String()+"|"+rootNames(roots), ref.Digest != "" && e.cache.ttl > 0
+		v, cacheState, verr := e.cache.Do(trustHash(cfg), key, cacheable, func() (verification, error) {
+			if rootsErr != nil {
+				return verification{}, rootsErr
+			}
+			return verifyWithRoots(roots, ref.String())
+		})
+		switch {
+		case verr == nil:
+			res.Evidence.SignatureVerified = true
+			res.Evidence.Identity = v.Identity
+			res.Evidence.TrustRoot = v.Root
+			res.Evidence.VerificationCache = cacheState
+			res.Evidence.VerifiedAt = v.VerifiedAt.Format(time.RFC3339)
+			digest = v.Digest
+			roots = slices.DeleteFunc(roots, func(r TrustRoot) bool { return r.Name != v.Root })
+			res.record("signature", CheckPass, fmt.Sprintf("verified by trust root %s as %s (cache %s, verified at %s)", v.Root, v.Identity, cacheState, res.Evidence.VerifiedAt))
+		default:
+			// An unsigned image has no cosign-resolved digest; exceptions are
+			// matched against the digest the registry serves for the tag.
+			if digest == "" && e.inspector != nil && ref.Tag != "" {
+				digest, _ = e.inspector.ResolveDigest(ref.Registry, ref.Repository, ref.Tag)
+			}
+			if !e.waive(&res, req, ref, digest, "signature", verr) {
+				return res.deny("signature", "cosign_verify_failed", verr)
+			}
 		}
-		ev.SignatureVerified = true
-		ev.Identity = verifyIdentity
-		digest = dg
 	} else {
-		ev.SignatureVerified = false
+		res.record("signature", CheckSkipped, "verification not required for digest-pinned reference")
 	}
 
 	if digest == "" {
-		ev.DenialReason = "digest_resolution_failed"
-		return "", ev, errors.New("could not resolve digest")
+		return res.deny("digest", "digest_resolution_failed", errors.New("could not resolve digest"))
 	}
 	if !strings.HasPrefix(digest, "sha256:") {
-		ev.DenialReason = "invalid_digest"
-		return "", ev, errors.New("digest must be sha256")
+		return res.deny("digest", "invalid_digest", errors.New("digest must be sha256"))
+	}
+	res.Evidence.ResolvedDigest = digest
+	pinned := ref.Pinned(digest)
+	res.record("digest", CheckPass, pinned)
+
+	var ic *registry.ImageConfig
+	if !cfg.InspectImageConfig {
+		res.record("image_config", CheckSkipped, "image config inspection disabled")
+	} else {
+		var icEv *ImageConfigEvidence
+		ic, icEv, err = e.checkImageConfig(cfg, ref, digest, req.DownstreamPort)
+		res.Evidence.ImageConfig = icEv
+		switch {
+		case err == nil:
+			res.record("image_config", CheckPass, fmt.Sprintf("user %s, %d bytes, ports %v", ic.User, ic.SizeBytes, ic.ExposedPorts))
+		case !e.waive(&res, req, ref, digest, "image_config", err):
+			return res.denyFailure("image_config", "image_config_unavailable", err)
+		}
 	}
-	ev.ResolvedDigest = digest
-	pinned := toPinnedImage(imageRef, digest)
-	return pinned, ev, nil
-}
 
-func registryOf(imageRef string) (string, error) {
-	if strings.TrimSpace(imageRef) == "" {
-		return "", errors.New("empty image_ref")
+	declared, effective, err := resolveTools(cfg, ic, req.AllowedTools)
+	res.Evidence.DeclaredTools = declared
+	if err != nil {
+		return res.denyFailure("tools", "tool_not_declared", err)
 	}
-	ref := imageRef
-	if at := strings.Index(ref, "@"); at >= 0 {
-		ref = ref[:at]
+	res.Evidence.EffectiveTools = effective
+	res.AllowedTools = effective
+	switch {
+	case declared == nil && len(effective) == 0:
+		res.record("tools", CheckSkipped, "no tool manifest; all tools allowed")
+	case declared == nil:
+		res.record("tools", CheckSkipped, "no tool manifest; caller allowlist used as-is")
+	default:
+		res.record("tools", CheckPass, fmt.Sprintf("allowed %v of declared %v", effective, declared))
 	}
-	firstSlash := strings.Index(ref, "/")
-	if firstSlash <= 0 {
-		return "", errors.New("image_ref must include registry")
+
+	cmdEv, err := checkCommand(cfg.CommandRules, ref.Name(), ic, req.Command, req.Args)
+	res.Evidence.Command = cmdEv
+	if err != nil {
+		return res.denyFailure("command", "entrypoint_override_denied", err)
+	}
+	if cmdEv.EntrypointOverridden || cmdEv.ArgsOverridden {
+		res.record("command", CheckPass, "override allowed by command rule "+cmdEv.Rule)
+	} else {
+		res.record("command", CheckPass, "image entrypoint and default args")
 	}
-	return ref[:firstSlash], nil
-}
 
-func isAllowlisted(reg string, allow []string) bool {
-	for _, a := range allow {
-		if reg == a {
-			return true
+	if !cfg.RequireProvenance && !cfg.RequireSBOM {
+		res.record("attestations", CheckSkipped, "no attestations required")
+	} else {
+		atts, err := e.checkAttestations(cfg, roots, pinned, digest)
+		res.Evidence.Attestations = atts
+		switch {
+		case err == nil:
+			res.record("attestations", CheckPass, fmt.Sprintf("%d attestations verified", len(atts)))
+		case !e.waive(&res, req, ref, digest, "attestations", err):
+			return res.denyFailure("attestations", "attestation_missing", err)
 		}
 	}
-	return false
-}
 
-func digestFromRef(imageRef string) string {
-	if at := strings.Index(imageRef, "@sha256:"); at >= 0 {
-		return imageRef[at+1:]
+	if !cfg.RequireVulnScan {
+		res.record("vulnerabilities", CheckSkipped, "vulnerability scan not required")
+	} else {
+		summary, err := e.checkVulnerabilities(cfg, roots, ref, pinned, digest)
+		res.Evidence.Vulnerabilities = summary
+		switch {
+		case err == nil:
+			res.record("vulnerabilities", CheckPass, fmt.Sprintf("critical=%d high=%d excepted=%d", summary.Critical, summary.High, len(summary.Excepted)))
+		case !e.waive(&res, req, ref, digest, "vulnerabilities", err):
+			return res.denyFailure("vulnerabilities", "vuln_scan_missing", err)
+		}
 	}
-	return ""
+
+	res.PinnedRef = pinned
+	return res
 }
 
-func toPinnedImage(imageRef, digest string) string {
-	base := imageRef
-	if at := strings.Index(base, "@"); at >= 0 {
-		base = base[:at]
-	}
-	lastSlash := strings.LastIndex(base, "/")
-	lastColon := strings.LastIndex(base, ":")
-	if lastColon > lastSlash {
-		base = base[:lastColon]
+// trustHash fingerprints everything that decides whether a signature is
+// trusted, including the key material itself, so rotating a key file in place
+// invalidates cached verifications.
+func trustHash(cfg Config) string {
+	h := sha256.New()
+	for _, r := range cfg.TrustRoots {
+		fmt.Fprintf(h, "root=%s\x00identity=%s\x00issuer=%s\x00key=%s\x00scope=%s\x00", r.Name, r.Identity, r.Issuer, r.KeyPath, strings.Join(r.Registries, ","))
+		if r.NotBefore != nil {
+			fmt.Fprintf(h, "not_before=%d\x00", r.NotBefore.Unix())
+		}
+		if r.NotAfter != nil {
+			fmt.Fprintf(h, "not_after=%d\x00", r.NotAfter.Unix())
+		}
+		if r.KeyPath != "" {
+			if b, err := os.ReadFile(r.KeyPath); err == nil {
+				h.Write(b)
+			} else {
+				fmt.Fprintf(h, "unreadable=%v", err)
+			}
+		}
 	}
-	return base + "@" + digest
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 type cosignVerifyResult struct {
@@ -145,16 +503,23 @@ type cosignVerifyResult struct {
 	Optional map[string]any `json:"optional"`
 }
 
-func verifyAndResolveDigest(cfg Config, imageRef string) (string, string, error) {
-	args := []string{"verify", imageRef, "--output", "json"}
-	identity := cfg.CosignIdentity
-	if cfg.CosignKeyPath != "" {
-		args = append(args, "--key", cfg.CosignKeyPath)
-	} else if cfg.CosignIdentity != "" && cfg.CosignIssuer != "" {
-		args = append(args, "--certificate-identity", cfg.CosignIdentity, "--certificate-oidc-issuer", cfg.CosignIssuer)
-	} else {
-		return "", "", errors.New("cosign trust config missing: set key or identity+issuer")
+// verifyWithRoots tries each root in order and reports the first one that
+// verifies the image.
+func verifyWithRoots(roots []TrustRoot, imageRef string) (verification, error) {
+	errs := []string{}
+	for _, root := range roots {
+		dg, identity, err := verifyAndResolveDigest(root, imageRef)
+		if err == nil {
+			return verification{Digest: dg, Identity: identity, Root: root.Name, VerifiedAt: time.Now().UTC()}, nil
+		}
+		errs = append(errs, root.Name+": "+err.Error())
 	}
+	return verification{}, errors.New(strings.Join(errs, "; "))
+}
+
+func verifyAndResolveDigest(root TrustRoot, imageRef string) (string, string, error) {
+	args := append([]string{"verify", imageRef, "--output", "json"}, root.cosignArgs()...)
+	identity := root.Identity
 
 	out, err := exec.Command("cosign", args...).CombinedOutput()
 	if err != nil {
@@ -0,0 +1,159 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	dockerHubRegistry = "docker.io"
+	dockerHubLibrary  = "library"
+)
+
+var (
+	repositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagPattern        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	digestPattern     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+func ParseReference(imageRef string) (Reference, error) {
+	s := strings.TrimSpace(imageRef)
+	if s == "" {
+		return Reference{}, errors.New("empty image_ref")
+	}
+	var ref Reference
+	if at := strings.Index(s, "@"); at >= 0 {
+		ref.Digest = s[at+1:]
+		s = s[:at]
+		if !digestPattern.MatchString(ref.Digest) {
+			return Reference{}, fmt.Errorf("invalid digest %q", ref.Digest)
+		}
+	}
+
+	ref.Registry, s = splitRegistry(s)
+	if lastColon := strings.LastIndex(s, ":"); lastColon >= 0 {
+		ref.Tag = s[lastColon+1:]
+		s = s[:lastColon]
+		if !tagPattern.MatchString(ref.Tag) {
+			return Reference{}, fmt.Errorf("invalid tag %q", ref.Tag)
+		}
+	}
+	if ref.Registry == dockerHubRegistry && !strings.Contains(s, "/") {
+		s = dockerHubLibrary + "/" + s
+	}
+	if !repositoryPattern.MatchString(s) {
+		return Reference{}, fmt.Errorf("invalid repository %q", s)
+	}
+	ref.Repository = s
+	return ref, nil
+}
+
+// splitRegistry follows the Docker convention: the first path component is a
+// registry host only if it contains a dot or port, or is exactly localhost.
+func splitRegistry(s string) (string, string) {
+	slash := strings.Index(s, "/")
+	if slash < 0 {
+		return dockerHubRegistry, s
+	}
+	host := s[:slash]
+	if host != "localhost" && !strings.ContainsAny(host, ".:") {
+		return dockerHubRegistry, s
+	}
+	return normalizeRegistry(host), s[slash+1:]
+}
+
+func normalizeRegistry(host string) string {
+	host = strings.ToLower(host)
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return dockerHubRegistry
+	}
+	return host
+}
+
+func (r Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+func (r Reference) Pinned(digest string) string {
+	return r.Name() + "@" + digest
+}
+
+type ImageRule struct {
+	Pattern string
+}
+
+func ParseImageRules(entries []string) ([]ImageRule, error) {
+	rules := make([]ImageRule, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		pattern := normalizeRule(e)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid image rule %q: %w", e, err)
+		}
+		rules = append(rules, ImageRule{Pattern: pattern})
+	}
+	return rules, nil
+}
+
+// normalizeRule applies ParseReference's normalization to a rule, so
+// "nginx" and "docker.io/nginx" cover docker.io/library/nginx. A lone first
+// component is a whole registry only if it looks like a host.
+func normalizeRule(e string) string {
+	e = strings.TrimSuffix(e, "/")
+	if host, _, ok := strings.Cut(e, "/"); !ok && (host == "localhost" || strings.ContainsAny(host, ".:")) {
+		return normalizeRegistry(host)
+	}
+	registry, repo := splitRegistry(e)
+	if registry == dockerHubRegistry && !strings.Contains(repo, "/") {
+		repo = dockerHubLibrary + "/" + repo
+	}
+	return registry + "/" + repo
+}
+
+// Matches reports whether the rule covers the repository name. A rule matches
+// the name itself or any path-segment prefix of it, so "ghcr.io/org" covers
+// "ghcr.io/org/tool" but not "ghcr.io/org-evil/tool".
+func (r ImageRule) Matches(name string) bool {
+	segments := strings.Split(name, "/")
+	for i := len(segments); i > 0; i-- {
+		prefix := strings.Join(segments[:i], "/")
+		if ok, _ := path.Match(r.Pattern, prefix); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRule(name string, rules []ImageRule) (ImageRule, bool) {
+	for _, r := range rules {
+		if r.Matches(name) {
+			return r, true
+		}
+	}
+	return ImageRule{}, false
+}
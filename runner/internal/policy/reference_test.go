@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	for in, want := range map[string]Reference{
+		"nginx":                              {Registry: "docker.io", Repository: "library/nginx"},
+		"nginx:1.27":                         {Registry: "docker.io", Repository: "library/nginx", Tag: "1.27"},
+		"acme/tool":                          {Registry: "docker.io", Repository: "acme/tool"},
+		"index.docker.io/acme/tool":          {Registry: "docker.io", Repository: "acme/tool"},
+		"docker.io/nginx":                    {Registry: "docker.io", Repository: "library/nginx"},
+		"localhost/tool":                     {Registry: "localhost", Repository: "tool"},
+		"localhost:5000/acme/tool:v1":        {Registry: "localhost:5000", Repository: "acme/tool", Tag: "v1"},
+		"registry.local:5000/tool@" + digest: {Registry: "registry.local:5000", Repository: "tool", Digest: digest},
+		"GHCR.io/acme/mcp-echo:v1@" + digest: {Registry: "ghcr.io", Repository: "acme/mcp-echo", Tag: "v1", Digest: digest},
+	} {
+		got, err := ParseReference(in)
+		if err != nil || got != want {
+			t.Errorf("%s: got %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "ghcr.io/Acme/tool", "ghcr.io/acme/tool@sha256:abc", "ghcr.io/acme/tool:-bad", "ghcr.io/acme//tool"} {
+		if _, err := ParseReference(in); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
+
+func TestImageRuleMatches(t *testing.T) {
+	rules, err := ParseImageRules([]string{"ghcr.io/acme", "ghcr.io/our-org/mcp-*", "index.docker.io/library/nginx", "localhost:5000", "docker.io/redis", "busybox", "bitnami/postgresql"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"ghcr.io/acme/tool":            "ghcr.io/acme",
+		"ghcr.io/acme":                 "ghcr.io/acme",
+		"ghcr.io/acme-evil/tool":       "",
+		"ghcr.io/our-org/mcp-github":   "ghcr.io/our-org/mcp-*",
+		"ghcr.io/our-org/other":        "",
+		"docker.io/library/nginx":      "docker.io/library/nginx",
+		"docker.io/library/nginxx":     "",
+		"localhost:5000/anything":      "localhost:5000",
+		"localhost/anything":           "",
+		"docker.io/library/redis":      "docker.io/library/redis",
+		"docker.io/library/busybox":    "docker.io/library/busybox",
+		"docker.io/bitnami/postgresql": "docker.io/bitnami/postgresql",
+		"docker.io/redis/other":        "",
+	} {
+		got, ok := matchRule(name, rules)
+		if ok != (want != "") || got.Pattern != want {
+			t.Errorf("%s: matched %q (%v), want %q", name, got.Pattern, ok, want)
+		}
+	}
+	for in, want := range map[string]string{"docker.io": "docker.io", "Index.Docker.IO/nginx/": "docker.io/library/nginx", "localhost": "localhost", "registry.local:5000": "registry.local:5000"} {
+		if got := normalizeRule(in); got != want {
+			t.Errorf("normalizeRule(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if _, err := ParseImageRules([]string{"ghcr.io/[acme"}); err == nil {
+		t.Error("malformed glob: expected error")
+	}
+}
+
+func TestDenyRulesWin(t *testing.T) {
+	allow, _ := ParseImageRules([]string{"ghcr.io/acme"})
+	deny, _ := ParseImageRules([]string{"ghcr.io/acme/legacy-*"})
+	e := NewEnforcer(Config{AllowRules: allow, DenyRules: deny}, nil, nil)
+
+	res := e.Evaluate(Request{ImageRef: "ghcr.io/acme/legacy-tool:v1"})
+	if res.Err == nil || res.Evidence.DenialReason != "image_denylisted" || res.Evidence.MatchedRule != "deny:ghcr.io/acme/legacy-*" {
+		t.Errorf("denied image: %v %+v", res.Err, res.Evidence)
+	}
+	res = e.Evaluate(Request{ImageRef: "ghcr.io/other/tool:v1"})
+	if res.Err == nil || res.Evidence.DenialReason != "registry_not_allowlisted" || res.Evidence.RegistryAllowed {
+		t.Errorf("unlisted image: %v %+v", res.Err, res.Evidence)
+	}
+}
+
+func TestConfigRequiresAllowlist(t *testing.T) {
+	if _, err := ConfigFrom(func(string) string { return "" }); err == nil || !strings.Contains(err.Error(), "RUNNER_ALLOWLISTED_REGISTRIES") {
+		t.Errorf("expected missing allowlist error, got %v", err)
+	}
+}
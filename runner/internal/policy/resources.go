@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ResourceRequest is what the caller asked for. Empty quantities take the
+// server defaults; empty limits equal the request.
+type ResourceRequest struct {
+	CPU              string
+	CPULimit         string
+	Memory           string
+	MemoryLimit      string
+	EphemeralStorage string
+	TimeoutSeconds   int64
+}
+
+// ResourceLimits are the server-side floors and ceilings. A zero
+// MaxEphemeralStorage means no ceiling.
+type ResourceLimits struct {
+	MinCPU              resource.Quantity
+	MaxCPU              resource.Quantity
+	MinMemory           resource.Quantity
+	MaxMemory           resource.Quantity
+	MaxEphemeralStorage resource.Quantity
+	MinTimeoutSeconds   int64
+	MaxTimeoutSeconds   int64
+}
+
+type ResourceEvidence struct {
+	CPURequest       string   `json:"cpu_request"`
+	CPULimit         string   `json:"cpu_limit"`
+	MemoryRequest    string   `json:"memory_request"`
+	MemoryLimit      string   `json:"memory_limit"`
+	EphemeralStorage string   `json:"ephemeral_storage,omitempty"`
+	TimeoutSeconds   int64    `json:"timeout_seconds"`
+	Violations       []string `json:"violations,omitempty"`
+}
+
+// ParseQuantities reports the first caller-supplied quantity that does not
+// parse, naming its request field.
+func (r ResourceRequest) ParseQuantities() error {
+	for _, q := range []struct{ field, value string }{
+		{"cpu", r.CPU}, {"cpu_limit", r.CPULimit},
+		{"memory", r.Memory}, {"memory_limit", r.MemoryLimit},
+		{"ephemeral_storage", r.EphemeralStorage},
+	} {
+		if strings.TrimSpace(q.value) == "" {
+			continue
+		}
+		if _, err := resource.ParseQuantity(q.value); err != nil {
+			return fmt.Errorf("%s is not a valid quantity", q.field)
+		}
+	}
+	return nil
+}
+
+// ResolveResources applies defaults and checks the result against limits.
+// The returned evidence is complete even when the request is denied.
+func ResolveResources(req, defaults ResourceRequest, limits ResourceLimits) (*ResourceEvidence, error) {
+	if err := req.ParseQuantities(); err != nil {
+		return nil, err
+	}
+	if req.TimeoutSeconds < 0 {
+		return nil, errors.New("timeout_seconds must be >= 0")
+	}
+	pick := func(v, fallback string) resource.Quantity {
+		if strings.TrimSpace(v) == "" {
+			v = fallback
+		}
+		if strings.TrimSpace(v) == "" {
+			return resource.Quantity{}
+		}
+		q, _ := resource.ParseQuantity(v)
+		return q
+	}
+	cpu := pick(req.CPU, defaults.CPU)
+	cpuLimit := pick(req.CPULimit, cpu.String())
+	mem := pick(req.Memory, defaults.Memory)
+	memLimit := pick(req.MemoryLimit, mem.String())
+	eph := pick(req.EphemeralStorage, defaults.EphemeralStorage)
+	timeout := req.TimeoutSeconds
+	if timeout == 0 {
+		timeout = defaults.TimeoutSeconds
+	}
+
+	ev := &ResourceEvidence{
+		CPURequest:     cpu.String(),
+		CPULimit:       cpuLimit.String(),
+		MemoryRequest:  mem.String(),
+		MemoryLimit:    memLimit.String(),
+		TimeoutSeconds: timeout,
+	}
+	if !eph.IsZero() {
+		ev.EphemeralStorage = eph.String()
+	}
+
+	violate := func(format string, args ...any) {
+		ev.Violations = append(ev.Violations, fmt.Sprintf(format, args...))
+	}
+	if cpu.Cmp(limits.MinCPU) < 0 {
+		violate("cpu request %s below minimum %s", cpu.String(), limits.MinCPU.String())
+	}
+	if cpuLimit.Cmp(cpu) < 0 {
+		violate("cpu limit %s below request %s", cpuLimit.String(), cpu.String())
+	}
+	if cpuLimit.Cmp(limits.MaxCPU) > 0 {
+		violate("cpu limit %s exceeds maximum %s", cpuLimit.String(), limits.MaxCPU.String())
+	}
+	if mem.Cmp(limits.MinMemory) < 0 {
+		violate("memory request %s below minimum %s", mem.String(), limits.MinMemory.String())
+	}
+	if memLimit.Cmp(mem) < 0 {
+		violate("memory limit %s below request %s", memLimit.String(), mem.String())
+	}
+	if memLimit.Cmp(limits.MaxMemory) > 0 {
+		violate("memory limit %s exceeds maximum %s", memLimit.String(), limits.MaxMemory.String())
+	}
+	if !limits.MaxEphemeralStorage.IsZero() && eph.Cmp(limits.MaxEphemeralStorage) > 0 {
+		violate("ephemeral storage %s exceeds maximum %s", eph.String(), limits.MaxEphemeralStorage.String())
+	}
+	if timeout < limits.MinTimeoutSeconds || timeout > limits.MaxTimeoutSeconds {
+		violate("timeout %ds outside %d-%ds", timeout, limits.MinTimeoutSeconds, limits.MaxTimeoutSeconds)
+	}
+	if len(ev.Violations) > 0 {
+		return ev, errors.New(strings.Join(ev.Violations, "; "))
+	}
+	return ev, nil
+}
@@ -0,0 +1,50 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestResolveResources(t *testing.T) {
+	defaults := ResourceRequest{CPU: "100m", Memory: "128Mi", TimeoutSeconds: 300}
+	limits := ResourceLimits{
+		MinCPU:              resource.MustParse("50m"),
+		MaxCPU:              resource.MustParse("2"),
+		MinMemory:           resource.MustParse("64Mi"),
+		MaxMemory:           resource.MustParse("1Gi"),
+		MaxEphemeralStorage: resource.MustParse("1Gi"),
+		MinTimeoutSeconds:   1,
+		MaxTimeoutSeconds:   3600,
+	}
+
+	ev, err := ResolveResources(ResourceRequest{CPULimit: "500m"}, defaults, limits)
+	if err != nil {
+		t.Fatalf("defaults should be admitted: %v", err)
+	}
+	if ev.CPURequest != "100m" || ev.CPULimit != "500m" || ev.MemoryLimit != "128Mi" || ev.TimeoutSeconds != 300 {
+		t.Fatalf("unexpected resolution %+v", ev)
+	}
+
+	cases := map[string]struct {
+		req  ResourceRequest
+		want string
+	}{
+		"cpu over max":        {ResourceRequest{CPU: "4"}, "cpu limit 4 exceeds maximum 2"},
+		"memory below min":    {ResourceRequest{Memory: "16Mi"}, "memory request 16Mi below minimum 64Mi"},
+		"limit below request": {ResourceRequest{Memory: "256Mi", MemoryLimit: "128Mi"}, "memory limit 128Mi below request 256Mi"},
+		"ephemeral over max":  {ResourceRequest{EphemeralStorage: "5Gi"}, "ephemeral storage 5Gi exceeds maximum 1Gi"},
+		"timeout over max":    {ResourceRequest{TimeoutSeconds: 7200}, "timeout 7200s outside 1-3600s"},
+	}
+	for name, c := range cases {
+		ev, err := ResolveResources(c.req, defaults, limits)
+		if err == nil || ev == nil || !strings.Contains(strings.Join(ev.Violations, ";"), c.want) {
+			t.Errorf("%s: got %v (%+v), want violation %q", name, err, ev, c.want)
+		}
+	}
+
+	if _, err := ResolveResources(ResourceRequest{CPU: "lots"}, defaults, limits); err == nil || !strings.Contains(err.Error(), "cpu is not a valid quantity") {
+		t.Fatalf("expected parse error, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mcp-orc/runner/internal/registry"
+)
+
+// ToolManifestKey is read from the image config labels or, failing that, the
+// manifest annotations. Both are covered by the signed digest. The value is a
+// JSON array or a comma-separated list of tool names.
+const ToolManifestKey = "io.mcp-orc.tools"
+
+// resolveTools intersects the caller's allowed_tools with what the image
+// declares. It returns the effective allowlist for the run; an empty result
+// with no manifest keeps the legacy "all tools" behaviour.
+func resolveTools(cfg Config, ic *registry.ImageConfig, requested []string) (declared, effective []string, err error) {
+	requested = normalizeTools(requested)
+	var raw string
+	var found bool
+	if ic != nil {
+		if raw, found = ic.Labels[ToolManifestKey]; !found {
+			raw, found = ic.Annotations[ToolManifestKey]
+		}
+	}
+	if !found {
+		if cfg.RequireToolManifest {
+			return nil, nil, &stageFailure{reason: "tool_manifest_missing", err: fmt.Errorf("image declares no %s label or annotation", ToolManifestKey)}
+		}
+		return nil, requested, nil
+	}
+
+	declared, err = parseToolManifest(raw)
+	if err != nil {
+		return nil, nil, &stageFailure{reason: "tool_manifest_invalid", err: err}
+	}
+	if len(requested) == 0 {
+		return declared, declared, nil
+	}
+	known := map[string]bool{}
+	for _, t := range declared {
+		known[t] = true
+	}
+	undeclared := []string{}
+	for _, t := range requested {
+		if !known[t] {
+			undeclared = append(undeclared, t)
+		}
+	}
+	if len(undeclared) > 0 {
+		return declared, nil, &stageFailure{reason: "tool_not_declared", err: fmt.Errorf("tools %v are not declared by the image (declares %v)", undeclared, declared)}
+	}
+	return declared, requested, nil
+}
+
+func parseToolManifest(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	var tools []string
+	if strings.HasPrefix(raw, "[") {
+		if err := json.Unmarshal([]byte(raw), &tools); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", ToolManifestKey, err)
+		}
+	} else {
+		tools = strings.Split(raw, ",")
+	}
+	tools = normalizeTools(tools)
+	if len(tools) == 0 {
+		return nil, fmt.Errorf("%s declares no tools", ToolManifestKey)
+	}
+	return tools, nil
+}
+
+func normalizeTools(tools []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, t := range tools {
+		if t = strings.TrimSpace(t); t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,38 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mcp-orc/runner/internal/registry"
+)
+
+func TestResolveTools(t *testing.T) {
+	labelled := &registry.ImageConfig{Labels: map[string]string{ToolManifestKey: `["search","echo"]`}}
+	annotated := &registry.ImageConfig{Annotations: map[string]string{ToolManifestKey: "echo, fetch"}}
+	cases := []struct {
+		name      string
+		cfg       Config
+		ic        *registry.ImageConfig
+		requested []string
+		effective []string
+		reason    string
+	}{
+		{"fallback to manifest", Config{}, labelled, nil, []string{"echo", "search"}, ""},
+		{"subset of manifest", Config{}, annotated, []string{"fetch"}, []string{"fetch"}, ""},
+		{"undeclared tool", Config{}, labelled, []string{"echo", "shell"}, nil, "tool_not_declared"},
+		{"no manifest keeps caller list", Config{}, &registry.ImageConfig{}, []string{"x"}, []string{"x"}, ""},
+		{"manifest required", Config{RequireToolManifest: true}, nil, []string{"x"}, nil, "tool_manifest_missing"},
+		{"empty manifest", Config{}, &registry.ImageConfig{Labels: map[string]string{ToolManifestKey: "[]"}}, nil, nil, "tool_manifest_invalid"},
+	}
+	for _, c := range cases {
+		_, effective, err := resolveTools(c.cfg, c.ic, c.requested)
+		reason := ""
+		if sf, ok := err.(*stageFailure); ok {
+			reason = sf.reason
+		}
+		if reason != c.reason || !reflect.DeepEqual(effective, c.effective) {
+			t.Errorf("%s: got %v / %q, want %v / %q", c.name, effective, reason, c.effective, c.reason)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// TrustRoot is one cosign verification root: a public key or a keyless
+// identity/issuer pair, optionally limited to a validity window and to the
+// registries it may vouch for. Several roots can be active at once so a new
+// key can be rolled in before the old one is retired.
+type TrustRoot struct {
+	Name       string     `json:"name"`
+	KeyPath    string     `json:"key_path,omitempty"`
+	Identity   string     `json:"identity,omitempty"`
+	Issuer     string     `json:"issuer,omitempty"`
+	NotBefore  *time.Time `json:"not_before,omitempty"`
+	NotAfter   *time.Time `json:"not_after,omitempty"`
+	Registries []string   `json:"registries,omitempty"`
+
+	scope []ImageRule
+}
+
+type trustFile struct {
+	Roots []TrustRoot `json:"roots"`
+}
+
+// LoadTrustRoots reads and validates a trust root file (YAML or JSON).
+func LoadTrustRoots(path string) ([]TrustRoot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read trust roots: %w", err)
+	}
+	var f trustFile
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, fmt.Errorf("parse trust roots %s: %w", path, err)
+	}
+	if len(f.Roots) == 0 {
+		return nil, fmt.Errorf("trust roots %s: no roots defined", path)
+	}
+	seen := map[string]bool{}
+	for i := range f.Roots {
+		if err := f.Roots[i].init(); err != nil {
+			return nil, fmt.Errorf("trust roots %s: %w", path, err)
+		}
+		if seen[f.Roots[i].Name] {
+			return nil, fmt.Errorf("trust roots %s: duplicate root %q", path, f.Roots[i].Name)
+		}
+		seen[f.Roots[i].Name] = true
+	}
+	return f.Roots, nil
+}
+
+func (t *TrustRoot) init() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return errors.New("root name is required")
+	}
+	hasKey := t.KeyPath != ""
+	hasIdentity := t.Identity != "" || t.Issuer != ""
+	switch {
+	case hasKey == hasIdentity:
+		return fmt.Errorf("root %q: set exactly one of key_path or identity+issuer", t.Name)
+	case hasIdentity && (t.Identity == "" || t.Issuer == ""):
+		return fmt.Errorf("root %q: identity and issuer must be set together", t.Name)
+	}
+	if t.NotBefore != nil && t.NotAfter != nil && !t.NotAfter.After(*t.NotBefore) {
+		return fmt.Errorf("root %q: not_after must be after not_before", t.Name)
+	}
+	scope, err := ParseImageRules(t.Registries)
+	if err != nil {
+		return fmt.Errorf("root %q: %w", t.Name, err)
+	}
+	t.scope = scope
+	return nil
+}
+
+// Applies reports whether the root may verify imageName at now. A root with
+// no registries covers every image.
+func (t TrustRoot) Applies(imageName string, now time.Time) bool {
+	if t.NotBefore != nil && now.Before(*t.NotBefore) {
+		return false
+	}
+	if t.NotAfter != nil && !now.Before(*t.NotAfter) {
+		return false
+	}
+	if len(t.scope) == 0 {
+		return true
+	}
+	_, ok := matchRule(imageName, t.scope)
+	return ok
+}
+
+func (t TrustRoot) cosignArgs() []string {
+	if t.KeyPath != "" {
+		return []string{"--key", t.KeyPath}
+	}
+	return []string{"--certificate-identity", t.Identity, "--certificate-oidc-issuer", t.Issuer}
+}
+
+// trustRootsFromEnv returns the trust root file when
+// set, otherwise a single "default" root from the legacy cosign settings.
+func trustRootsFromEnv(file, keyPath, identity, issuer string) ([]TrustRoot, error) {
+	if file != "" {
+		if keyPath != "" || identity != "" || issuer != "" {
+			return nil, errors.New("RUNNER_TRUST_ROOTS_FILE cannot be combined with RUNNER_COSIGN_KEY_PATH/IDENTITY/ISSUER")
+		}
+		return LoadTrustRoots(file)
+	}
+	if keyPath == "" && identity == "" && issuer == "" {
+		return nil, nil
+	}
+	root := TrustRoot{Name: "default", KeyPath: keyPath, Identity: identity, Issuer: issuer}
+	if err := root.init(); err != nil {
+		return nil, err
+	}
+	return []TrustRoot{root}, nil
+}
+
+// candidateRoots lists the roots allowed to vouch for imageName now, in file
+// order.
+func candidateRoots(cfg Config, imageName string, now time.Time) ([]TrustRoot, error) {
+	if len(cfg.TrustRoots) == 0 {
+		return nil, errors.New("cosign trust config missing: set RUNNER_TRUST_ROOTS_FILE or key or identity+issuer")
+	}
+	roots := []TrustRoot{}
+	for _, r := range cfg.TrustRoots {
+		if r.Applies(imageName, now) {
+			roots = append(roots, r)
+		}
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no trust root is valid for %s at %s", imageName, now.Format(time.RFC3339))
+	}
+	return roots, nil
+}
+
+func rootNames(roots []TrustRoot) string {
+	names := make([]string, len(roots))
+	for i, r := range roots {
+		names[i] = r.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// ReloadTrust re-reads the trust root file. A file that fails validation
+// leaves the active roots in place.
+func (e *Enforcer) ReloadTrust() (bool, error) {
+	cfg := e.Config()
+	if cfg.TrustRootsFile == "" {
+		return false, nil
+	}
+	roots, err := LoadTrustRoots(cfg.TrustRootsFile)
+	if err != nil {
+		return false, err
+	}
+	next := cfg
+	next.TrustRoots = roots
+	if trustHash(next) == trustHash(cfg) {
+		return false, nil
+	}
+	e.mu.Lock()
+	e.cfg.TrustRoots = roots
+	e.mu.Unlock()
+	return true, nil
+}
+
+func (e *Enforcer) TrustHash() string {
+	return trustHash(e.Config())
+}
+
+func (e *Enforcer) WatchTrust(ctx context.Context, interval time.Duration, onReload func(hash string, err error)) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				changed, err := e.ReloadTrust()
+				if (changed || err != nil) && onReload != nil {
+					onReload(e.TrustHash(), err)
+				}
+			}
+		}
+	}()
+}
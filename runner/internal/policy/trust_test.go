@@ -0,0 +1,112 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTrustRoots(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadTrustRootsValidation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "trust.yaml")
+	cases := map[string]string{
+		"key and identity": `roots: [{name: a, key_path: /k, identity: x, issuer: y}]`,
+		"identity only":    `roots: [{name: a, identity: x}]`,
+		"duplicate":        `roots: [{name: a, key_path: /k}, {name: a, key_path: /k2}]`,
+		"inverted window":  `roots: [{name: a, key_path: /k, not_before: "2026-02-01T00:00:00Z", not_after: "2026-01-01T00:00:00Z"}]`,
+		"unknown field":    `roots: [{name: a, key_path: /k, keypath: /k}]`,
+	}
+	for name, content := range cases {
+		writeTrustRoots(t, path, content)
+		if _, err := LoadTrustRoots(path); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestCandidateRootsWindowAndScope(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "trust.yaml")
+	writeTrustRoots(t, path, `
+roots:
+  - name: old-key
+    key_path: /keys/old.pub
+    not_after: "2026-06-01T00:00:00Z"
+  - name: new-key
+    key_path: /keys/new.pub
+    not_before: "2026-05-01T00:00:00Z"
+  - name: vendor
+    identity: https://github.com/vendor/release/.github/workflows/release.yml@refs/heads/main
+    issuer: https://token.actions.githubusercontent.com
+    registries: [ghcr.io/vendor]
+`)
+	roots, err := LoadTrustRoots(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{TrustRoots: roots}
+	at := func(s string) time.Time {
+		ts, _ := time.Parse(time.RFC3339, s)
+		return ts
+	}
+
+	for _, c := range []struct {
+		image, at, want string
+	}{
+		{"ghcr.io/org/mcp", "2026-04-01T00:00:00Z", "old-key"},
+		{"ghcr.io/org/mcp", "2026-05-15T00:00:00Z", "old-key,new-key"},
+		{"ghcr.io/org/mcp", "2026-07-01T00:00:00Z", "new-key"},
+		{"ghcr.io/vendor/tool", "2026-07-01T00:00:00Z", "new-key,vendor"},
+	} {
+		got, err := candidateRoots(cfg, c.image, at(c.at))
+		if err != nil || rootNames(got) != c.want {
+			t.Errorf("%s at %s: got %q (%v), want %q", c.image, c.at, rootNames(got), err, c.want)
+		}
+	}
+
+	cfg.TrustRoots = roots[:1]
+	if _, err := candidateRoots(cfg, "ghcr.io/org/mcp", at("2026-07-01T00:00:00Z")); err == nil || !strings.Contains(err.Error(), "no trust root") {
+		t.Fatalf("expected expired root to leave no candidates, got %v", err)
+	}
+}
+
+func TestReloadTrust(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "trust.yaml")
+	writeTrustRoots(t, path, `roots: [{name: a, key_path: /keys/a.pub}]`)
+	roots, err := LoadTrustRoots(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnforcer(Config{TrustRoots: roots, TrustRootsFile: path}, nil, nil)
+
+	if changed, err := e.ReloadTrust(); changed || err != nil {
+		t.Fatalf("unchanged file: changed=%t err=%v", changed, err)
+	}
+	hash := e.TrustHash()
+	writeTrustRoots(t, path, `roots: [{name: a, key_path: /keys/a.pub}, {name: b, key_path: /keys/b.pub}]`)
+	if changed, err := e.ReloadTrust(); !changed || err != nil {
+		t.Fatalf("added root: changed=%t err=%v", changed, err)
+	}
+	if e.TrustHash() == hash || len(e.Config().TrustRoots) != 2 {
+		t.Fatalf("reload did not apply new roots")
+	}
+
+	hash = e.TrustHash()
+	writeTrustRoots(t, path, `roots: [{name: a}]`)
+	if _, err := e.ReloadTrust(); err == nil {
+		t.Fatalf("expected invalid file to be rejected")
+	}
+	if e.TrustHash() != hash {
+		t.Fatalf("failed reload must keep the previous roots")
+	}
+}
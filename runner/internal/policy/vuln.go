@@ -0,0 +1,229 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const PredicateCosignVuln = "https://cosign.sigstore.dev/attestation/vuln/v1"
+
+type VulnSummary struct {
+	Scanner           string   `json:"scanner,omitempty"`
+	ScannedAt         string   `json:"scanned_at,omitempty"`
+	AttestationDigest string   `json:"attestation_digest"`
+	Critical          int      `json:"critical"`
+	High              int      `json:"high"`
+	Medium            int      `json:"medium"`
+	Low               int      `json:"low"`
+	Unknown           int      `json:"unknown"`
+	Excepted          []string `json:"excepted,omitempty"`
+}
+
+type VulnException struct {
+	ID      string    `json:"id"`
+	Expires time.Time `json:"expires"`
+	Reason  string    `json:"reason"`
+	Images  []string  `json:"images,omitempty"`
+}
+
+type vulnExceptionFile struct {
+	Exceptions []VulnException `json:"exceptions"`
+}
+
+type vulnPredicate struct {
+	Scanner struct {
+		URI    string          `json:"uri"`
+		Result json.RawMessage `json:"result"`
+	} `json:"scanner"`
+	Metadata struct {
+		ScanFinishedOn time.Time `json:"scanFinishedOn"`
+	} `json:"metadata"`
+}
+
+// Trivy and Grype JSON reports are the scanner results we understand.
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			Severity        string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+type grypeReport struct {
+	Matches []struct {
+		Vulnerability struct {
+			ID       string `json:"id"`
+			Severity string `json:"severity"`
+		} `json:"vulnerability"`
+	} `json:"matches"`
+}
+
+type finding struct {
+	id       string
+	severity string
+}
+
+func (e *Enforcer) checkVulnerabilities(cfg Config, roots []TrustRoot, ref Reference, pinnedRef, digest string) (*VulnSummary, error) {
+	stmts, err := e.verifiedStatements(cfg, roots, pinnedRef, digest, []string{"vuln"})
+	if err != nil {
+		return nil, &stageFailure{reason: "vuln_scan_missing", err: fmt.Errorf("vulnerability scan: %w", err)}
+	}
+	st, pred, err := latestScan(stmts)
+	if err != nil {
+		return nil, &stageFailure{reason: "vuln_scan_missing", err: err}
+	}
+	findings, err := parseFindings(pred.Scanner.Result)
+	if err != nil {
+		return nil, &stageFailure{reason: "vuln_scan_missing", err: err}
+	}
+
+	now := time.Now().UTC()
+	summary := &VulnSummary{Scanner: pred.Scanner.URI, AttestationDigest: st.digest}
+	if !pred.Metadata.ScanFinishedOn.IsZero() {
+		summary.ScannedAt = pred.Metadata.ScanFinishedOn.UTC().Format(time.RFC3339)
+	}
+	if cfg.VulnMaxScanAgeHours > 0 {
+		if pred.Metadata.ScanFinishedOn.IsZero() || now.Sub(pred.Metadata.ScanFinishedOn) > time.Duration(cfg.VulnMaxScanAgeHours)*time.Hour {
+			return summary, &stageFailure{reason: "vuln_scan_stale", err: fmt.Errorf("vulnerability scan older than %dh", cfg.VulnMaxScanAgeHours)}
+		}
+	}
+
+	exceptions, err := loadVulnExceptions(cfg.VulnExceptionsFile)
+	if err != nil {
+		return summary, &stageFailure{reason: "vuln_exceptions_invalid", err: err}
+	}
+	excepted := map[string]bool{}
+	for _, f := range findings {
+		if exceptionApplies(exceptions, f.id, ref.Name(), now) {
+			excepted[f.id] = true
+			continue
+		}
+		switch f.severity {
+		case "CRITICAL":
+			summary.Critical++
+		case "HIGH":
+			summary.High++
+		case "MEDIUM":
+			summary.Medium++
+		case "LOW", "NEGLIGIBLE":
+			summary.Low++
+		default:
+			summary.Unknown++
+		}
+	}
+	summary.Excepted = sortedKeys(excepted)
+
+	if summary.Critical > int(cfg.VulnMaxCritical) || summary.High > int(cfg.VulnMaxHigh) {
+		return summary, &stageFailure{
+			reason: "vulnerability_threshold_exceeded",
+			err:    fmt.Errorf("critical=%d (max %d) high=%d (max %d)", summary.Critical, cfg.VulnMaxCritical, summary.High, cfg.VulnMaxHigh),
+		}
+	}
+	return summary, nil
+}
+
+func latestScan(stmts []verifiedStatement) (verifiedStatement, vulnPredicate, error) {
+	var best verifiedStatement
+	var bestPred vulnPredicate
+	found := false
+	for _, st := range stmts {
+		var pred vulnPredicate
+		if err := json.Unmarshal(st.Predicate, &pred); err != nil {
+			continue
+		}
+		if !found || pred.Metadata.ScanFinishedOn.After(bestPred.Metadata.ScanFinishedOn) {
+			best, bestPred, found = st, pred, true
+		}
+	}
+	if !found {
+		return best, bestPred, fmt.Errorf("no parseable %s predicate", PredicateCosignVuln)
+	}
+	return best, bestPred, nil
+}
+
+// parseFindings deduplicates by vulnerability ID so a CVE present in several
+// packages counts once, at its highest reported severity.
+func parseFindings(raw json.RawMessage) ([]finding, error) {
+	bySeverity := map[string]string{}
+	add := func(id, sev string) {
+		if id == "" {
+			return
+		}
+		sev = strings.ToUpper(sev)
+		if prev, ok := bySeverity[id]; !ok || severityRank(sev) > severityRank(prev) {
+			bySeverity[id] = sev
+		}
+	}
+
+	var trivy trivyReport
+	var grype grypeReport
+	switch {
+	case json.Unmarshal(raw, &trivy) == nil && trivy.Results != nil:
+		for _, r := range trivy.Results {
+			for _, v := range r.Vulnerabilities {
+				add(v.VulnerabilityID, v.Severity)
+			}
+		}
+	case json.Unmarshal(raw, &grype) == nil && grype.Matches != nil:
+		for _, m := range grype.Matches {
+			add(m.Vulnerability.ID, m.Vulnerability.Severity)
+		}
+	default:
+		return nil, fmt.Errorf("unrecognized scanner result format")
+	}
+
+	findings := make([]finding, 0, len(bySeverity))
+	for _, id := range sortedKeys(bySeverity) {
+		findings = append(findings, finding{id: id, severity: bySeverity[id]})
+	}
+	return findings, nil
+}
+
+func severityRank(sev string) int {
+	return map[string]int{"NEGLIGIBLE": 1, "LOW": 1, "MEDIUM": 2, "HIGH": 3, "CRITICAL": 4}[sev]
+}
+
+func loadVulnExceptions(path string) ([]VulnException, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read vuln exceptions: %w", err)
+	}
+	var f vulnExceptionFile
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, fmt.Errorf("parse vuln exceptions: %w", err)
+	}
+	for _, ex := range f.Exceptions {
+		if ex.ID == "" || ex.Expires.IsZero() {
+			return nil, fmt.Errorf("vuln exception %q must set id and expires", ex.ID)
+		}
+	}
+	return f.Exceptions, nil
+}
+
+func exceptionApplies(exceptions []VulnException, id, imageName string, now time.Time) bool {
+	for _, ex := range exceptions {
+		if ex.ID != id || !now.Before(ex.Expires) {
+			continue
+		}
+		if len(ex.Images) == 0 {
+			return true
+		}
+		rules, err := ParseImageRules(ex.Images)
+		if err != nil {
+			continue
+		}
+		if _, ok := matchRule(imageName, rules); ok {
+			return true
+		}
+	}
+	return false
+}
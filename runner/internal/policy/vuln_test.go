@@ -0,0 +1,53 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFindingsDeduplicatesAtHighestSeverity(t *testing.T) {
+	trivy := `{"Results":[
+		{"Vulnerabilities":[{"VulnerabilityID":"CVE-1","Severity":"HIGH"},{"VulnerabilityID":"CVE-2","Severity":"LOW"}]},
+		{"Vulnerabilities":[{"VulnerabilityID":"CVE-1","Severity":"CRITICAL"}]}]}`
+	findings, err := parseFindings([]byte(trivy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 2 || findings[0] != (finding{id: "CVE-1", severity: "CRITICAL"}) {
+		t.Fatalf("unexpected findings %+v", findings)
+	}
+
+	grype := `{"matches":[{"vulnerability":{"id":"GHSA-1","severity":"High"}}]}`
+	findings, err = parseFindings([]byte(grype))
+	if err != nil || len(findings) != 1 || findings[0].severity != "HIGH" {
+		t.Fatalf("unexpected grype findings %+v %v", findings, err)
+	}
+
+	if _, err := parseFindings([]byte(`{"something":"else"}`)); err == nil {
+		t.Fatalf("expected unknown format to be rejected")
+	}
+}
+
+func TestExceptionApplies(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	exceptions := []VulnException{
+		{ID: "CVE-1", Expires: now.Add(time.Hour)},
+		{ID: "CVE-2", Expires: now.Add(-time.Hour)},
+		{ID: "CVE-3", Expires: now.Add(time.Hour), Images: []string{"ghcr.io/our-org/*"}},
+	}
+	cases := []struct {
+		id, image string
+		want      bool
+	}{
+		{"CVE-1", "ghcr.io/any/image", true},
+		{"CVE-2", "ghcr.io/any/image", false},
+		{"CVE-3", "ghcr.io/our-org/mcp", true},
+		{"CVE-3", "ghcr.io/other/mcp", false},
+		{"CVE-4", "ghcr.io/our-org/mcp", false},
+	}
+	for _, c := range cases {
+		if got := exceptionApplies(exceptions, c.id, c.image, now); got != c.want {
+			t.Errorf("%s on %s: got %t, want %t", c.id, c.image, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+// Package pool keeps warm pods for hot image digests. It wraps a
+// backend.Backend: CreateRunPod hands an already running pod with the same
+// spec to the run when one is available and backfills the pool in the
+// background; everything else passes through to the wrapped backend.
+//
+// An image becomes hot when an admitted run asks for it, so only digests
+// that passed the supply-chain gate are ever pre-created. Runs with brokered
+// secrets or in job mode are never pooled.
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mcp-orc/runner/internal/audit"
+	"github.com/mcp-orc/runner/internal/backend"
+	"github.com/mcp-orc/runner/internal/k8s"
+)
+
+// warmProfile is the network profile of unclaimed pods; the claim applies
+// the run's own profile.
+const warmProfile = "deny-all"
+
+type Config struct {
+	// Size is the number of warm pods kept per hot image.
+	Size int
+	// IdleSeconds drops an image's pool after this long without a run, and
+	// recycles warm pods older than this.
+	IdleSeconds int64
+	// MaxImages caps how many images are kept warm; the least recently used
+	// one is dropped first.
+	MaxImages int
+	// MaxTimeoutSeconds is the longest run timeout a warm pod must still fit
+	// once claimed.
+	MaxTimeoutSeconds int64
+}
+
+type Stats struct {
+	Size        int          `json:"size"`
+	IdleSeconds int64        `json:"idle_seconds"`
+	MaxImages   int          `json:"max_images"`
+	Hits        int64        `json:"hits"`
+	Misses      int64        `json:"misses"`
+	Images      []ImageStats `json:"images"`
+}
+
+type ImageStats struct {
+	ImageRef string    `json:"image_ref"`
+	Pods     int       `json:"pods"`
+	Creating int       `json:"creating"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+type warmPod struct {
+	name    string
+	created time.Time
+}
+
+type entry struct {
+	spec     k8s.PodSpecInput
+	pods     []warmPod
+	creating int
+	lastUsed time.Time
+}
+
+type Pool struct {
+	backend.Backend
+	cfg Config
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	hits    int64
+	misses  int64
+}
+
+func New(b backend.Backend, cfg Config) *Pool {
+	return &Pool{Backend: b, cfg: cfg, now: time.Now, entries: map[string]*entry{}}
+}
+
+// eligible reports whether a run can use a pod created before it: secrets
+// are copied per run and jobs own their pods.
+func eligible(in k8s.PodSpecInput) bool {
+	return len(in.Secrets) == 0 && in.RunMode != k8s.RunModeJob && !in.Warm
+}
+
+// poolKey identifies interchangeable pods: everything in the spec except
+// what a claim sets.
+func poolKey(in k8s.PodSpecInput) string {
+	in.RunID, in.NetworkProfile, in.TimeoutSeconds, in.SecretTTLSeconds = "", "", 0, 0
+	raw, _ := json.Marshal(in)
+	return string(raw)
+}
+
+func (p *Pool) CreateRunPod(ctx context.Context, in k8s.PodSpecInput) (string, error) {
+	if !eligible(in) {
+		return p.Backend.CreateRunPod(ctx, in)
+	}
+	key := poolKey(in)
+	if name, ok := p.take(ctx, key, in); ok {
+		audit.Event("warm_pod_claimed", map[string]any{"run_id": in.RunID, "pod_name": name, "image_ref": in.ImageRef})
+		go p.fill(key)
+		return name, nil
+	}
+	p.miss(key, in)
+	go p.fill(key)
+	return p.Backend.CreateRunPod(ctx, in)
+}
+
+// take claims the first running pod of the image's pool. Candidates are
+// removed from the pool while they are checked so concurrent runs never
+// race for the same pod; pods still starting go back.
+func (p *Pool) take(ctx context.Context, key string, in k8s.PodSpecInput) (string, bool) {
+	p.mu.Lock()
+	e, ok := p.entries[key]
+	if !ok {
+		p.mu.Unlock()
+		return "", false
+	}
+	e.lastUsed = p.now()
+	candidates := e.pods
+	e.pods = nil
+	p.mu.Unlock()
+
+	claimed := ""
+	var keep []warmPod
+	for _, wp := range candidates {
+		if claimed != "" {
+			keep = append(keep, wp)
+			continue
+		}
+		phase, _, err := p.Backend.GetPodStatus(ctx, in.Namespace, wp.name)
+		switch {
+		case err != nil || phase == "Pending":
+			keep = append(keep, wp)
+		case phase == "Running":
+			if ready, err := p.Backend.PodReady(ctx, in.Namespace, wp.name); err != nil || !ready {
+				keep = append(keep, wp)
+				continue
+			}
+			claim := k8s.Claim{RunID: in.RunID, NetworkProfile: in.NetworkProfile, TimeoutSeconds: in.TimeoutSeconds}
+			if err := p.Backend.ClaimPod(ctx, in.Namespace, wp.name, claim); err != nil {
+				_ = p.Backend.DeletePod(context.Background(), in.Namespace, wp.name)
+				continue
+			}
+			claimed = wp.name
+		default:
+			_ = p.Backend.DeletePod(context.Background(), in.Namespace, wp.name)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cur, ok := p.entries[key]; ok && cur == e {
+		e.pods = append(e.pods, keep...)
+	} else {
+		p.deletePods(in.Namespace, keep)
+	}
+	if claimed == "" {
+		return "", false
+	}
+	p.hits++
+	return claimed, true
+}
+
+// miss counts a run that found no warm pod and makes its image hot.
+func (p *Pool) miss(key string, in k8s.PodSpecInput) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.misses++
+	if e, ok := p.entries[key]; ok {
+		e.lastUsed = p.now()
+		return
+	}
+	if p.cfg.MaxImages > 0 && len(p.entries) >= p.cfg.MaxImages {
+		p.evictOldest()
+	}
+	in.RunID, in.NetworkProfile, in.TimeoutSeconds, in.SecretTTLSeconds = "", "", 0, 0
+	p.entries[key] = &entry{spec: in, lastUsed: p.now()}
+}
+
+func (p *Pool) evictOldest() {
+	oldest := ""
+	for k, e := range p.entries {
+		if oldest == "" || e.lastUsed.Before(p.entries[oldest].lastUsed) {
+			oldest = k
+		}
+	}
+	if oldest != "" {
+		p.drop(oldest)
+	}
+}
+
+// drop removes an image's pool and deletes its pods; p.mu must be held.
+func (p *Pool) drop(key string) {
+	e := p.entries[key]
+	delete(p.entries, key)
+	p.deletePods(e.spec.Namespace, e.pods)
+}
+
+func (p *Pool) deletePods(namespace string, pods []warmPod) {
+	for _, wp := range pods {
+		go func(name string) {
+			_ = p.Backend.DeletePod(context.Background(), namespace, name)
+		}(wp.name)
+	}
+}
+
+// fill creates pods until the image's pool (ready or starting) is full.
+func (p *Pool) fill(key string) {
+	p.mu.Lock()
+	e, ok := p.entries[key]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	need := p.cfg.Size - len(e.pods) - e.creating
+	if need <= 0 {
+		p.mu.Unlock()
+		return
+	}
+	e.creating += need
+	spec := e.spec
+	p.mu.Unlock()
+
+	for i := 0; i < need; i++ {
+		in := spec
+		in.RunID = uuid.NewString()
+		in.Warm = true
+		in.NetworkProfile = warmProfile
+		// Long enough to sit idle and then serve the longest run; a claim
+		// shortens it to the run's timeout.
+		in.TimeoutSeconds = p.cfg.IdleSeconds + p.cfg.MaxTimeoutSeconds
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		name, err := p.Backend.CreateRunPod(ctx, in)
+		cancel()
+		if err != nil {
+			audit.Event("warm_pool_fill_failed", map[string]any{"image_ref": spec.ImageRef, "reason": err.Error()})
+		}
+
+		p.mu.Lock()
+		e.creating--
+		switch {
+		case err != nil:
+		case p.entries[key] == e:
+			e.pods = append(e.pods, warmPod{name: name, created: p.now()})
+		default:
+			p.deletePods(spec.Namespace, []warmPod{{name: name}})
+		}
+		p.mu.Unlock()
+	}
+}
+
+// Run drops idle images and recycles old warm pods until ctx is done.
+func (p *Pool) Run(ctx context.Context) {
+	interval := time.Duration(p.cfg.IdleSeconds) * time.Second / 4
+	if interval <= 0 || interval > 30*time.Second {
+		interval = 30 * time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			p.Maintain()
+		}
+	}
+}
+
+// Maintain runs one pass of Run.
+func (p *Pool) Maintain() {
+	idle := time.Duration(p.cfg.IdleSeconds) * time.Second
+	now := p.now()
+	var refill []string
+	p.mu.Lock()
+	for key, e := range p.entries {
+		if now.Sub(e.lastUsed) > idle {
+			p.drop(key)
+			continue
+		}
+		fresh := e.pods[:0]
+		var stale []warmPod
+		for _, wp := range e.pods {
+			if now.Sub(wp.created) > idle {
+				stale = append(stale, wp)
+			} else {
+				fresh = append(fresh, wp)
+			}
+		}
+		e.pods = fresh
+		p.deletePods(e.spec.Namespace, stale)
+		refill = append(refill, key)
+	}
+	p.mu.Unlock()
+	for _, key := range refill {
+		p.fill(key)
+	}
+}
+
+// Close deletes every unclaimed pod.
+func (p *Pool) Close(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, e := range p.entries {
+		for _, wp := range e.pods {
+			_ = p.Backend.DeletePod(ctx, e.spec.Namespace, wp.name)
+		}
+		e.pods = nil
+		delete(p.entries, key)
+	}
+}
+
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := Stats{Size: p.cfg.Size, IdleSeconds: p.cfg.IdleSeconds, MaxImages: p.cfg.MaxImages, Hits: p.hits, Misses: p.misses, Images: []ImageStats{}}
+	for _, e := range p.entries {
+		s.Images = append(s.Images, ImageStats{ImageRef: e.spec.ImageRef, Pods: len(e.pods), Creating: e.creating, LastUsed: e.lastUsed})
+	}
+	sort.Slice(s.Images, func(i, j int) bool { return s.Images[i].LastUsed.After(s.Images[j].LastUsed) })
+	return s
+}
+
+var _ backend.Backend = (*Pool)(nil)
@@ -0,0 +1,153 @@
+package pool
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mcp-orc/runner/internal/backend/fake"
+	"github.com/mcp-orc/runner/internal/k8s"
+	"github.com/mcp-orc/runner/internal/secrets"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func spec(image, runID string) k8s.PodSpecInput {
+	return k8s.PodSpecInput{Namespace: "mcp-runs", RunID: runID, ImageRef: image, NetworkProfile: "dns-only", TimeoutSeconds: 60, CPURequest: "100m"}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func livePods(b *fake.Backend, warm bool) int {
+	n := 0
+	for _, p := range b.Pods() {
+		if !p.Deleted && p.Spec.Warm == warm && p.Claim == nil {
+			n++
+		}
+	}
+	return n
+}
+
+func TestMissFillsAndHitClaims(t *testing.T) {
+	b := fake.New()
+	p := New(b, Config{Size: 2, IdleSeconds: 600, MaxImages: 4, MaxTimeoutSeconds: 3600})
+	ctx := context.Background()
+
+	name, err := p.CreateRunPod(ctx, spec("ghcr.io/acme/tool@sha256:aa", "run-1"))
+	if err != nil || name != "run-run-1" {
+		t.Fatalf("miss should create the run's own pod, got %q %v", name, err)
+	}
+	waitFor(t, "pool fill", func() bool { return livePods(b, true) == 2 })
+	for _, pod := range b.Pods() {
+		if pod.Spec.Warm && (pod.Spec.NetworkProfile != warmProfile || pod.Spec.TimeoutSeconds != 4200) {
+			t.Fatalf("warm pod spec %+v", pod.Spec)
+		}
+	}
+
+	name, err = p.CreateRunPod(ctx, spec("ghcr.io/acme/tool@sha256:aa", "run-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod, _ := b.Pod("mcp-runs", name)
+	if !pod.Spec.Warm || pod.Claim == nil {
+		t.Fatalf("expected a claimed warm pod, got %+v", pod)
+	}
+	if *pod.Claim != (k8s.Claim{RunID: "run-2", NetworkProfile: "dns-only", TimeoutSeconds: 60}) {
+		t.Fatalf("claim %+v", *pod.Claim)
+	}
+	waitFor(t, "backfill", func() bool { s := p.Stats(); return s.Images[0].Pods == 2 && s.Images[0].Creating == 0 })
+
+	s := p.Stats()
+	if s.Hits != 1 || s.Misses != 1 || len(s.Images) != 1 || s.Images[0].Pods != 2 {
+		t.Fatalf("stats %+v", s)
+	}
+}
+
+func TestDifferentSpecMisses(t *testing.T) {
+	b := fake.New()
+	p := New(b, Config{Size: 1, IdleSeconds: 600, MaxImages: 4})
+	ctx := context.Background()
+	_, _ = p.CreateRunPod(ctx, spec("ghcr.io/acme/tool@sha256:aa", "run-1"))
+	waitFor(t, "pool fill", func() bool { return livePods(b, true) == 1 })
+
+	other := spec("ghcr.io/acme/tool@sha256:aa", "run-2")
+	other.EnvAllowlist = map[string]string{"MODE": "fast"}
+	name, _ := p.CreateRunPod(ctx, other)
+	if name != "run-run-2" {
+		t.Fatalf("a different spec must not get a warm pod, got %s", name)
+	}
+	if s := p.Stats(); s.Hits != 0 || s.Misses != 2 {
+		t.Fatalf("stats %+v", s)
+	}
+}
+
+func TestIneligibleRunsBypassPool(t *testing.T) {
+	b := fake.New()
+	p := New(b, Config{Size: 1, IdleSeconds: 600, MaxImages: 4})
+	ctx := context.Background()
+	withSecret := spec("ghcr.io/acme/tool@sha256:aa", "run-1")
+	withSecret.Secrets = []secrets.Secret{{Name: "token"}}
+	job := spec("ghcr.io/acme/tool@sha256:aa", "run-2")
+	job.RunMode = k8s.RunModeJob
+	for _, in := range []k8s.PodSpecInput{withSecret, job} {
+		if _, err := p.CreateRunPod(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := p.Stats(); s.Misses != 0 || len(s.Images) != 0 {
+		t.Fatalf("ineligible runs touched the pool: %+v", s)
+	}
+}
+
+func TestMaintainDropsIdleImages(t *testing.T) {
+	b := fake.New()
+	p := New(b, Config{Size: 1, IdleSeconds: 60, MaxImages: 4})
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	_, _ = p.CreateRunPod(context.Background(), spec("ghcr.io/acme/tool@sha256:aa", "run-1"))
+	waitFor(t, "pool fill", func() bool { s := p.Stats(); return len(s.Images) == 1 && s.Images[0].Pods == 1 })
+
+	now = now.Add(2 * time.Minute)
+	p.Maintain()
+	if s := p.Stats(); len(s.Images) != 0 {
+		t.Fatalf("idle image kept: %+v", s)
+	}
+	waitFor(t, "warm pod deletion", func() bool { return livePods(b, true) == 0 })
+}
+
+func TestMaxImagesEvictsLeastRecentlyUsed(t *testing.T) {
+	b := fake.New()
+	p := New(b, Config{Size: 1, IdleSeconds: 600, MaxImages: 1})
+	ctx := context.Background()
+	_, _ = p.CreateRunPod(ctx, spec("ghcr.io/acme/a@sha256:aa", "run-1"))
+	waitFor(t, "pool fill", func() bool { return livePods(b, true) == 1 })
+	_, _ = p.CreateRunPod(ctx, spec("ghcr.io/acme/b@sha256:bb", "run-2"))
+
+	s := p.Stats()
+	if len(s.Images) != 1 || s.Images[0].ImageRef != "ghcr.io/acme/b@sha256:bb" {
+		t.Fatalf("stats %+v", s)
+	}
+	waitFor(t, "evicted pod deletion", func() bool {
+		for _, pod := range b.Pods() {
+			if pod.Spec.Warm && pod.Spec.ImageRef == "ghcr.io/acme/a@sha256:aa" && !pod.Deleted {
+				return false
+			}
+		}
+		return true
+	})
+}
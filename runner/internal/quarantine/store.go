@@ -0,0 +1,181 @@
+// Package quarantine keeps the evidence bundles of quarantined runs. With a
+// directory every bundle is written to disk before the quarantine is
+// acknowledged, so evidence survives restarts and the pod's deletion.
+package quarantine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mcp-orc/runner/internal/k8s"
+	"github.com/mcp-orc/runner/internal/policy"
+)
+
+var ErrNotFound = errors.New("quarantine bundle not found")
+
+// Bundle is what was known about a run when it was quarantined.
+type Bundle struct {
+	RunID   string `json:"run_id"`
+	PodName string `json:"pod_name"`
+	// Trigger is "manual" or the automatic trigger that fired, e.g.
+	// "tool_scope_violation".
+	Trigger        string          `json:"trigger"`
+	Reason         string          `json:"reason"`
+	Caller         string          `json:"caller,omitempty"`
+	QuarantinedAt  time.Time       `json:"quarantined_at"`
+	ImageDigest    string          `json:"image_digest,omitempty"`
+	PolicyEvidence policy.Evidence `json:"policy_evidence"`
+	Evidence       k8s.Evidence    `json:"evidence"`
+	// SHA256 is the digest of the bundle with this field empty, recorded in
+	// the run_quarantined audit event.
+	SHA256 string `json:"sha256"`
+	// Resolution is set once an admin ends the quarantine: "released" lifts
+	// the isolation, "deleted" removes the workload. The bundle is kept.
+	Resolution string     `json:"resolution,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+}
+
+// Summary is a Bundle without the captured evidence.
+type Summary struct {
+	RunID         string     `json:"run_id"`
+	PodName       string     `json:"pod_name"`
+	Trigger       string     `json:"trigger"`
+	Reason        string     `json:"reason"`
+	QuarantinedAt time.Time  `json:"quarantined_at"`
+	SHA256        string     `json:"sha256"`
+	Resolution    string     `json:"resolution,omitempty"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+}
+
+type Store struct {
+	mu      sync.RWMutex
+	dir     string
+	bundles map[string]Bundle
+}
+
+func NewStore(dir string) (*Store, error) {
+	s := &Store{dir: dir, bundles: map[string]Bundle{}}
+	if dir == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("quarantine dir: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("quarantine dir: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read quarantine bundle: %w", err)
+		}
+		var b Bundle
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return nil, fmt.Errorf("parse quarantine bundle %s: %w", e.Name(), err)
+		}
+		s.bundles[b.RunID] = b
+	}
+	return s, nil
+}
+
+// Put seals b with its digest and stores it, replacing any earlier bundle
+// for the run.
+func (s *Store) Put(b Bundle) (Bundle, error) {
+	b.SHA256 = ""
+	raw, err := json.Marshal(b)
+	if err != nil {
+		return Bundle{}, err
+	}
+	sum := sha256.Sum256(raw)
+	b.SHA256 = hex.EncodeToString(sum[:])
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.persist(b); err != nil {
+		return Bundle{}, err
+	}
+	s.bundles[b.RunID] = b
+	return b, nil
+}
+
+func (s *Store) Get(runID string) (Bundle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.bundles[runID]
+	if !ok {
+		return Bundle{}, ErrNotFound
+	}
+	return b, nil
+}
+
+// Resolve records how an admin ended the quarantine.
+func (s *Store) Resolve(runID, resolution, by string, at time.Time) (Bundle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.bundles[runID]
+	if !ok {
+		return Bundle{}, ErrNotFound
+	}
+	b := prev
+	b.Resolution, b.ResolvedAt, b.ResolvedBy = resolution, &at, by
+	if err := s.persist(b); err != nil {
+		return Bundle{}, err
+	}
+	s.bundles[runID] = b
+	return b, nil
+}
+
+// List returns every bundle's summary, newest first.
+func (s *Store) List() []Summary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Summary, 0, len(s.bundles))
+	for _, b := range s.bundles {
+		out = append(out, Summary{RunID: b.RunID, PodName: b.PodName, Trigger: b.Trigger, Reason: b.Reason, QuarantinedAt: b.QuarantinedAt, SHA256: b.SHA256, Resolution: b.Resolution, ResolvedAt: b.ResolvedAt})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].QuarantinedAt.After(out[j].QuarantinedAt) })
+	return out
+}
+
+func (s *Store) persist(b Bundle) error {
+	if s.dir == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".bundle-*")
+	if err != nil {
+		return fmt.Errorf("write quarantine bundle: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write quarantine bundle: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write quarantine bundle: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write quarantine bundle: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, b.RunID+".json")); err != nil {
+		return fmt.Errorf("write quarantine bundle: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mediaOCIIndex          = "application/vnd.oci.image.index.v1+json"
+	mediaOCIManifest       = "application/vnd.oci.image.manifest.v1+json"
+	mediaDockerManifest    = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaDockerList        = "application/vnd.docker.distribution.manifest.list.v2+json"
+	maxManifestBytes       = 4 << 20
+	maxConfigBytes         = 8 << 20
+	defaultPlatform        = "linux/amd64"
+	maxCachedImageConfigs  = 256
+	dockerHubRegistry      = "docker.io"
+	dockerHubRegistryHost  = "registry-1.docker.io"
+	localhostRegistryAlias = "localhost"
+)
+
+type ImageConfig struct {
+	ConfigDigest string
+	User         string
+	ExposedPorts []string
+	Entrypoint   []string
+	Cmd          []string
+	Labels       map[string]string
+	Annotations  map[string]string
+	SizeBytes    int64
+}
+
+type Client struct {
+	Platform string
+	Insecure map[string]bool
+	http     *http.Client
+	auth     map[string]string
+
+	mu    sync.Mutex
+	cache map[string]*ImageConfig
+}
+
+func NewClient(platform string, insecure []string) *Client {
+	if platform == "" {
+		platform = defaultPlatform
+	}
+	c := &Client{
+		Platform: platform,
+		Insecure: map[string]bool{},
+		http:     &http.Client{Timeout: 30 * time.Second},
+		auth:     loadDockerAuth(),
+		cache:    map[string]*ImageConfig{},
+	}
+	for _, h := range insecure {
+		c.Insecure[h] = true
+	}
+	return c
+}
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	Platform  *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant"`
+	} `json:"platform,omitempty"`
+}
+
+type manifest struct {
+	MediaType   string            `json:"mediaType"`
+	Config      descriptor        `json:"config"`
+	Layers      []descriptor      `json:"layers"`
+	Manifests   []descriptor      `json:"manifests"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type configBlob struct {
+	Config struct {
+		User         string              `json:"User"`
+		ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+		Entrypoint   []string            `json:"Entrypoint"`
+		Cmd          []string            `json:"Cmd"`
+		Labels       map[string]string   `json:"Labels"`
+	} `json:"config"`
+}
+
+// ImageConfig fetches the manifest and config blob for a digest-pinned image.
+// Every fetched document is checked against its digest, so the result is as
+// trustworthy as the (already signature-verified) digest itself.
+func (c *Client) ImageConfig(registry, repository, digest string) (*ImageConfig, error) {
+	key := registry + "/" + repository + "@" + digest
+	c.mu.Lock()
+	if cached, ok := c.cache[key]; ok {
+		c.mu.Unlock()
+		return cached, nil
+	}
+	c.mu.Unlock()
+
+	m, err := c.manifestFor(registry, repository, digest)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := c.fetch(registry, repository, "blobs", m.Config.Digest, "", maxConfigBytes)
+	if err != nil {
+		return nil, fmt.Errorf("fetch image config: %w", err)
+	}
+	var blob configBlob
+	if err := json.Unmarshal(raw, &blob); err != nil {
+		return nil, fmt.Errorf("parse image config: %w", err)
+	}
+
+	cfg := &ImageConfig{
+		ConfigDigest: m.Config.Digest,
+		User:         blob.Config.User,
+		Entrypoint:   blob.Config.Entrypoint,
+		Cmd:          blob.Config.Cmd,
+		Labels:       blob.Config.Labels,
+		Annotations:  m.Annotations,
+		SizeBytes:    m.Config.Size,
+	}
+	for p := range blob.Config.ExposedPorts {
+		cfg.ExposedPorts = append(cfg.ExposedPorts, p)
+	}
+	sort.Strings(cfg.ExposedPorts)
+	for _, l := range m.Layers {
+		cfg.SizeBytes += l.Size
+	}
+
+	c.mu.Lock()
+	if len(c.cache) >= maxCachedImageConfigs {
+		c.cache = map[string]*ImageConfig{}
+	}
+	c.cache[key] = cfg
+	c.mu.Unlock()
+	return cfg, nil
+}
+
+func (c *Client) manifestFor(registry, repository, digest string) (*manifest, error) {
+	accept := strings.Join([]string{mediaOCIIndex, mediaOCIManifest, mediaDockerList, mediaDockerManifest}, ", ")
+	raw, err := c.fetch(registry, repository, "manifests", digest, accept, maxManifestBytes)
+	if err != nil {
+		return nil, fmt.Errorf("fetch manifest: %w", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	if len(m.Manifests) == 0 {
+		if m.Config.Digest == "" {
+			return nil, errors.New("manifest has no config descriptor")
+		}
+		return &m, nil
+	}
+
+	goos, arch, _ := strings.Cut(c.Platform, "/")
+	for _, d := range m.Manifests {
+		if d.Platform != nil && d.Platform.OS == goos && d.Platform.Architecture == arch {
+			return c.manifestFor(registry, repository, d.Digest)
+		}
+	}
+	return nil, fmt.Errorf("image index has no %s manifest", c.Platform)
+}
+
+// ResolveDigest returns the digest the registry currently serves for a tag.
+// The result is computed from the manifest bytes, not taken from headers.
+func (c *Client) ResolveDigest(registry, repository, tag string) (string, error) {
+	accept := strings.Join([]string{mediaOCIIndex, mediaOCIManifest, mediaDockerList, mediaDockerManifest}, ", ")
+	raw, err := c.fetch(registry, repository, "manifests", tag, accept, maxManifestBytes)
+	if err != nil {
+		return "", fmt.Errorf("resolve tag %s: %w", tag, err)
+	}
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// fetch GETs a manifest or blob. When reference is a digest the body is
+// checked against it.
+func (c *Client) fetch(registry, repository, kind, reference, accept string, limit int64) ([]byte, error) {
+	host := registry
+	if host == dockerHubRegistry {
+		host = dockerHubRegistryHost
+	}
+	scheme := "https"
+	if c.Insecure[registry] || registry == localhostRegistryAlias || strings.HasPrefix(registry, localhostRegistryAlias+":") {
+		scheme = "http"
+	}
+	u := fmt.Sprintf("%s://%s/v2/%s/%s/%s", scheme, host, repository, kind, reference)
+
+	resp, err := c.get(u, accept, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		token, err := c.token(challenge, host, repository)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = c.get(u, accept, "Bearer "+token); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: status %d", u, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("GET %s: response exceeds %d bytes", u, limit)
+	}
+	if strings.HasPrefix(reference, "sha256:") {
+		sum := sha256.Sum256(body)
+		if got := "sha256:" + hex.EncodeToString(sum[:]); got != reference {
+			return nil, fmt.Errorf("GET %s: content digest %s does not match", u, got)
+		}
+	}
+	return body, nil
+}
+
+func (c *Client) get(u, accept, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return c.http.Do(req)
+}
+
+// token performs the registry bearer-token handshake, using docker config
+// credentials for the host when available and anonymous access otherwise.
+func (c *Client) token(challenge, host, repository string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported registry auth challenge %q", challenge)
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(params, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			fields[k] = strings.Trim(v, `"`)
+		}
+	}
+	if fields["realm"] == "" {
+		return "", errors.New("registry auth challenge missing realm")
+	}
+	q := url.Values{}
+	if fields["service"] != "" {
+		q.Set("service", fields["service"])
+	}
+	q.Set("scope", "repository:"+repository+":pull")
+
+	req, err := http.NewRequest(http.MethodGet, fields["realm"]+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if basic := c.auth[host]; basic != "" {
+		req.Header.Set("Authorization", "Basic "+basic)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token request: status %d", resp.StatusCode)
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tok); err != nil {
+		return "", fmt.Errorf("parse registry token: %w", err)
+	}
+	if tok.Token != "" {
+		return tok.Token, nil
+	}
+	if tok.AccessToken != "" {
+		return tok.AccessToken, nil
+	}
+	return "", errors.New("registry token response empty")
+}
+
+// loadDockerAuth reads base64 "user:password" entries from the docker config
+// used by cosign ($DOCKER_CONFIG/config.json or ~/.docker/config.json).
+func loadDockerAuth() map[string]string {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".docker")
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return map[string]string{}
+	}
+	var parsed struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return map[string]string{}
+	}
+	out := map[string]string{}
+	for host, a := range parsed.Auths {
+		if _, err := base64.StdEncoding.DecodeString(a.Auth); err != nil || a.Auth == "" {
+			continue
+		}
+		host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+		host, _, _ = strings.Cut(host, "/")
+		if host == "index.docker.io" {
+			host = dockerHubRegistryHost
+		}
+		out[host] = a.Auth
+	}
+	return out
+}
@@ -9,11 +9,15 @@ import (
 )
 
 type Run struct {
-	RunID          string
-	PodName        string
-	Namespace      string
-	Status         string
-	Reason         string
+	RunID     string
+	PodName   string
+	RunMode   string
+	Namespace string
+	Status    string
+	Reason    string
+	// FailureReason is the last normalized failure cause seen for the run;
+	// it outlives the pod's events.
+	FailureReason  string
 	CreatedAt      time.Time
 	FinishedAt     *time.Time
 	StoppedByAP    bool
@@ -21,6 +25,22 @@ type Run struct {
 	PolicyEvidence policy.Evidence
 	AllowedTools   map[string]struct{}
 	DownstreamPort int
+	NetworkProfile string
+	// Secrets are the brokered secret names mounted into the pod.
+	Secrets []string
+	// IdleTimeoutSeconds stops the run after this long without tool
+	// activity; 0 leaves it to the deadline and cleanup timer.
+	IdleTimeoutSeconds int64
+	LastActivity       time.Time
+	// ReadyAt is when the workload was first seen Ready. The idle clock
+	// starts there: a slow image pull or sandbox start is not idleness.
+	ReadyAt *time.Time
+	// InFlight counts tool calls still waiting on the pod; a run is never
+	// idle while one is.
+	InFlight int
+	// QuarantinedAt is set while the run is quarantined; the garbage
+	// collector keeps its workload until an admin releases or deletes it.
+	QuarantinedAt *time.Time
 }
 
 type Store struct {
@@ -48,6 +68,23 @@ func (s *Store) Get(runID string) (Run, error) {
 	return run, nil
 }
 
+// List returns a snapshot of every run.
+func (s *Store) List() []Run {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Run, 0, len(s.runs))
+	for _, run := range s.runs {
+		out = append(out, run)
+	}
+	return out
+}
+
+func (s *Store) Delete(runID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.runs, runID)
+}
+
 func (s *Store) Update(runID string, fn func(r Run) Run) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -0,0 +1,161 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mcp-orc/runner/internal/policy"
+)
+
+// MountPath is where brokered secrets appear in the run container, one
+// directory per secret and one file per key.
+const MountPath = "/run/secrets/mcp"
+
+// AllowedImagesAnnotation lists the image patterns (allowlist syntax) a
+// source secret may be released to. Secrets without it are never released.
+const AllowedImagesAnnotation = "runner.mcp-orc.io/allowed-images"
+
+var (
+	namePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+	keyPattern  = regexp.MustCompile(`^[-._a-zA-Z0-9]{1,253}$`)
+)
+
+var ErrNotFound = errors.New("secret not found")
+
+type Secret struct {
+	Name          string
+	Data          map[string][]byte
+	AllowedImages []string
+}
+
+// Keys returns the secret's key names, never its values.
+func (s Secret) Keys() []string {
+	keys := make([]string, 0, len(s.Data))
+	for k := range s.Data {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+type Backend interface {
+	Get(ctx context.Context, name string) (Secret, error)
+}
+
+// Broker releases secrets from a backend to runs whose image the secret is
+// scoped to.
+type Broker struct {
+	backend Backend
+}
+
+func NewBroker(backend Backend) *Broker {
+	return &Broker{backend: backend}
+}
+
+// CheckRefs validates secret references without reading the backend: names
+// are well formed, unique and at most maxSecrets (0 means unlimited). It
+// reveals nothing about which secrets exist.
+func (b *Broker) CheckRefs(refs []string, maxSecrets int64) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	if b == nil {
+		return errors.New("secret brokering is not configured")
+	}
+	if maxSecrets > 0 && int64(len(refs)) > maxSecrets {
+		return fmt.Errorf("at most %d secrets per run", maxSecrets)
+	}
+	seen := map[string]bool{}
+	for _, name := range refs {
+		if !namePattern.MatchString(name) {
+			return fmt.Errorf("secret %q: invalid name", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("secret %q: referenced twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// Resolve fetches every referenced secret for imageName, after CheckRefs.
+// Errors name the secret and never include data.
+func (b *Broker) Resolve(ctx context.Context, refs []string, imageName string, maxSecrets int64) ([]Secret, error) {
+	if err := b.CheckRefs(refs, maxSecrets); err != nil || len(refs) == 0 {
+		return nil, err
+	}
+	out := make([]Secret, 0, len(refs))
+	for _, name := range refs {
+		s, err := b.backend.Get(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("secret %q: %w", name, err)
+		}
+		rules, err := policy.ParseImageRules(s.AllowedImages)
+		if err != nil {
+			return nil, fmt.Errorf("secret %q: %w", name, err)
+		}
+		allowed := false
+		for _, r := range rules {
+			if r.Matches(imageName) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, fmt.Errorf("secret %q is not released to %s", name, imageName)
+		}
+		for k := range s.Data {
+			if !keyPattern.MatchString(k) {
+				return nil, fmt.Errorf("secret %q: invalid key name", name)
+			}
+		}
+		s.Name = name
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// FileBackend reads <dir>/<name>.json files of the form
+// {"allowed_images": [...], "data": {"key": "value"}}. It stands in for a
+// real secret store in tests and local development.
+type FileBackend struct {
+	Dir string
+}
+
+func (f FileBackend) Get(_ context.Context, name string) (Secret, error) {
+	raw, err := os.ReadFile(filepath.Join(f.Dir, name+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Secret{}, ErrNotFound
+	}
+	if err != nil {
+		return Secret{}, err
+	}
+	var file struct {
+		AllowedImages []string          `json:"allowed_images"`
+		Data          map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return Secret{}, fmt.Errorf("parse secret file: %w", err)
+	}
+	s := Secret{Name: name, AllowedImages: file.AllowedImages, Data: map[string][]byte{}}
+	for k, v := range file.Data {
+		s.Data[k] = []byte(v)
+	}
+	return s, nil
+}
+
+// SplitAllowedImages parses the AllowedImagesAnnotation value.
+func SplitAllowedImages(v string) []string {
+	out := []string{}
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
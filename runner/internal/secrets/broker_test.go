@@ -0,0 +1,63 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBrokerResolve(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("github", `{"allowed_images": ["ghcr.io/acme/mcp-*"], "data": {"token": "ghp_value"}}`)
+	write("unscoped", `{"data": {"token": "v"}}`)
+	write("badkey", `{"allowed_images": ["ghcr.io/acme"], "data": {"../x": "v"}}`)
+	write("redis", `{"allowed_images": ["docker.io/redis"], "data": {"password": "v"}}`)
+	b := NewBroker(FileBackend{Dir: dir})
+	ctx := context.Background()
+
+	got, err := b.Resolve(ctx, []string{"github"}, "ghcr.io/acme/mcp-github", 8)
+	if err != nil || len(got) != 1 || string(got[0].Data["token"]) != "ghp_value" {
+		t.Fatalf("resolve: %v %+v", err, got)
+	}
+	// Rules are normalized like references: docker.io/redis is library/redis.
+	if _, err := b.Resolve(ctx, []string{"redis"}, "docker.io/library/redis", 8); err != nil {
+		t.Fatalf("resolve with a Docker Hub short rule: %v", err)
+	}
+
+	cases := map[string]struct {
+		refs  []string
+		image string
+		max   int64
+		want  string
+	}{
+		"other image": {[]string{"github"}, "ghcr.io/evil/mcp-github", 8, "not released"},
+		"unscoped":    {[]string{"unscoped"}, "ghcr.io/acme/mcp-github", 8, "not released"},
+		"missing":     {[]string{"nope"}, "ghcr.io/acme/mcp-github", 8, "not found"},
+		"bad name":    {[]string{"../github"}, "ghcr.io/acme/mcp-github", 8, "invalid name"},
+		"bad key":     {[]string{"badkey"}, "ghcr.io/acme/x", 8, "invalid key"},
+		"duplicate":   {[]string{"github", "github"}, "ghcr.io/acme/mcp-github", 8, "referenced twice"},
+		"over limit":  {[]string{"github", "unscoped"}, "ghcr.io/acme/mcp-github", 1, "at most 1"},
+	}
+	for label, c := range cases {
+		_, err := b.Resolve(ctx, c.refs, c.image, c.max)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got %v, want %q", label, err, c.want)
+			continue
+		}
+		if strings.Contains(err.Error(), "ghp_value") {
+			t.Errorf("%s: error leaks secret data: %v", label, err)
+		}
+	}
+
+	var none *Broker
+	if _, err := none.Resolve(ctx, []string{"github"}, "ghcr.io/acme/mcp-github", 8); err == nil {
+		t.Error("nil broker released a secret")
+	}
+}
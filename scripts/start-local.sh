@@ -4,6 +4,20 @@ set -euo pipefail
 ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
 RUNNER_PORT="${RUNNER_PORT:-8080}"
 FRONTEND_PORT="${FRONTEND_PORT:-4173}"
+# RUNNER_BACKEND=local runs MCP servers as sandboxed processes instead of pods
+# (Linux only, no cluster needed). Images are read from LOCAL_IMAGE_DIR and
+# secrets from LOCAL_SECRETS_DIR; see runner/README.md "Local backend".
+RUNNER_BACKEND="${RUNNER_BACKEND:-kubernetes}"
+LOCAL_IMAGE_DIR="${LOCAL_IMAGE_DIR:-$ROOT_DIR/.local/images}"
+LOCAL_SECRETS_DIR="${LOCAL_SECRETS_DIR:-$ROOT_DIR/.local/secrets}"
+# The runner has no built-in image allowlist. Without a config file, default
+# to the demo images in infra/k8s/samples; set RUNNER_ALLOWLISTED_REGISTRIES
+# (e.g. ghcr.io/your-org/mcp-*) to run your own. The environment overrides the
+# config file, so a file's allowlist is left alone.
+if [[ -z "${RUNNER_ALLOWLISTED_REGISTRIES:-}" && -z "${RUNNER_CONFIG_FILE:-}" ]]; then
+  export RUNNER_ALLOWLISTED_REGISTRIES="cgr.dev/chainguard"
+  echo "note: RUNNER_ALLOWLISTED_REGISTRIES defaults to ${RUNNER_ALLOWLISTED_REGISTRIES} for local runs" >&2
+fi
 
 cleanup() {
   if [[ -n "${RUNNER_PID:-}" ]]; then kill "$RUNNER_PID" >/dev/null 2>&1 || true; fi
@@ -11,10 +25,26 @@ cleanup() {
 }
 trap cleanup EXIT INT TERM
 
-echo "[1/3] Starting runner on :${RUNNER_PORT}"
+runner_cmd=(go run ./cmd/runner)
+if [[ "$RUNNER_BACKEND" == "local" ]]; then
+  mkdir -p "$LOCAL_IMAGE_DIR" "$LOCAL_SECRETS_DIR"
+  export RUNNER_LOCAL_IMAGE_DIR="$LOCAL_IMAGE_DIR"
+  export RUNNER_SECRETS_BACKEND="${RUNNER_SECRETS_BACKEND:-file}"
+  export RUNNER_SECRETS_DIR="${RUNNER_SECRETS_DIR:-$LOCAL_SECRETS_DIR}"
+  # A delegated cgroup lets the sandbox enforce CPU, memory and PID limits.
+  if [[ -z "${RUNNER_LOCAL_CGROUP:-}" ]] && command -v systemd-run >/dev/null 2>&1 \
+    && systemd-run --user --scope --quiet true >/dev/null 2>&1; then
+    runner_cmd=(systemd-run --user --scope --quiet -p Delegate=yes bash -c \
+      'export RUNNER_LOCAL_CGROUP="/sys/fs/cgroup$(cut -d: -f3 /proc/self/cgroup)"; exec go run ./cmd/runner')
+  elif [[ -z "${RUNNER_LOCAL_CGROUP:-}" ]]; then
+    echo "warning: no delegated cgroup (systemd-run --user unavailable); run limits are not enforced" >&2
+  fi
+fi
+
+echo "[1/3] Starting runner (${RUNNER_BACKEND} backend) on :${RUNNER_PORT}"
 (
   cd "$ROOT_DIR/runner"
-  RUNNER_ADDR=":${RUNNER_PORT}" go run ./cmd/runner
+  RUNNER_ADDR=":${RUNNER_PORT}" RUNNER_BACKEND="$RUNNER_BACKEND" "${runner_cmd[@]}"
 ) &
 RUNNER_PID=$!
 